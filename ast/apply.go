@@ -0,0 +1,283 @@
+package ast
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ApplyFunc is called by Apply for every node. Returning false from a pre call skips the children of the node,
+// returning false from a post call aborts the traversal.
+type ApplyFunc func(c *Cursor) bool
+
+// Apply Traverses the tree rooted at root and calls pre before and post after the children of every node. Nodes may
+// be replaced, deleted or have siblings inserted through the Cursor. Apply returns the possibly replaced root.
+func Apply(root Node, pre, post ApplyFunc) (result Node) {
+	parent := &struct{ Node Node }{root}
+	defer func() {
+		if r := recover(); r != nil && r != errAbort {
+			panic(r)
+		}
+		result = parent.Node
+	}()
+
+	a := &application{pre: pre, post: post}
+	a.apply(parent, "Node", nil, root)
+	return
+}
+
+var errAbort = new(int)
+
+// Cursor describes the node being visited by Apply and its position in the parent.
+type Cursor struct {
+	parent any
+	name   string
+	iter   *iterator
+	node   Node
+}
+
+type iterator struct {
+	index, step int
+}
+
+// Node Returns the current node.
+func (c *Cursor) Node() Node {
+	return c.node
+}
+
+// Parent Returns the parent of the current node. It is nil for the root.
+func (c *Cursor) Parent() Node {
+	parent, _ := c.parent.(Node)
+	return parent
+}
+
+// Name Returns the name of the parent field holding the current node, e.g. "Left" or "Statements".
+func (c *Cursor) Name() string {
+	return c.name
+}
+
+// Index Returns the index of the current node in the parent field, or -1 if the field is not a list.
+func (c *Cursor) Index() int {
+	if c.iter == nil {
+		return -1
+	}
+	return c.iter.index
+}
+
+func (c *Cursor) field() reflect.Value {
+	return reflect.Indirect(reflect.ValueOf(c.parent)).FieldByName(c.name)
+}
+
+func value(n Node, t reflect.Type) reflect.Value {
+	if n == nil {
+		return reflect.Zero(t)
+	}
+	return reflect.ValueOf(n)
+}
+
+// Replace Replaces the current node with n. The replacement is traversed instead of the original node.
+func (c *Cursor) Replace(n Node) {
+	v := c.field()
+	if i := c.Index(); i >= 0 {
+		v = v.Index(i)
+	}
+	v.Set(value(n, v.Type()))
+	c.node = n
+}
+
+// Delete Deletes the current node from its list. It panics if the node is not part of a list.
+func (c *Cursor) Delete() {
+	i := c.Index()
+	if i < 0 {
+		panic("ast.Cursor.Delete: node is not part of a list")
+	}
+	v := c.field()
+	l := v.Len()
+	reflect.Copy(v.Slice(i, l), v.Slice(i+1, l))
+	v.Index(l - 1).Set(reflect.Zero(v.Type().Elem()))
+	v.SetLen(l - 1)
+	c.iter.step--
+}
+
+// InsertAfter Inserts n after the current node in its list. n is not traversed by Apply.
+func (c *Cursor) InsertAfter(n Node) {
+	i := c.Index()
+	if i < 0 {
+		panic("ast.Cursor.InsertAfter: node is not part of a list")
+	}
+	v := c.field()
+	v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem())))
+	l := v.Len()
+	reflect.Copy(v.Slice(i+2, l), v.Slice(i+1, l))
+	v.Index(i + 1).Set(value(n, v.Type().Elem()))
+	c.iter.step++
+}
+
+// InsertBefore Inserts n before the current node in its list. n is not traversed by Apply.
+func (c *Cursor) InsertBefore(n Node) {
+	i := c.Index()
+	if i < 0 {
+		panic("ast.Cursor.InsertBefore: node is not part of a list")
+	}
+	v := c.field()
+	v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem())))
+	l := v.Len()
+	reflect.Copy(v.Slice(i+1, l), v.Slice(i, l))
+	v.Index(i).Set(value(n, v.Type().Elem()))
+	c.iter.index++
+}
+
+type application struct {
+	pre, post ApplyFunc
+	cursor    Cursor
+	iter      iterator
+}
+
+func (a *application) apply(parent any, name string, iter *iterator, node Node) {
+	if isNil(node) {
+		return
+	}
+
+	saved := a.cursor
+	a.cursor = Cursor{parent: parent, name: name, iter: iter, node: node}
+
+	if a.pre != nil && !a.pre(&a.cursor) {
+		a.cursor = saved
+		return
+	}
+
+	switch n := a.cursor.node.(type) {
+	case nil:
+		// Replaced with nil
+	case *Program:
+		a.applyList(n, "Statements")
+	case *Identifier, *Number, *String:
+		// Leaves
+	case *BreakStmt:
+		a.apply(n, "Label", nil, n.Label)
+	case *ContinueStmt:
+		a.apply(n, "Label", nil, n.Label)
+	case *BinaryExpr:
+		a.apply(n, "Left", nil, n.Left)
+		a.apply(n, "Right", nil, n.Right)
+	case *UnaryExpr:
+		a.apply(n, "Expr", nil, n.Expr)
+	case *FunctionExpr:
+		a.applyList(n, "Params")
+		a.apply(n, "Body", nil, n.Body)
+	case *CallExpr:
+		a.apply(n, "Caller", nil, n.Caller)
+		a.applyList(n, "Args")
+	case *SubscriptExpr:
+		a.apply(n, "Array", nil, n.Array)
+		a.apply(n, "Index", nil, n.Index)
+	case *SliceExpr:
+		a.apply(n, "Array", nil, n.Array)
+		a.apply(n, "Low", nil, n.Low)
+		a.apply(n, "High", nil, n.High)
+	case *ArrayExpr:
+		a.applyList(n, "Elements")
+	case *NewExpr:
+		a.apply(n, "TypeName", nil, n.TypeName)
+		a.apply(n, "Expression", nil, n.Expression)
+	case *DeclareStmt:
+		a.apply(n, "Ident", nil, n.Ident)
+		a.apply(n, "Expr", nil, n.Expr)
+	case *FuncDeclStmt:
+		a.apply(n, "Name", nil, n.Name)
+		a.apply(n, "Func", nil, n.Func)
+	case *BlockStmt:
+		a.applyList(n, "Statements")
+	case *AssignStmt:
+		a.apply(n, "Ident", nil, n.Ident)
+		a.apply(n, "Expr", nil, n.Expr)
+	case *ArrayAssignStmt:
+		a.apply(n, "Ident", nil, n.Ident)
+		a.apply(n, "Index", nil, n.Index)
+		a.apply(n, "Expr", nil, n.Expr)
+	case *ConditionalStmt:
+		a.apply(n, "Cond", nil, n.Cond)
+		a.apply(n, "Block", nil, n.Block)
+		a.apply(n, "Else", nil, n.Else)
+	case *ReturnStmt:
+		a.applyList(n, "Returned")
+	case *ForStmt:
+		a.apply(n, "Label", nil, n.Label)
+		a.apply(n, "Init", nil, n.Init)
+		a.apply(n, "Cond", nil, n.Cond)
+		a.apply(n, "Update", nil, n.Update)
+		a.apply(n, "Stmt", nil, n.Stmt)
+	case *ExprStmt:
+		a.apply(n, "Expr", nil, n.Expr)
+	case *TryStmt:
+		a.apply(n, "Body", nil, n.Body)
+		a.apply(n, "Param", nil, n.Param)
+		a.apply(n, "Catch", nil, n.Catch)
+		a.apply(n, "Finally", nil, n.Finally)
+	case *ThrowStmt:
+		a.apply(n, "Expr", nil, n.Expr)
+	case *DeferStmt:
+		a.apply(n, "Call", nil, n.Call)
+	case *YieldExpr:
+		a.apply(n, "Value", nil, n.Value)
+	case *MatchStmt:
+		a.apply(n, "Subject", nil, n.Subject)
+		a.applyList(n, "Arms")
+	case *MatchArm:
+		a.applyList(n, "Patterns")
+		a.apply(n, "Guard", nil, n.Guard)
+		a.apply(n, "Body", nil, n.Body)
+	case *ForInStmt:
+		a.apply(n, "Label", nil, n.Label)
+		a.apply(n, "Key", nil, n.Key)
+		a.apply(n, "Value", nil, n.Value)
+		a.apply(n, "Iterable", nil, n.Iterable)
+		a.apply(n, "Stmt", nil, n.Stmt)
+	case *CompoundAssignStmt:
+		a.apply(n, "Target", nil, n.Target)
+		a.apply(n, "Value", nil, n.Value)
+	case *ConditionalExpr:
+		a.apply(n, "Cond", nil, n.Cond)
+		a.apply(n, "Then", nil, n.Then)
+		a.apply(n, "Else", nil, n.Else)
+	case *MemberExpr:
+		a.apply(n, "Object", nil, n.Object)
+		a.apply(n, "Name", nil, n.Name)
+	case *RangeExpr:
+		a.apply(n, "Start", nil, n.Start)
+		a.apply(n, "End", nil, n.End)
+	default:
+		panic(fmt.Sprintf("ast.Apply: unexpected node type %T", n))
+	}
+
+	if a.post != nil && !a.post(&a.cursor) {
+		panic(errAbort)
+	}
+
+	a.cursor = saved
+}
+
+func (a *application) applyList(parent Node, name string) {
+	saved := a.iter
+	a.iter.index = 0
+	for {
+		v := reflect.Indirect(reflect.ValueOf(parent)).FieldByName(name)
+		if a.iter.index >= v.Len() {
+			break
+		}
+
+		node, _ := v.Index(a.iter.index).Interface().(Node)
+		a.iter.step = 1
+		a.apply(parent, name, &a.iter, node)
+		a.iter.index += a.iter.step
+	}
+	a.iter = saved
+}
+
+// isNil Reports whether node is nil or a nil pointer.
+func isNil(node Node) bool {
+	if node == nil {
+		return true
+	}
+	v := reflect.ValueOf(node)
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
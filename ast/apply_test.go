@@ -0,0 +1,115 @@
+package ast_test
+
+import (
+	"bytes"
+	"script/ast"
+	"script/format"
+	"testing"
+)
+
+func source(t *testing.T, program *ast.Program) string {
+	t.Helper()
+	var b bytes.Buffer
+	if err := format.Fprint(&b, program); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		pre  ast.ApplyFunc
+		want string
+	}{
+		{
+			name: "replace",
+			src:  "x := 1 + 1\nprintln([1, 2])\n",
+			pre: func(c *ast.Cursor) bool {
+				if n, ok := c.Node().(*ast.Number); ok && n.Value == "1" {
+					c.Replace(ast.NewNumber(-1, "3"))
+				}
+				return true
+			},
+			want: "x := 3 + 3\nprintln([3, 2])\n",
+		},
+		{
+			name: "delete",
+			src:  "a := 1\nprintln(a)\nprintln(a)\nb := 2\n",
+			pre: func(c *ast.Cursor) bool {
+				if _, ok := c.Node().(*ast.ExprStmt); ok {
+					c.Delete()
+				}
+				return true
+			},
+			want: "a := 1\nb := 2\n",
+		},
+		{
+			name: "insert",
+			src:  "a := 1\nb := 2\n",
+			pre: func(c *ast.Cursor) bool {
+				if _, ok := c.Node().(*ast.DeclareStmt); ok && c.Name() == "Statements" {
+					c.InsertBefore(ast.NewReturnStmt(-1, ast.NewIdentifier(-1, "before")))
+					c.InsertAfter(ast.NewReturnStmt(-1, ast.NewIdentifier(-1, "after")))
+				}
+				return true
+			},
+			want: "return before\na := 1\nreturn after\nreturn before\nb := 2\nreturn after\n",
+		},
+		{
+			name: "skip children",
+			src:  "fn f() { return 1 }\nx := 1\n",
+			pre: func(c *ast.Cursor) bool {
+				if n, ok := c.Node().(*ast.Number); ok {
+					c.Replace(ast.NewNumber(-1, n.Value+"0"))
+				}
+				_, isFunc := c.Node().(*ast.FuncDeclStmt)
+				return !isFunc
+			},
+			want: "fn f() {\n    return 1\n}\nx := 10\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			program := ast.Apply(parse(t, test.src), test.pre, nil).(*ast.Program)
+			if got := source(t, program); got != test.want {
+				t.Errorf("got\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestApplyCursor(t *testing.T) {
+	program := parse(t, "a := b\n")
+
+	var got []string
+	ast.Apply(program, func(c *ast.Cursor) bool {
+		if id, ok := c.Node().(*ast.Identifier); ok {
+			got = append(got, id.Symbol+" "+c.Name()+" "+typeName(c.Parent()))
+		}
+		return true
+	}, nil)
+	if len(got) != 2 || got[0] != "a Ident DeclareStmt" || got[1] != "b Expr DeclareStmt" {
+		t.Errorf("got %q", got)
+	}
+	if program, ok := ast.Apply(program, nil, nil).(*ast.Program); !ok || len(program.Statements) != 1 {
+		t.Error("Apply without functions must return the root unchanged")
+	}
+}
+
+func TestApplyAbort(t *testing.T) {
+	program := parse(t, "a := 1\nb := 2\nc := 3\n")
+
+	visited := 0
+	ast.Apply(program, nil, func(c *ast.Cursor) bool {
+		if _, ok := c.Node().(*ast.DeclareStmt); ok {
+			visited++
+			return visited < 2
+		}
+		return true
+	})
+	if visited != 2 {
+		t.Errorf("visited %d statements after aborting, want 2", visited)
+	}
+}
@@ -0,0 +1,141 @@
+package ast
+
+import (
+	"script/lexer"
+	"strconv"
+)
+
+// The constructors below create nodes whose position is not taken from a parsed token. pos is a rune offset into the
+// source, like lexer.Token.Pos, or -1 for synthesized nodes without a position. Nodes without unexported fields can be
+// created with composite literals instead.
+
+func NewIdentifier(pos int, symbol string) *Identifier {
+	return &Identifier{Symbol: symbol, tok: lexer.Token{Pos: pos, Id: lexer.IDENTIFIER, Lexeme: symbol}}
+}
+
+func NewNumber(pos int, value string) *Number {
+	return &Number{Value: value, tok: lexer.Token{Pos: pos, Id: lexer.NUMBER, Lexeme: value}}
+}
+
+func NewString(pos int, value string) *String {
+	return &String{Value: value, tok: lexer.Token{Pos: pos, Id: lexer.STRING, Lexeme: strconv.Quote(value)}}
+}
+
+// NewFunctionExpr Creates a function expression. pos is the position of the fn keyword.
+func NewFunctionExpr(pos int, params []*Identifier, body *BlockStmt, variadic bool) *FunctionExpr {
+	return &FunctionExpr{
+		Params:     params,
+		Body:       body,
+		IsVariadic: variadic,
+		tok:        lexer.Token{Pos: pos, Id: lexer.FN, Lexeme: "fn"},
+	}
+}
+
+// NewBlockStmt Creates a block. closing is the position of the closing brace.
+func NewBlockStmt(closing int, statements ...Stmt) *BlockStmt {
+	return &BlockStmt{
+		Statements: statements,
+		closing:    lexer.Token{Pos: closing, Id: lexer.CLOSE_BRACE, Lexeme: "}"},
+	}
+}
+
+// NewReturnStmt Creates a return statement. pos is the position of the return keyword.
+func NewReturnStmt(pos int, returned ...Expr) *ReturnStmt {
+	if returned == nil {
+		returned = make([]Expr, 0)
+	}
+	return &ReturnStmt{
+		Returned: returned,
+		tok:      lexer.Token{Pos: pos, Id: lexer.RETURN, Lexeme: "return"},
+	}
+}
+
+// NewForStmt Creates a for statement. init, cond and update may be nil. pos is the position of the for keyword.
+func NewForStmt(pos int, init Stmt, cond Expr, update Stmt, body *BlockStmt) *ForStmt {
+	return &ForStmt{
+		Init:   init,
+		Cond:   cond,
+		Update: update,
+		Stmt:   body,
+		tok:    lexer.Token{Pos: pos, Id: lexer.FOR, Lexeme: "for"},
+	}
+}
+
+// NewBreakStmt Creates a break statement. label may be nil.
+func NewBreakStmt(pos int, label *Identifier) *BreakStmt {
+	return &BreakStmt{Label: label, tok: lexer.Token{Pos: pos, Id: lexer.BREAK, Lexeme: "break"}}
+}
+
+// NewContinueStmt Creates a continue statement. label may be nil.
+func NewContinueStmt(pos int, label *Identifier) *ContinueStmt {
+	return &ContinueStmt{Label: label, tok: lexer.Token{Pos: pos, Id: lexer.CONTINUE, Lexeme: "continue"}}
+}
+
+// NewTryStmt Creates a try statement. pos is the position of the try keyword.
+func NewTryStmt(pos int, body *BlockStmt, param *Identifier, catch, finally *BlockStmt) *TryStmt {
+	return &TryStmt{
+		Body:    body,
+		Param:   param,
+		Catch:   catch,
+		Finally: finally,
+		tok:     lexer.Token{Pos: pos, Id: lexer.TRY, Lexeme: "try"},
+	}
+}
+
+func NewThrowStmt(pos int, expr Expr) *ThrowStmt {
+	return &ThrowStmt{Expr: expr, tok: lexer.Token{Pos: pos, Id: lexer.THROW, Lexeme: "throw"}}
+}
+
+// NewForInStmt Creates a for-in statement. key may be nil.
+func NewForInStmt(pos int, key, value *Identifier, iterable Expr, stmt *BlockStmt) *ForInStmt {
+	return &ForInStmt{
+		Key:      key,
+		Value:    value,
+		Iterable: iterable,
+		Stmt:     stmt,
+		tok:      lexer.Token{Pos: pos, Id: lexer.FOR, Lexeme: "for"},
+	}
+}
+
+// NewRangeExpr Creates a range. pos is the position of the dots.
+func NewRangeExpr(pos int, start, end Expr) *RangeExpr {
+	return &RangeExpr{Start: start, End: end, tok: lexer.Token{Pos: pos, Id: lexer.DOT_DOT, Lexeme: ".."}}
+}
+
+// NewMatchStmt Creates a match statement. pos and closing are the positions of the match keyword and the closing brace.
+func NewMatchStmt(pos, closing int, subject Expr, arms ...*MatchArm) *MatchStmt {
+	return &MatchStmt{
+		Subject: subject,
+		Arms:    arms,
+		tok:     lexer.Token{Pos: pos, Id: lexer.MATCH, Lexeme: "match"},
+		closing: lexer.Token{Pos: closing, Id: lexer.CLOSE_BRACE, Lexeme: "}"},
+	}
+}
+
+func NewYieldExpr(pos int, value Expr) *YieldExpr {
+	return &YieldExpr{Value: value, tok: lexer.Token{Pos: pos, Id: lexer.YIELD, Lexeme: "yield"}}
+}
+
+func NewDeferStmt(pos int, call *CallExpr) *DeferStmt {
+	return &DeferStmt{Call: call, tok: lexer.Token{Pos: pos, Id: lexer.DEFER, Lexeme: "defer"}}
+}
+
+// NewConditionalExpr Creates cond ? then : els. pos is the position of the question mark.
+func NewConditionalExpr(pos int, cond, then, els Expr) *ConditionalExpr {
+	return &ConditionalExpr{Cond: cond, Then: then, Else: els, tok: lexer.Token{Pos: pos, Id: lexer.QUESTION, Lexeme: "?"}}
+}
+
+// NewCompoundAssignStmt Creates target op value, target++ or target--. op is an assignment operator like
+// lexer.PLUS_EQUALS or lexer.PLUS_PLUS, value is nil for the latter. pos is the position of the operator.
+func NewCompoundAssignStmt(pos int, target Expr, op lexer.TokenId, value Expr) *CompoundAssignStmt {
+	lexemes := map[lexer.TokenId]string{
+		lexer.PLUS_EQUALS:     "+=",
+		lexer.MINUS_EQUALS:    "-=",
+		lexer.ASTERISK_EQUALS: "*=",
+		lexer.SLASH_EQUALS:    "/=",
+		lexer.PERCENT_EQUALS:  "%=",
+		lexer.PLUS_PLUS:       "++",
+		lexer.MINUS_MINUS:     "--",
+	}
+	return &CompoundAssignStmt{Target: target, Value: value, tok: lexer.Token{Pos: pos, Id: op, Lexeme: lexemes[op]}}
+}
@@ -0,0 +1,64 @@
+package ast
+
+import "script/lexer"
+
+// binaryOperators maps the compound assignment operators to the binary operators they apply.
+var binaryOperators = map[lexer.TokenId]lexer.TokenId{
+	lexer.PLUS_EQUALS:     lexer.PLUS,
+	lexer.MINUS_EQUALS:    lexer.MINUS,
+	lexer.ASTERISK_EQUALS: lexer.ASTERISK,
+	lexer.SLASH_EQUALS:    lexer.SLASH,
+	lexer.PERCENT_EQUALS:  lexer.PERCENT,
+	lexer.PLUS_PLUS:       lexer.PLUS,
+	lexer.MINUS_MINUS:     lexer.MINUS,
+}
+
+// Lower Returns the statement s stands for in terms of the statements the compiler knows, or s itself if it needs no
+// lowering. The nodes of s are shared but not modified, so the tree stays intact for tools like the formatter.
+func Lower(s Stmt) Stmt {
+	switch s := s.(type) {
+	case *CompoundAssignStmt:
+		return lowerCompoundAssign(s)
+	default:
+		return s
+	}
+}
+
+// lowerCompoundAssign Lowers target op= value to target = target op value. The array and the index of a subscript
+// target are stored in temporaries unless they are identifiers or literals, so that they are evaluated only once:
+//
+//	f()[g()] += 1  =>  { _array := f(); _index := g(); _array[_index] = _array[_index] + 1 }
+func lowerCompoundAssign(s *CompoundAssignStmt) Stmt {
+	value := s.Value
+	if value == nil {
+		value = &Number{Value: "1", tok: lexer.Token{Pos: s.tok.Pos, Id: lexer.NUMBER, Lexeme: "1"}}
+	}
+	apply := func(target Expr) Expr {
+		return &BinaryExpr{Left: target, Operator: binaryOperators[s.tok.Id], Right: value}
+	}
+
+	switch t := s.Target.(type) {
+	case *Identifier:
+		return &AssignStmt{Ident: t, Expr: apply(t)}
+	case *SubscriptExpr:
+		var temps []Stmt
+		temp := func(e Expr, name string) Expr {
+			switch e.(type) {
+			case *Identifier, *Number, *String:
+				return e
+			}
+			ident := &Identifier{Symbol: name, tok: lexer.Token{Pos: e.Tok().Pos, Id: lexer.IDENTIFIER, Lexeme: name}}
+			temps = append(temps, &DeclareStmt{Ident: ident, Expr: e})
+			return ident
+		}
+		array, index := temp(t.Array, "_array"), temp(t.Index, "_index")
+		assign := &ArrayAssignStmt{Ident: array, Index: index, Expr: apply(&SubscriptExpr{Array: array, Index: index})}
+		if len(temps) == 0 {
+			return assign
+		}
+		// The block scopes the temporaries. Its closing brace is the operator, so that it stays within the span of s.
+		return &BlockStmt{Statements: append(temps, assign), closing: s.tok}
+	default:
+		return s
+	}
+}
@@ -54,6 +54,12 @@ func (i *Identifier) String() string {
 
 func (i *Identifier) expr() {}
 
+// IsBool Reports whether i names one of the builtin booleans true and false. Match patterns compare them by value
+// instead of binding them.
+func (i *Identifier) IsBool() bool {
+	return i.Symbol == "true" || i.Symbol == "false"
+}
+
 type Number struct {
 	Value string
 	tok   lexer.Token
@@ -69,6 +75,21 @@ func (n *Number) String() string {
 
 func (n *Number) expr() {}
 
+type String struct {
+	Value string
+	tok   lexer.Token
+}
+
+func (s *String) Tok() lexer.Token {
+	return s.tok
+}
+
+func (s *String) String() string {
+	return script.Stringify(s)
+}
+
+func (s *String) expr() {}
+
 type BinaryExpr struct {
 	Left     Expr
 	Operator lexer.TokenId
@@ -100,14 +121,82 @@ func (u *UnaryExpr) String() string {
 
 func (u *UnaryExpr) expr() {}
 
+// RangeExpr is start..end, the integers from start up to, but excluding, end.
+type RangeExpr struct {
+	Start, End Expr
+	tok        lexer.Token
+}
+
+func (r *RangeExpr) Tok() lexer.Token {
+	return r.tok
+}
+
+func (r *RangeExpr) String() string {
+	return script.Stringify(r)
+}
+
+func (r *RangeExpr) expr() {}
+
+// ConditionalExpr is cond ? then : else. Only one of Then and Else is evaluated.
+type ConditionalExpr struct {
+	Cond, Then, Else Expr
+	tok              lexer.Token
+}
+
+func (c *ConditionalExpr) Tok() lexer.Token {
+	return c.tok
+}
+
+func (c *ConditionalExpr) String() string {
+	return script.Stringify(c)
+}
+
+func (c *ConditionalExpr) expr() {}
+
+// MemberExpr is object.name, the value of the key name in a host map. object?.name is nil if the object is nil.
+type MemberExpr struct {
+	Object Expr
+	// Name is not a variable.
+	Name     *Identifier
+	Optional bool
+}
+
+func (m *MemberExpr) Tok() lexer.Token {
+	return m.Object.Tok()
+}
+
+func (m *MemberExpr) String() string {
+	return script.Stringify(m)
+}
+
+func (m *MemberExpr) expr() {}
+
+// YieldExpr is yield [value]. It suspends the running coroutine and evaluates to the value it is resumed with.
+type YieldExpr struct {
+	// Value is nil if the coroutine yields nil.
+	Value Expr
+	tok   lexer.Token
+}
+
+func (y *YieldExpr) Tok() lexer.Token {
+	return y.tok
+}
+
+func (y *YieldExpr) String() string {
+	return script.Stringify(y)
+}
+
+func (y *YieldExpr) expr() {}
+
 type FunctionExpr struct {
 	Params     []*Identifier
 	Body       *BlockStmt
 	IsVariadic bool
+	tok        lexer.Token
 }
 
 func (f *FunctionExpr) Tok() lexer.Token {
-	return f.Params[0].Tok()
+	return f.tok
 }
 
 func (f *FunctionExpr) String() string {
@@ -134,6 +223,8 @@ func (f *CallExpr) expr() {}
 type SubscriptExpr struct {
 	Index Expr
 	Array Expr
+	// Optional is set for array?[index], which is nil if the array is nil.
+	Optional bool
 }
 
 func (s *SubscriptExpr) Tok() lexer.Token {
@@ -146,6 +237,25 @@ func (s *SubscriptExpr) String() string {
 
 func (s *SubscriptExpr) expr() {}
 
+// SliceExpr is array[low:high], the elements of the array from low up to, but excluding, high.
+type SliceExpr struct {
+	Array Expr
+	// Low and High are nil if they are omitted, as in array[:high] or array[low:].
+	Low, High Expr
+	// Optional is set for array?[low:high], which is nil if the array is nil.
+	Optional bool
+}
+
+func (s *SliceExpr) Tok() lexer.Token {
+	return s.Array.Tok()
+}
+
+func (s *SliceExpr) String() string {
+	return script.Stringify(s)
+}
+
+func (s *SliceExpr) expr() {}
+
 type ArrayExpr struct {
 	Elements []Expr
 }
@@ -193,8 +303,31 @@ func (d *DeclareStmt) String() string {
 
 func (d *DeclareStmt) stmt() {}
 
+// FuncDeclStmt is a named function declaration, fn name(params) {}. Declarations at the top level of a program are
+// hoisted, so they can be called before they appear.
+type FuncDeclStmt struct {
+	Name *Identifier
+	Func *FunctionExpr
+}
+
+func (f *FuncDeclStmt) Tok() lexer.Token {
+	return f.Func.Tok()
+}
+
+func (f *FuncDeclStmt) String() string {
+	return script.Stringify(f)
+}
+
+func (f *FuncDeclStmt) stmt() {}
+
 type BlockStmt struct {
 	Statements []Stmt
+	closing    lexer.Token
+}
+
+// Closing Returns the closing brace of the block.
+func (b *BlockStmt) Closing() lexer.Token {
+	return b.closing
 }
 
 func (b *BlockStmt) Tok() lexer.Token {
@@ -216,6 +349,9 @@ type AssignStmt struct {
 }
 
 func (a *AssignStmt) Tok() lexer.Token {
+	if a.Ident == nil {
+		return a.Expr.Tok()
+	}
 	return a.Ident.Tok()
 }
 
@@ -241,6 +377,31 @@ func (a *ArrayAssignStmt) String() string {
 
 func (a *ArrayAssignStmt) stmt() {}
 
+// CompoundAssignStmt is target op= value, target++ or target--. The target is an identifier or a subscript, which is
+// evaluated only once. It is compiled through Lower.
+type CompoundAssignStmt struct {
+	Target Expr
+	// Value is nil for ++ and --.
+	Value Expr
+	// tok is the assignment operator, e.g. += or ++.
+	tok lexer.Token
+}
+
+func (c *CompoundAssignStmt) Tok() lexer.Token {
+	return c.Target.Tok()
+}
+
+// Operator Returns the token of the assignment operator, e.g. += or ++.
+func (c *CompoundAssignStmt) Operator() lexer.Token {
+	return c.tok
+}
+
+func (c *CompoundAssignStmt) String() string {
+	return script.Stringify(c)
+}
+
+func (c *CompoundAssignStmt) stmt() {}
+
 type ConditionalStmt struct {
 	Cond  Expr
 	Block *BlockStmt
@@ -259,10 +420,11 @@ func (i *ConditionalStmt) stmt() {}
 
 type ReturnStmt struct {
 	Returned []Expr
+	tok      lexer.Token
 }
 
 func (r *ReturnStmt) Tok() lexer.Token {
-	return r.Returned[0].Tok()
+	return r.tok
 }
 
 func (r *ReturnStmt) String() string {
@@ -272,13 +434,16 @@ func (r *ReturnStmt) String() string {
 func (r *ReturnStmt) stmt() {}
 
 type ForStmt struct {
+	// Label is the name of a labeled loop, as in outer: for {}, or nil.
+	Label        *Identifier
 	Init, Update Stmt
 	Cond         Expr
 	Stmt         Stmt
+	tok          lexer.Token
 }
 
 func (l *ForStmt) Tok() lexer.Token {
-	return l.Stmt.Tok()
+	return l.tok
 }
 
 func (l *ForStmt) String() string {
@@ -287,8 +452,72 @@ func (l *ForStmt) String() string {
 
 func (l *ForStmt) stmt() {}
 
+// ForInStmt is for value in iterable {} or for key, value in iterable {}. Key is nil in the first form.
+type ForInStmt struct {
+	// Label is the name of a labeled loop, or nil, see ForStmt.
+	Label      *Identifier
+	Key, Value *Identifier
+	Iterable   Expr
+	Stmt       *BlockStmt
+	tok        lexer.Token
+}
+
+func (l *ForInStmt) Tok() lexer.Token {
+	return l.tok
+}
+
+func (l *ForInStmt) String() string {
+	return script.Stringify(l)
+}
+
+func (l *ForInStmt) stmt() {}
+
+// BreakStmt is break [label]. Label is nil for the innermost loop.
+// MatchStmt is match subject { arms }. The first arm whose pattern matches the subject and whose guard holds runs.
+type MatchStmt struct {
+	Subject Expr
+	Arms    []*MatchArm
+	tok     lexer.Token
+	closing lexer.Token
+}
+
+func (m *MatchStmt) Tok() lexer.Token {
+	return m.tok
+}
+
+// Closing Returns the closing brace of the statement.
+func (m *MatchStmt) Closing() lexer.Token {
+	return m.closing
+}
+
+func (m *MatchStmt) String() string {
+	return script.Stringify(m)
+}
+
+func (m *MatchStmt) stmt() {}
+
+// MatchArm is patterns [if guard] => body. The arm matches if one of the patterns does. Patterns are literals,
+// negative numbers, ranges of integer literals, arrays of patterns and identifiers, which bind the matched value. The
+// identifier _ matches anything without binding it.
+type MatchArm struct {
+	Patterns []Expr
+	// Guard is nil if the arm has no guard.
+	Guard Expr
+	// Body is a block or a single statement.
+	Body Stmt
+}
+
+func (m *MatchArm) Tok() lexer.Token {
+	return m.Patterns[0].Tok()
+}
+
+func (m *MatchArm) String() string {
+	return script.Stringify(m)
+}
+
 type BreakStmt struct {
-	tok lexer.Token
+	Label *Identifier
+	tok   lexer.Token
 }
 
 func (b *BreakStmt) Tok() lexer.Token {
@@ -301,8 +530,10 @@ func (b *BreakStmt) String() string {
 
 func (b *BreakStmt) stmt() {}
 
+// ContinueStmt is continue [label]. Label is nil for the innermost loop.
 type ContinueStmt struct {
-	tok lexer.Token
+	Label *Identifier
+	tok   lexer.Token
 }
 
 func (c *ContinueStmt) Tok() lexer.Token {
@@ -315,16 +546,69 @@ func (c *ContinueStmt) String() string {
 
 func (c *ContinueStmt) stmt() {}
 
-type exprStmt struct {
+// ExprStmt is an expression evaluated for its side effects, e.g. a call. Its result is discarded.
+type ExprStmt struct {
 	Expr Expr
 }
 
-func (e *exprStmt) Tok() lexer.Token {
+func (e *ExprStmt) Tok() lexer.Token {
 	return e.Expr.Tok()
 }
 
-func (e *exprStmt) String() string {
+func (e *ExprStmt) String() string {
 	return script.Stringify(e)
 }
 
-func (e *exprStmt) stmt() {}
+func (e *ExprStmt) stmt() {}
+
+// TryStmt is try {} catch (e) {} finally {}. At least one of Catch and Finally is set. Param is the optional name the
+// caught value is bound to.
+type TryStmt struct {
+	Body    *BlockStmt
+	Param   *Identifier
+	Catch   *BlockStmt
+	Finally *BlockStmt
+	tok     lexer.Token
+}
+
+func (t *TryStmt) Tok() lexer.Token {
+	return t.tok
+}
+
+func (t *TryStmt) String() string {
+	return script.Stringify(t)
+}
+
+func (t *TryStmt) stmt() {}
+
+type ThrowStmt struct {
+	Expr Expr
+	tok  lexer.Token
+}
+
+func (t *ThrowStmt) Tok() lexer.Token {
+	return t.tok
+}
+
+func (t *ThrowStmt) String() string {
+	return script.Stringify(t)
+}
+
+func (t *ThrowStmt) stmt() {}
+
+// DeferStmt is defer call(...). The callee and the arguments are evaluated by the statement, the call is made when the
+// enclosing function returns.
+type DeferStmt struct {
+	Call *CallExpr
+	tok  lexer.Token
+}
+
+func (d *DeferStmt) Tok() lexer.Token {
+	return d.tok
+}
+
+func (d *DeferStmt) String() string {
+	return script.Stringify(d)
+}
+
+func (d *DeferStmt) stmt() {}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"script/lexer"
+	"strconv"
 )
 
 // https://en.cppreference.com/w/cpp/language/operator_precedence
@@ -106,8 +107,8 @@ func (p *parser) expectLFB() error {
 		_, err := p.expect(lexer.LF, "LF")
 		return err
 	case lexer.CLOSE_BRACE:
-		_, err := p.expect(lexer.CLOSE_BRACE, "Close Brace")
-		return err
+		// The closing brace is consumed by parseBlockStmt.
+		return nil
 	default:
 		_, err := p.expect(lexer.EOF, "LF, close brace or EOF")
 		return err
@@ -128,9 +129,31 @@ func (p *parser) parseStmt() (Stmt, error) {
 	case lexer.FOR:
 		return p.parseForStmt()
 	case lexer.CONTINUE:
-		return &ContinueStmt{p.consume()}, nil
+		s := &ContinueStmt{tok: p.consume()}
+		var err error
+		s.Label, err = p.parseLabel()
+		return s, err
 	case lexer.BREAK:
-		return &BreakStmt{p.consume()}, nil
+		s := &BreakStmt{tok: p.consume()}
+		var err error
+		s.Label, err = p.parseLabel()
+		return s, err
+	case lexer.IDENTIFIER:
+		if p.get(1).Id == lexer.COLON && p.get(2).Id == lexer.FOR {
+			return p.parseLabeledStmt()
+		}
+	case lexer.FN:
+		if p.get(1).Id == lexer.IDENTIFIER {
+			return p.parseFuncDeclStmt()
+		}
+	case lexer.TRY:
+		return p.parseTryStmt()
+	case lexer.THROW:
+		return p.parseThrowStmt()
+	case lexer.DEFER:
+		return p.parseDeferStmt()
+	case lexer.MATCH:
+		return p.parseMatchStmt()
 	default:
 	}
 
@@ -146,44 +169,166 @@ func (p *parser) parseStmt() (Stmt, error) {
 			return p.parseDeclareStmt(n)
 		case lexer.EQUALS:
 			return p.parseAssignStmt(n)
-		case lexer.PLUS_PLUS:
-			p.consume()
-			return &AssignStmt{
-				Ident: n,
-				Expr: &BinaryExpr{
-					Left:     n,
-					Operator: lexer.PLUS,
-					Right:    &Number{Value: "1", tok: p.get(0)},
-				},
-			}, nil
-		case lexer.MINUS_MINUS:
-			p.consume()
-			return &AssignStmt{
-				Ident: n,
-				Expr: &BinaryExpr{
-					Left:     n,
-					Operator: lexer.MINUS,
-					Right:    &Number{Value: "1", tok: p.get(0)},
-				},
-			}, nil
+		case lexer.PLUS_EQUALS, lexer.MINUS_EQUALS, lexer.ASTERISK_EQUALS, lexer.SLASH_EQUALS, lexer.PERCENT_EQUALS,
+			lexer.PLUS_PLUS, lexer.MINUS_MINUS:
+			return p.parseCompoundAssignStmt(n)
 		default:
 			return nil, lexer.NewTokError(p.get(1), "expected statement (identifier)")
 		}
 	case *SubscriptExpr:
 		switch p.get(0).Id {
 		case lexer.EQUALS:
+			if n.Optional {
+				return nil, lexer.NewTokError(p.get(0), "cannot assign to an optional subscript")
+			}
 			return p.parseArrayAssignStmt(n)
+		case lexer.PLUS_EQUALS, lexer.MINUS_EQUALS, lexer.ASTERISK_EQUALS, lexer.SLASH_EQUALS, lexer.PERCENT_EQUALS,
+			lexer.PLUS_PLUS, lexer.MINUS_MINUS:
+			if n.Optional {
+				return nil, lexer.NewTokError(p.get(0), "cannot assign to an optional subscript")
+			}
+			return p.parseCompoundAssignStmt(n)
 		default:
 			return nil, lexer.NewTokError(p.get(1), "expected statement (subscript)")
 		}
+	case *SliceExpr:
+		switch p.get(0).Id {
+		case lexer.EQUALS, lexer.PLUS_EQUALS, lexer.MINUS_EQUALS, lexer.ASTERISK_EQUALS, lexer.SLASH_EQUALS,
+			lexer.PERCENT_EQUALS, lexer.PLUS_PLUS, lexer.MINUS_MINUS:
+			return nil, lexer.NewTokError(p.get(0), "cannot assign to a slice")
+		}
+		return nil, lexer.NewTokError(p.get(1), fmt.Sprintf("expected statement (%T)", n))
+	case *MemberExpr:
+		switch p.get(0).Id {
+		case lexer.EQUALS, lexer.PLUS_EQUALS, lexer.MINUS_EQUALS, lexer.ASTERISK_EQUALS, lexer.SLASH_EQUALS,
+			lexer.PERCENT_EQUALS, lexer.PLUS_PLUS, lexer.MINUS_MINUS:
+			// Members are read from host maps, which scripts cannot modify.
+			return nil, lexer.NewTokError(p.get(0), "cannot assign to a member")
+		}
+		return nil, lexer.NewTokError(p.get(1), fmt.Sprintf("expected statement (%T)", n))
 	case *CallExpr:
 		return p.parseCallStmt(n)
+	case *YieldExpr:
+		return &ExprStmt{Expr: n}, nil
 	default:
 		return nil, lexer.NewTokError(p.get(1), fmt.Sprintf("expected statement (%T)", n))
 	}
 
 }
 
+func (p *parser) parseMatchStmt() (Stmt, error) {
+	tok, err := p.expect(lexer.MATCH, "expected match")
+	if err != nil {
+		return nil, err
+	}
+	s := &MatchStmt{tok: tok}
+	if s.Subject, err = p.parseExpr(); err != nil {
+		return nil, errors.Join(err, lexer.NewTokError(tok, "match statement"))
+	}
+	if _, err := p.expect(lexer.OPEN_BRACE, "open brace"); err != nil {
+		return nil, err
+	}
+
+	for {
+		for p.get(0).Id == lexer.LF {
+			p.consume()
+		}
+		if p.done() || p.get(0).Id == lexer.CLOSE_BRACE {
+			break
+		}
+		arm, err := p.parseMatchArm()
+		if err != nil {
+			return nil, errors.Join(err, lexer.NewTokError(tok, "match arm"))
+		}
+		if err := p.expectLFB(); err != nil {
+			return nil, err
+		}
+		s.Arms = append(s.Arms, arm)
+	}
+
+	if s.closing, err = p.expect(lexer.CLOSE_BRACE, "close brace"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (p *parser) parseMatchArm() (*MatchArm, error) {
+	arm := &MatchArm{}
+	for {
+		pattern, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		arm.Patterns = append(arm.Patterns, pattern)
+		if p.get(0).Id != lexer.COMMA {
+			break
+		}
+		p.consume()
+	}
+
+	if p.get(0).Id == lexer.IF {
+		p.consume()
+		guard, err := p.parseExpr()
+		if err != nil {
+			return nil, errors.Join(err, lexer.NewTokError(p.get(0), "match guard"))
+		}
+		arm.Guard = guard
+	}
+
+	if _, err := p.expect(lexer.ARROW, "expected =>"); err != nil {
+		return nil, err
+	}
+	if p.get(0).Id == lexer.OPEN_BRACE {
+		body, err := p.parseBlockStmt()
+		if err != nil {
+			return nil, err
+		}
+		arm.Body = body
+		return arm, nil
+	}
+
+	body, err := p.parseStmt()
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		return nil, lexer.NewTokError(p.get(0), "expected statement after =>")
+	}
+	arm.Body = body
+	return arm, nil
+}
+
+// parseLabel Parses the optional label of a break or continue statement.
+func (p *parser) parseLabel() (*Identifier, error) {
+	if p.get(0).Id != lexer.IDENTIFIER {
+		return nil, nil
+	}
+	return p.parseIdent()
+}
+
+// parseLabeledStmt Parses label: for ...
+func (p *parser) parseLabeledStmt() (Stmt, error) {
+	label, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(lexer.COLON, "expected colon after label"); err != nil {
+		return nil, err
+	}
+
+	stmt, err := p.parseForStmt()
+	if err != nil {
+		return nil, err
+	}
+	switch s := stmt.(type) {
+	case *ForStmt:
+		s.Label = label
+	case *ForInStmt:
+		s.Label = label
+	}
+	return stmt, nil
+}
+
 func (p *parser) parseConditionalStmt() (Stmt, error) {
 	if _, err := p.expect(lexer.IF, "expected if"); err != nil {
 		return nil, err
@@ -219,20 +364,102 @@ func (p *parser) parseConditionalStmt() (Stmt, error) {
 }
 
 func (p *parser) parseReturnStmt() (Stmt, error) {
-	if _, err := p.expect(lexer.RETURN, "expected return"); err != nil {
+	tok, err := p.expect(lexer.RETURN, "expected return")
+	if err != nil {
 		return nil, err
 	}
 
-	expressions, err := p.parseCommaSeparatedExpr(lexer.LF)
-	if err != nil {
-		return nil, err
+	expressions := make([]Expr, 0)
+	switch p.get(0).Id {
+	case lexer.LF, lexer.CLOSE_BRACE, lexer.EOF:
+	default:
+		for {
+			expr, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			expressions = append(expressions, expr)
+
+			if p.get(0).Id != lexer.COMMA {
+				break
+			}
+			p.consume()
+		}
 	}
 
 	return &ReturnStmt{
 		Returned: expressions,
+		tok:      tok,
 	}, nil
 }
 
+func (p *parser) parseTryStmt() (Stmt, error) {
+	tok, err := p.expect(lexer.TRY, "expected try")
+	if err != nil {
+		return nil, err
+	}
+	s := &TryStmt{tok: tok}
+	if s.Body, err = p.parseBlockStmt(); err != nil {
+		return nil, errors.Join(err, lexer.NewTokError(tok, "try statement"))
+	}
+
+	if p.get(0).Id == lexer.CATCH {
+		p.consume()
+		if p.get(0).Id == lexer.OPEN_PAREN {
+			p.consume()
+			if s.Param, err = p.parseIdent(); err != nil {
+				return nil, errors.Join(err, lexer.NewTokError(p.get(0), "catch clause"))
+			}
+			if _, err = p.expect(lexer.CLOSE_PAREN, "close paren in catch"); err != nil {
+				return nil, err
+			}
+		}
+		if s.Catch, err = p.parseBlockStmt(); err != nil {
+			return nil, errors.Join(err, lexer.NewTokError(p.get(0), "catch clause"))
+		}
+	}
+
+	if p.get(0).Id == lexer.FINALLY {
+		p.consume()
+		if s.Finally, err = p.parseBlockStmt(); err != nil {
+			return nil, errors.Join(err, lexer.NewTokError(p.get(0), "finally clause"))
+		}
+	}
+
+	if s.Catch == nil && s.Finally == nil {
+		return nil, lexer.NewTokError(p.get(0), "try statement expects catch or finally")
+	}
+	return s, nil
+}
+
+func (p *parser) parseThrowStmt() (Stmt, error) {
+	tok, err := p.expect(lexer.THROW, "expected throw")
+	if err != nil {
+		return nil, err
+	}
+	expr, err := p.parseExpr()
+	if err != nil {
+		return nil, errors.Join(err, lexer.NewTokError(p.get(0), "throw statement"))
+	}
+	return &ThrowStmt{Expr: expr, tok: tok}, nil
+}
+
+func (p *parser) parseDeferStmt() (Stmt, error) {
+	tok, err := p.expect(lexer.DEFER, "expected defer")
+	if err != nil {
+		return nil, err
+	}
+	expr, err := p.parseExpr()
+	if err != nil {
+		return nil, errors.Join(err, lexer.NewTokError(p.get(0), "defer statement"))
+	}
+	call, ok := expr.(*CallExpr)
+	if !ok {
+		return nil, NewNodeError(expr, "defer expects a function call")
+	}
+	return &DeferStmt{Call: call, tok: tok}, nil
+}
+
 func (p *parser) parseDeclareStmt(ident *Identifier) (Stmt, error) {
 	if _, err := p.expect(lexer.COLON_EQUALS, "expected :="); err != nil {
 		return nil, err
@@ -287,6 +514,21 @@ func (p *parser) parseArrayAssignStmt(sub *SubscriptExpr) (Stmt, error) {
 	}, nil
 }
 
+// parseCompoundAssignStmt Parses the operator and the value of target op= value, target++ or target--.
+func (p *parser) parseCompoundAssignStmt(target Expr) (Stmt, error) {
+	s := &CompoundAssignStmt{Target: target, tok: p.consume()}
+	if s.tok.Id == lexer.PLUS_PLUS || s.tok.Id == lexer.MINUS_MINUS {
+		return s, nil
+	}
+
+	value, err := p.parseExpr()
+	if err != nil {
+		return nil, errors.Join(err, lexer.NewTokError(s.tok, "expected expression"))
+	}
+	s.Value = value
+	return s, nil
+}
+
 func (p *parser) parseBlockStmt() (*BlockStmt, error) {
 	if _, err := p.expect(lexer.OPEN_BRACE, "open brace"); err != nil {
 		return nil, err
@@ -317,15 +559,78 @@ func (p *parser) parseBlockStmt() (*BlockStmt, error) {
 		block.Statements = append(block.Statements, stmt)
 	}
 
-	if _, err := p.expect(lexer.CLOSE_BRACE, "close brace"); err != nil {
+	closing, err := p.expect(lexer.CLOSE_BRACE, "close brace")
+	if err != nil {
 		return nil, err
 	}
+	block.closing = closing
 
 	return block, nil
 }
 
 func (p *parser) parseExpr() (Expr, error) {
-	return p.parseBinaryExprLogicalOr()
+	return p.parseConditional()
+}
+
+// parseConditional Parses cond ? then : else, which binds looser than ranges and associates to the right.
+func (p *parser) parseConditional() (Expr, error) {
+	cond, err := p.parseRange()
+	if err != nil || p.get(0).Id != lexer.QUESTION {
+		return cond, err
+	}
+
+	tok := p.consume()
+	then, err := p.parseExpr()
+	if err != nil {
+		return nil, errors.Join(err, lexer.NewTokError(tok, "conditional expression"))
+	}
+	if _, err := p.expect(lexer.COLON, "colon in conditional expression"); err != nil {
+		return nil, err
+	}
+	els, err := p.parseConditional()
+	if err != nil {
+		return nil, errors.Join(err, lexer.NewTokError(tok, "conditional expression"))
+	}
+	return &ConditionalExpr{Cond: cond, Then: then, Else: els, tok: tok}, nil
+}
+
+// parseRange Parses start..end, which binds looser than all binary operators.
+func (p *parser) parseRange() (Expr, error) {
+	start, err := p.parseBinaryExprCoalesce()
+	if err != nil || p.get(0).Id != lexer.DOT_DOT {
+		return start, err
+	}
+
+	tok := p.consume()
+	end, err := p.parseBinaryExprCoalesce()
+	if err != nil {
+		return nil, errors.Join(err, lexer.NewTokError(tok, "range"))
+	}
+	return &RangeExpr{Start: start, End: end, tok: tok}, nil
+}
+
+// parseBinaryExprCoalesce Parses a ?? b, which is b if a is nil.
+func (p *parser) parseBinaryExprCoalesce() (Expr, error) {
+	left, err := p.parseBinaryExprLogicalOr()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.get(0).Id == lexer.QUESTION_QUESTION {
+		operator := p.consume()
+		var right Expr
+		if right, err = p.parseBinaryExprLogicalOr(); err != nil {
+			return nil, err
+		}
+
+		left = &BinaryExpr{
+			Left:     left,
+			Operator: operator.Id,
+			Right:    right,
+		}
+	}
+
+	return left, nil
 }
 
 func (p *parser) parseBinaryExprLogicalOr() (Expr, error) {
@@ -352,7 +657,7 @@ func (p *parser) parseBinaryExprLogicalOr() (Expr, error) {
 }
 
 func (p *parser) parseBinaryExprLogicalAnd() (Expr, error) {
-	left, err := p.parseBinaryExprEquality()
+	left, err := p.parseBinaryExprBitwiseOr()
 	if err != nil {
 		return nil, err
 	}
@@ -360,7 +665,30 @@ func (p *parser) parseBinaryExprLogicalAnd() (Expr, error) {
 	for p.get(0).Id == lexer.AND_AND {
 		operator := p.consume()
 		var right Expr
-		if right, err = p.parseBinaryExprEquality(); err != nil {
+		if right, err = p.parseBinaryExprBitwiseOr(); err != nil {
+			return nil, err
+		}
+
+		left = &BinaryExpr{
+			Left:     left,
+			Operator: operator.Id,
+			Right:    right,
+		}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseBinaryExprBitwiseOr() (Expr, error) {
+	left, err := p.parseBinaryExprBitwiseXOR()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.get(0).Id == lexer.PIPE {
+		operator := p.consume()
+		var right Expr
+		if right, err = p.parseBinaryExprBitwiseXOR(); err != nil {
 			return nil, err
 		}
 
@@ -374,75 +702,51 @@ func (p *parser) parseBinaryExprLogicalAnd() (Expr, error) {
 	return left, nil
 }
 
-//
-//func (p *parser) parseBinaryExprBitwiseOr() (Caller, error) {
-//	left, err := p.parseBinaryExprRelative()
-//	if err != nil {
-//		return nil, err
-//	}
-//
-//	for p.get(0).Id == lexer.EQUALS_EQUALS || p.get(0).Id == lexer.EXCLAMATION_EQUALS {
-//		operator := p.consume()
-//		var right Caller
-//		if right, err = p.parseBinaryExprRelative(); err != nil {
-//			return nil, err
-//		}
-//
-//		left = &BinaryExpr{
-//			Left:     left,
-//			Operator: operator.Id,
-//			Right:    right,
-//		}
-//	}
-//
-//	return left, nil
-//}
-//
-//func (p *parser) parseBinaryExprBitwiseXOR() (Caller, error) {
-//	left, err := p.parseBinaryExprRelative()
-//	if err != nil {
-//		return nil, err
-//	}
-//
-//	for p.get(0).Id == lexer.EQUALS_EQUALS || p.get(0).Id == lexer.EXCLAMATION_EQUALS {
-//		operator := p.consume()
-//		var right Caller
-//		if right, err = p.parseBinaryExprRelative(); err != nil {
-//			return nil, err
-//		}
-//
-//		left = &BinaryExpr{
-//			Left:     left,
-//			Operator: operator.Id,
-//			Right:    right,
-//		}
-//	}
-//
-//	return left, nil
-//}
-//
-//func (p *parser) parseBinaryExprBitwiseAnd() (Caller, error) {
-//	left, err := p.parseBinaryExprRelative()
-//	if err != nil {
-//		return nil, err
-//	}
-//
-//	for p.get(0).Id == lexer.EQUALS_EQUALS || p.get(0).Id == lexer.EXCLAMATION_EQUALS {
-//		operator := p.consume()
-//		var right Caller
-//		if right, err = p.parseBinaryExprRelative(); err != nil {
-//			return nil, err
-//		}
-//
-//		left = &BinaryExpr{
-//			Left:     left,
-//			Operator: operator.Id,
-//			Right:    right,
-//		}
-//	}
-//
-//	return left, nil
-//}
+func (p *parser) parseBinaryExprBitwiseXOR() (Expr, error) {
+	left, err := p.parseBinaryExprBitwiseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.get(0).Id == lexer.CIRCUMFLEX {
+		operator := p.consume()
+		var right Expr
+		if right, err = p.parseBinaryExprBitwiseAnd(); err != nil {
+			return nil, err
+		}
+
+		left = &BinaryExpr{
+			Left:     left,
+			Operator: operator.Id,
+			Right:    right,
+		}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseBinaryExprBitwiseAnd() (Expr, error) {
+	left, err := p.parseBinaryExprEquality()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.get(0).Id == lexer.AND {
+		operator := p.consume()
+		var right Expr
+		if right, err = p.parseBinaryExprEquality(); err != nil {
+			return nil, err
+		}
+
+		left = &BinaryExpr{
+			Left:     left,
+			Operator: operator.Id,
+			Right:    right,
+		}
+	}
+
+	return left, nil
+}
 
 func (p *parser) parseBinaryExprEquality() (Expr, error) {
 	left, err := p.parseBinaryExprRelative()
@@ -468,12 +772,35 @@ func (p *parser) parseBinaryExprEquality() (Expr, error) {
 }
 
 func (p *parser) parseBinaryExprRelative() (Expr, error) {
-	left, err := p.parseBinaryExprAdditive()
+	left, err := p.parseBinaryExprShift()
 	if err != nil {
 		return nil, err
 	}
 
 	for p.get(0).Id == lexer.LESS_THAN || p.get(0).Id == lexer.GREATER_THAN || p.get(0).Id == lexer.LESS_THAN_EQUALS || p.get(0).Id == lexer.GREATER_THAN_EQUALS {
+		operator := p.consume()
+		var right Expr
+		if right, err = p.parseBinaryExprShift(); err != nil {
+			return nil, err
+		}
+
+		left = &BinaryExpr{
+			Left:     left,
+			Operator: operator.Id,
+			Right:    right,
+		}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseBinaryExprShift() (Expr, error) {
+	left, err := p.parseBinaryExprAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.get(0).Id == lexer.LESS_LESS || p.get(0).Id == lexer.GREATER_GREATER {
 		operator := p.consume()
 		var right Expr
 		if right, err = p.parseBinaryExprAdditive(); err != nil {
@@ -519,7 +846,7 @@ func (p *parser) parseBinaryExprMultiplicative() (Expr, error) {
 		return nil, err
 	}
 
-	for p.get(0).Id == lexer.ASTERISK || p.get(0).Id == lexer.SLASH {
+	for p.get(0).Id == lexer.ASTERISK || p.get(0).Id == lexer.SLASH || p.get(0).Id == lexer.PERCENT {
 		operator := p.consume()
 		var right Expr
 		if right, err = p.parseUnary(); err != nil {
@@ -557,20 +884,53 @@ func (p *parser) parseIdents() ([]*Identifier, error) {
 	return idents, nil
 }
 
+// parseUnary Parses a unary operator, which binds tighter than the binary operators except **, so -a + b is (-a) + b
+// and -a ** b is -(a ** b).
 func (p *parser) parseUnary() (Expr, error) {
 	switch p.get(0).Id {
-	case lexer.EXCLAMATION, lexer.MINUS, lexer.PLUS:
+	case lexer.EXCLAMATION, lexer.MINUS, lexer.PLUS, lexer.TILDE:
 		operator := p.consume()
-		expr, err := p.parseExpr()
+		expr, err := p.parseUnary()
 		if err != nil {
 			return nil, err
 		}
 		return &UnaryExpr{Operator: operator.Id, Expr: expr}, nil
+	case lexer.YIELD:
+		return p.parseYieldExpr()
 	default:
-		return p.parseCall()
+		return p.parsePower()
 	}
 }
 
+// parsePower Parses a ** b, which associates to the right. The exponent may have a unary operator, as in 2 ** -1.
+func (p *parser) parsePower() (Expr, error) {
+	base, err := p.parseCall()
+	if err != nil || p.get(0).Id != lexer.ASTERISK_ASTERISK {
+		return base, err
+	}
+
+	operator := p.consume()
+	exponent, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return &BinaryExpr{Left: base, Operator: operator.Id, Right: exponent}, nil
+}
+
+func (p *parser) parseYieldExpr() (Expr, error) {
+	e := &YieldExpr{tok: p.consume()}
+	switch p.get(0).Id {
+	case lexer.LF, lexer.CLOSE_BRACE, lexer.CLOSE_PAREN, lexer.CLOSE_BRACKET, lexer.COMMA, lexer.EOF:
+	default:
+		value, err := p.parseExpr()
+		if err != nil {
+			return nil, errors.Join(err, lexer.NewTokError(e.tok, "yield expression"))
+		}
+		e.Value = value
+	}
+	return e, nil
+}
+
 func (p *parser) parseCall() (Expr, error) {
 	after, err := p.parseFunctionExpr()
 	if err != nil {
@@ -596,22 +956,67 @@ func (p *parser) parseCall() (Expr, error) {
 				Caller: after,
 				Args:   args,
 			}
-		case lexer.OPEN_BRACKET:
+		case lexer.DOT, lexer.QUESTION_DOT:
+			optional := p.consume().Id == lexer.QUESTION_DOT
+			name, err := p.parseIdent()
+			if err != nil {
+				return nil, errors.Join(err, lexer.NewTokError(p.get(0), "member name"))
+			}
+			after = &MemberExpr{
+				Object:   after,
+				Name:     name,
+				Optional: optional,
+			}
+		case lexer.QUESTION, lexer.OPEN_BRACKET:
+			// array?[index] is an optional subscript only if the bracket directly follows the question mark, otherwise
+			// the question mark starts a conditional expression.
+			optional := p.get(0).Id == lexer.QUESTION
+			if optional && (p.get(1).Id != lexer.OPEN_BRACKET || p.get(1).Pos != p.get(0).Pos+1) {
+				return after, nil
+			}
+			if optional {
+				p.consume()
+			}
 			p.consume()
 
-			index, err := p.parseExpr()
+			// The low bound of a slice may be omitted, as in array[:high].
+			var index Expr
+			if p.get(0).Id != lexer.COLON {
+				var err error
+				index, err = p.parseExpr()
+				if err != nil {
+					return nil, errors.Join(err, lexer.NewTokError(p.get(0), "in index"))
+				}
+			}
 
-			if err != nil {
-				return nil, errors.Join(err, lexer.NewTokError(p.get(0), "in index"))
+			if p.get(0).Id == lexer.COLON {
+				p.consume()
+				slice := &SliceExpr{Array: after, Low: index, Optional: optional}
+				if p.get(0).Id != lexer.CLOSE_BRACKET {
+					high, err := p.parseExpr()
+					if err != nil {
+						return nil, errors.Join(err, lexer.NewTokError(p.get(0), "in slice"))
+					}
+					slice.High = high
+				}
+				if _, err := p.expect(lexer.CLOSE_BRACKET, "close bracket"); err != nil {
+					return nil, err
+				}
+				after = slice
+				continue
 			}
 
+			if index == nil {
+				return nil, lexer.NewTokError(p.get(0), "expected index")
+			}
 			if _, err := p.expect(lexer.CLOSE_BRACKET, "close bracket"); err != nil {
 				return nil, err
 			}
 
 			after = &SubscriptExpr{
-				Array: after,
-				Index: index,
+				Array:    after,
+				Index:    index,
+				Optional: optional,
 			}
 		default:
 			return after, nil
@@ -621,12 +1026,31 @@ func (p *parser) parseCall() (Expr, error) {
 
 }
 
+func (p *parser) parseFuncDeclStmt() (Stmt, error) {
+	tok, err := p.expect(lexer.FN, "expected fn")
+	if err != nil {
+		return nil, err
+	}
+	name, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	f, err := p.parseFunction(tok)
+	if err != nil {
+		return nil, errors.Join(err, lexer.NewTokError(tok, "in declaration of function "+name.Symbol))
+	}
+	return &FuncDeclStmt{Name: name, Func: f}, nil
+}
+
 func (p *parser) parseFunctionExpr() (Expr, error) {
 	if p.get(0).Id != lexer.FN {
 		return p.parseNewExpr()
 	}
-	p.consume()
+	return p.parseFunction(p.consume())
+}
 
+// parseFunction Parses the parameters and the body of a function. tok is the fn keyword.
+func (p *parser) parseFunction(tok lexer.Token) (*FunctionExpr, error) {
 	if _, err := p.expect(lexer.OPEN_PAREN, "open paren in function"); err != nil {
 		return nil, err
 	}
@@ -654,6 +1078,7 @@ func (p *parser) parseFunctionExpr() (Expr, error) {
 		Params:     idents,
 		Body:       block,
 		IsVariadic: variadic,
+		tok:        tok,
 	}, nil
 }
 
@@ -704,6 +1129,8 @@ func (p *parser) parsePrimary() (Expr, error) {
 		return p.parseIdent()
 	case lexer.NUMBER:
 		return p.parseNumber()
+	case lexer.STRING:
+		return p.parseString()
 	case lexer.OPEN_PAREN:
 		return p.parsePrecedence()
 	case lexer.OPEN_BRACKET:
@@ -761,6 +1188,18 @@ func (p *parser) parseNumber() (*Number, error) {
 	return &Number{Value: t.Lexeme, tok: t}, nil
 }
 
+func (p *parser) parseString() (*String, error) {
+	t, err := p.expect(lexer.STRING, "string")
+	if err != nil {
+		return nil, err
+	}
+	value, err := strconv.Unquote(t.Lexeme)
+	if err != nil {
+		return nil, lexer.NewTokError(t, "invalid string literal")
+	}
+	return &String{Value: value, tok: t}, nil
+}
+
 func (p *parser) parseCommaSeparatedExpr(end lexer.TokenId) ([]Expr, error) {
 	list := make([]Expr, 0)
 
@@ -818,7 +1257,8 @@ func (p *parser) parseCommaSeparatedIdent(end []lexer.TokenId) ([]*Identifier, e
 }
 
 func (p *parser) parseForStmt() (Stmt, error) {
-	if _, err := p.expect(lexer.FOR, "expected for"); err != nil {
+	tok, err := p.expect(lexer.FOR, "expected for")
+	if err != nil {
 		return nil, err
 	}
 
@@ -830,12 +1270,17 @@ func (p *parser) parseForStmt() (Stmt, error) {
 
 		return &ForStmt{
 			Stmt: block,
+			tok:  tok,
 		}, nil
 	}
 
+	if p.get(0).Id == lexer.IDENTIFIER &&
+		(p.get(1).Id == lexer.IN || p.get(1).Id == lexer.COMMA && p.get(3).Id == lexer.IN) {
+		return p.parseForInStmt(tok)
+	}
+
 	divider := lexer.COMMA
 
-	var err error
 	var init, update Stmt
 	var check Expr
 
@@ -878,12 +1323,40 @@ func (p *parser) parseForStmt() (Stmt, error) {
 		Cond:   check,
 		Update: update,
 		Stmt:   block,
+		tok:    tok,
 	}, nil
 }
 
+// parseForInStmt Parses the rest of for [key,] value in iterable {} after the for keyword tok.
+func (p *parser) parseForInStmt(tok lexer.Token) (Stmt, error) {
+	s := &ForInStmt{tok: tok}
+	value, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	if p.get(0).Id == lexer.COMMA {
+		p.consume()
+		s.Key = value
+		if value, err = p.parseIdent(); err != nil {
+			return nil, errors.Join(err, lexer.NewTokError(p.get(0), "for-in value"))
+		}
+	}
+	s.Value = value
+
+	if _, err := p.expect(lexer.IN, "expected in"); err != nil {
+		return nil, err
+	}
+	if s.Iterable, err = p.parseExpr(); err != nil {
+		return nil, errors.Join(err, lexer.NewTokError(p.get(0), "for-in iterable"))
+	}
+	if s.Stmt, err = p.parseBlockStmt(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
 func (p *parser) parseCallStmt(n *CallExpr) (Stmt, error) {
-	return &AssignStmt{
-		Ident: nil,
-		Expr:  n,
+	return &ExprStmt{
+		Expr: n,
 	}, nil
 }
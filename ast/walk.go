@@ -0,0 +1,163 @@
+package ast
+
+import "fmt"
+
+// Visitor is called by Walk for every node. If Visit returns a non-nil visitor w, Walk visits the children of node
+// with w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk Traverses the tree rooted at node in depth-first order. Missing optional children, such as the else branch of
+// a ConditionalStmt, are skipped.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		walkStmts(v, n.Statements)
+	case *Identifier, *Number, *String:
+		// Leaves
+	case *BreakStmt:
+		walkOptional(v, n.Label)
+	case *ContinueStmt:
+		walkOptional(v, n.Label)
+	case *BinaryExpr:
+		Walk(v, n.Left)
+		Walk(v, n.Right)
+	case *UnaryExpr:
+		Walk(v, n.Expr)
+	case *FunctionExpr:
+		for _, param := range n.Params {
+			Walk(v, param)
+		}
+		Walk(v, n.Body)
+	case *CallExpr:
+		Walk(v, n.Caller)
+		walkExprs(v, n.Args)
+	case *SubscriptExpr:
+		Walk(v, n.Array)
+		Walk(v, n.Index)
+	case *SliceExpr:
+		Walk(v, n.Array)
+		walkOptional(v, n.Low)
+		walkOptional(v, n.High)
+	case *ArrayExpr:
+		walkExprs(v, n.Elements)
+	case *NewExpr:
+		Walk(v, n.TypeName)
+		walkOptional(v, n.Expression)
+	case *DeclareStmt:
+		Walk(v, n.Ident)
+		Walk(v, n.Expr)
+	case *FuncDeclStmt:
+		Walk(v, n.Name)
+		Walk(v, n.Func)
+	case *BlockStmt:
+		walkStmts(v, n.Statements)
+	case *AssignStmt:
+		if n.Ident != nil {
+			Walk(v, n.Ident)
+		}
+		Walk(v, n.Expr)
+	case *ArrayAssignStmt:
+		Walk(v, n.Ident)
+		Walk(v, n.Index)
+		Walk(v, n.Expr)
+	case *ConditionalStmt:
+		Walk(v, n.Cond)
+		Walk(v, n.Block)
+		walkOptional(v, n.Else)
+	case *ReturnStmt:
+		walkExprs(v, n.Returned)
+	case *ForStmt:
+		walkOptional(v, n.Label)
+		walkOptional(v, n.Init)
+		walkOptional(v, n.Cond)
+		walkOptional(v, n.Update)
+		Walk(v, n.Stmt)
+	case *ExprStmt:
+		Walk(v, n.Expr)
+	case *TryStmt:
+		Walk(v, n.Body)
+		walkOptional(v, n.Param)
+		walkOptional(v, n.Catch)
+		walkOptional(v, n.Finally)
+	case *ThrowStmt:
+		Walk(v, n.Expr)
+	case *DeferStmt:
+		Walk(v, n.Call)
+	case *YieldExpr:
+		walkOptional(v, n.Value)
+	case *MatchStmt:
+		Walk(v, n.Subject)
+		for _, arm := range n.Arms {
+			Walk(v, arm)
+		}
+	case *MatchArm:
+		for _, pattern := range n.Patterns {
+			Walk(v, pattern)
+		}
+		walkOptional(v, n.Guard)
+		Walk(v, n.Body)
+	case *ForInStmt:
+		walkOptional(v, n.Label)
+		walkOptional(v, n.Key)
+		Walk(v, n.Value)
+		Walk(v, n.Iterable)
+		Walk(v, n.Stmt)
+	case *CompoundAssignStmt:
+		Walk(v, n.Target)
+		walkOptional(v, n.Value)
+	case *ConditionalExpr:
+		Walk(v, n.Cond)
+		Walk(v, n.Then)
+		Walk(v, n.Else)
+	case *MemberExpr:
+		Walk(v, n.Object)
+		Walk(v, n.Name)
+	case *RangeExpr:
+		Walk(v, n.Start)
+		Walk(v, n.End)
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+func walkStmts(v Visitor, list []Stmt) {
+	for _, s := range list {
+		Walk(v, s)
+	}
+}
+
+func walkExprs(v Visitor, list []Expr) {
+	for _, e := range list {
+		Walk(v, e)
+	}
+}
+
+// walkOptional Walks node unless it is nil.
+func walkOptional(v Visitor, node Node) {
+	if !isNil(node) {
+		Walk(v, node)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect Traverses the tree rooted at node like Walk. It calls f(node) for every node, and f(nil) after the children
+// of a node. The children are skipped if f returns false.
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
@@ -0,0 +1,61 @@
+package ast_test
+
+import (
+	"fmt"
+	"script/ast"
+	"script/lexer"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, src string) *ast.Program {
+	t.Helper()
+	tokens, errs := lexer.Tokenize([]byte(src))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	program, errs := ast.Parse(tokens)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	return program
+}
+
+// typeName Returns the type of n without the package, e.g. BinaryExpr, or "end" for nil.
+func typeName(n ast.Node) string {
+	if n == nil {
+		return "end"
+	}
+	return strings.TrimPrefix(fmt.Sprintf("%T", n), "*ast.")
+}
+
+func TestInspect(t *testing.T) {
+	program := parse(t, "x := 1 + y\nif x { println(x) }\n")
+
+	var got []string
+	ast.Inspect(program, func(n ast.Node) bool {
+		got = append(got, typeName(n))
+		return true
+	})
+	want := "Program DeclareStmt Identifier end BinaryExpr Number end Identifier end end end " +
+		"ConditionalStmt Identifier end BlockStmt ExprStmt CallExpr Identifier end Identifier end end end end end end"
+	if strings.Join(got, " ") != want {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, " "), want)
+	}
+}
+
+func TestInspectSkipsChildren(t *testing.T) {
+	program := parse(t, "fn f(a) { return a + 1 }\nx := 2\n")
+
+	var numbers []string
+	ast.Inspect(program, func(n ast.Node) bool {
+		if number, ok := n.(*ast.Number); ok {
+			numbers = append(numbers, number.Value)
+		}
+		_, isFunc := n.(*ast.FuncDeclStmt)
+		return !isFunc
+	})
+	if strings.Join(numbers, " ") != "2" {
+		t.Errorf("got numbers %v, want the ones outside the function only", numbers)
+	}
+}
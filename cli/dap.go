@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"script"
+	"script/dap"
+)
+
+// dapCommand Implements ys dap [-listen addr], which runs the debug adapter on stdin and stdout or, with -listen, on
+// every connection accepted on a local TCP address.
+func dapCommand(args []string) int {
+	flags := flag.NewFlagSet("dap", flag.ExitOnError)
+	listen := flags.String("listen", "", "serve on a TCP address, e.g. 127.0.0.1:4711, instead of stdio")
+	flags.Parse(args)
+
+	script.PanicOnError = false
+
+	var err error
+	if *listen == "" {
+		err = dap.NewServer().Serve(os.Stdin, os.Stdout)
+	} else {
+		var l net.Listener
+		if l, err = net.Listen("tcp", *listen); err == nil {
+			fmt.Fprintf(os.Stderr, "listening on %s\n", l.Addr())
+			err = dap.ServeListener(l)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"script"
+	"script/compiler"
+	"script/vm"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// debugCommand Implements ys debug file.ys, a terminal debugger with gdb-like commands.
+func debugCommand(args []string) int {
+	flags := flag.NewFlagSet("debug", flag.ExitOnError)
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: ys debug file.ys")
+		return 2
+	}
+
+	file := flags.Arg(0)
+	src, err := os.ReadFile(file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 2
+	}
+
+	v := vm.New()
+	bc, _, err := compiler.CompileSource(src, v.Globals()...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+		return 1
+	}
+
+	d := v.Debug(bc)
+	d.SetSource(script.NewSource(file, src))
+	s := &debugger{d: d, out: os.Stdout}
+	s.loop(os.Stdin)
+	return 0
+}
+
+// debugger is a terminal session controlling a vm.Debugger.
+type debugger struct {
+	d   *vm.Debugger
+	out io.Writer
+}
+
+type debugCmd struct {
+	names []string
+	args  string
+	help  string
+	run   func(s *debugger, args []string)
+}
+
+var debugCmds []debugCmd
+
+func init() {
+	debugCmds = []debugCmd{
+		{[]string{"run", "r", "continue", "c"}, "", "run until a breakpoint or the end of the program", (*debugger).cont},
+		{[]string{"step", "s"}, "", "step to the next line, entering functions", (*debugger).step},
+		{[]string{"next", "n"}, "", "step to the next line of the current function", (*debugger).next},
+		{[]string{"finish"}, "", "run until the current function returns", (*debugger).finish},
+		{[]string{"stepi", "si"}, "", "execute one instruction", (*debugger).stepi},
+		{[]string{"break", "b"}, "LINE|*INSTR", "set a breakpoint on a line or an instruction", (*debugger).breakpoint},
+		{[]string{"delete", "d"}, "[ID]", "delete a breakpoint, or all breakpoints", (*debugger).delete},
+		{[]string{"info", "i"}, "breakpoints|locals|stack|frames", "show information about the program", (*debugger).info},
+		{[]string{"print", "p"}, "NAME", "print the value of a variable", (*debugger).print},
+		{[]string{"backtrace", "bt"}, "", "show the calls in progress and their variables", (*debugger).backtrace},
+		{[]string{"list", "l"}, "[LINE]", "list the source around the current or the given line", (*debugger).list},
+		{[]string{"disassemble", "x"}, "", "show the instructions around the current one", (*debugger).disassemble},
+		{[]string{"help", "h"}, "", "show this help", (*debugger).help},
+	}
+}
+
+// loop Reads commands from r until it is exhausted or quit is entered. An empty line repeats the last command.
+func (s *debugger) loop(r io.Reader) {
+	fmt.Fprintln(s.out, `Type "help" for a list of commands, "run" to start the program.`)
+	scanner := bufio.NewScanner(r)
+	last := ""
+	for {
+		fmt.Fprint(s.out, "(ys) ")
+		if !scanner.Scan() {
+			fmt.Fprintln(s.out)
+			return
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			line = last
+		}
+		last = line
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "quit" || fields[0] == "q" {
+			return
+		}
+
+		cmd := findDebugCmd(fields[0])
+		if cmd == nil {
+			fmt.Fprintf(s.out, "Undefined command: %q. Try \"help\".\n", fields[0])
+			continue
+		}
+		cmd.run(s, fields[1:])
+	}
+}
+
+func findDebugCmd(name string) *debugCmd {
+	for i := range debugCmds {
+		if slices.Contains(debugCmds[i].names, name) {
+			return &debugCmds[i]
+		}
+	}
+	return nil
+}
+
+func (s *debugger) cont([]string) {
+	s.stopped(s.d.Continue())
+}
+
+func (s *debugger) step([]string) {
+	s.stopped(s.d.StepIn())
+}
+
+func (s *debugger) next([]string) {
+	s.stopped(s.d.StepOver())
+}
+
+func (s *debugger) finish([]string) {
+	if s.d.Depth() == 0 {
+		fmt.Fprintln(s.out, `"finish" not meaningful in the outermost frame.`)
+		return
+	}
+	s.stopped(s.d.StepOut())
+}
+
+func (s *debugger) stepi([]string) {
+	s.stopped(s.d.StepInstruction())
+	s.instruction(s.d.Pointer(), "=> ")
+}
+
+// stopped Reports why the program stopped and where.
+func (s *debugger) stopped(reason vm.StopReason, err error) {
+	switch reason {
+	case vm.StopExited:
+		fmt.Fprintln(s.out, "[program exited]")
+		return
+	case vm.StopException:
+		fmt.Fprintf(s.out, "Program failed: %v\n", err)
+	case vm.StopBreakpoint:
+		fmt.Fprintf(s.out, "Breakpoint %d, ", s.d.Hit().ID)
+		fmt.Fprintln(s.out, s.location())
+	}
+
+	if line := s.d.Position().Line; line > 0 {
+		fmt.Fprintf(s.out, "%d\t%s\n", line, s.d.Source().Line(line))
+	}
+}
+
+// location Describes the current position, e.g. fib.ys:3:5 or instruction 12.
+func (s *debugger) location() string {
+	if p := s.d.Position(); p.Line > 0 {
+		return p.String()
+	}
+	return fmt.Sprintf("instruction %d", s.d.Pointer())
+}
+
+func (s *debugger) breakpoint(args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(s.out, "usage: break LINE|*INSTR")
+		return
+	}
+
+	instruction := strings.HasPrefix(args[0], "*")
+	n, err := strconv.Atoi(strings.TrimPrefix(args[0], "*"))
+	if err != nil {
+		fmt.Fprintf(s.out, "invalid location %q\n", args[0])
+		return
+	}
+
+	var b *vm.Breakpoint
+	if instruction {
+		b, err = s.d.BreakInstruction(n)
+	} else {
+		b, err = s.d.BreakLine(n)
+	}
+	if err != nil {
+		fmt.Fprintln(s.out, err)
+		return
+	}
+	fmt.Fprintf(s.out, "Breakpoint %d at %s\n", b.ID, s.where(b))
+}
+
+func (s *debugger) where(b *vm.Breakpoint) string {
+	if b.Pointer >= 0 {
+		return fmt.Sprintf("instruction %d", b.Pointer)
+	}
+	return fmt.Sprintf("%s:%d", s.d.Source().File, b.Line)
+}
+
+func (s *debugger) delete(args []string) {
+	if len(args) == 0 {
+		s.d.ClearAll()
+		return
+	}
+	for _, arg := range args {
+		id, err := strconv.Atoi(arg)
+		if err != nil || !s.d.Clear(id) {
+			fmt.Fprintf(s.out, "No breakpoint number %s.\n", arg)
+		}
+	}
+}
+
+func (s *debugger) info(args []string) {
+	what := ""
+	if len(args) > 0 {
+		what = args[0]
+	}
+
+	switch what {
+	case "breakpoints", "b":
+		if len(s.d.Breakpoints()) == 0 {
+			fmt.Fprintln(s.out, "No breakpoints.")
+			return
+		}
+		fmt.Fprintln(s.out, "Num\tHits\tWhere")
+		for _, b := range s.d.Breakpoints() {
+			fmt.Fprintf(s.out, "%d\t%d\t%s\n", b.ID, b.Hits, s.where(b))
+		}
+	case "locals":
+		for _, f := range s.d.Frames() {
+			s.variables(f, "")
+			if f.IsCall() {
+				break
+			}
+		}
+	case "stack":
+		stack := s.d.Stack()
+		if len(stack) == 0 {
+			fmt.Fprintln(s.out, "The stack is empty.")
+		}
+		for i := len(stack) - 1; i >= 0; i-- {
+			fmt.Fprintf(s.out, "%d\t%s\n", i, vm.Repr(stack[i]))
+		}
+	case "frames":
+		s.backtrace(nil)
+	default:
+		fmt.Fprintln(s.out, "usage: info breakpoints|locals|stack|frames")
+	}
+}
+
+// variables Prints the variables declared in f. Builtins of the global frame are left out.
+func (s *debugger) variables(f *vm.Frame, indent string) {
+	builtins := vm.Builtins()
+	for _, name := range f.Names() {
+		if f.Parent == nil && slices.Contains(builtins, name) {
+			continue
+		}
+		fmt.Fprintf(s.out, "%s%s = %s\n", indent, name, vm.Repr(f.Declared[name]))
+	}
+}
+
+func (s *debugger) print(args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(s.out, "usage: print NAME")
+		return
+	}
+	v, ok := s.d.Lookup(args[0])
+	if !ok {
+		fmt.Fprintf(s.out, "No symbol %q in current context.\n", args[0])
+		return
+	}
+	fmt.Fprintf(s.out, "%s = %s\n", args[0], vm.Repr(v))
+}
+
+// backtrace Prints the calls in progress, innermost first, like the traceback of a runtime error, each with its local
+// variables. The variables of main include the globals.
+func (s *debugger) backtrace([]string) {
+	src := s.d.Source()
+	for i, call := range s.d.Calls() {
+		name := call.Name
+		if name == "" {
+			name = "<anonymous>"
+		}
+		switch {
+		case call.Native:
+			fmt.Fprintf(s.out, "#%d %s (native)\n", i, name)
+		case call.Position.Line > 0:
+			fmt.Fprintf(s.out, "#%d %s at %s\n", i, name, call.Position)
+			if src != nil {
+				fmt.Fprintf(s.out, "    %s\n", strings.TrimSpace(src.Line(call.Position.Line)))
+			}
+		default:
+			fmt.Fprintf(s.out, "#%d %s at instruction %d\n", i, name, call.Pointer)
+		}
+		for f := call.Frame; f != nil; f = f.Parent {
+			s.variables(f, "      ")
+			if f.IsCall() {
+				break
+			}
+		}
+	}
+}
+
+func (s *debugger) list(args []string) {
+	src := s.d.Source()
+	current := s.d.Position().Line
+	center := current
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			fmt.Fprintf(s.out, "invalid line %q\n", args[0])
+			return
+		}
+		center = n
+	}
+	center = max(center, 1)
+
+	for line := max(1, center-5); line <= min(src.Lines(), center+5); line++ {
+		marker := "  "
+		if line == current {
+			marker = "=>"
+		}
+		fmt.Fprintf(s.out, "%s %3d\t%s\n", marker, line, src.Line(line))
+	}
+}
+
+func (s *debugger) disassemble([]string) {
+	pointer := s.d.Pointer()
+	for i := max(0, pointer-5); i < min(len(s.d.Bytecode()), pointer+6); i++ {
+		marker := "   "
+		if i == pointer {
+			marker = "=> "
+		}
+		s.instruction(i, marker)
+	}
+}
+
+func (s *debugger) instruction(i int, marker string) {
+	bc := s.d.Bytecode()
+	if i < 0 || i >= len(bc) {
+		return
+	}
+	arg := ""
+	if bc[i].Arg != nil {
+		arg = fmt.Sprintf("%v", bc[i].Arg)
+	}
+	fmt.Fprintf(s.out, "%s%3d\t%s\t%s\n", marker, i, bc[i].Op, arg)
+}
+
+func (s *debugger) help([]string) {
+	for _, cmd := range debugCmds {
+		usage := strings.Join(cmd.names, ", ")
+		if cmd.args != "" {
+			usage += " " + cmd.args
+		}
+		fmt.Fprintf(s.out, "%-40s %s\n", usage, cmd.help)
+	}
+	fmt.Fprintf(s.out, "%-40s %s\n", "quit, q", "exit the debugger")
+}
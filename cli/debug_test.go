@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"script"
+	"script/compiler"
+	"script/vm"
+	"strings"
+	"testing"
+)
+
+const backtraceProgram = `g := 10
+fn inner(n) {
+    try {
+        k := n * 2
+        println(k)
+    } catch (e) {
+        println(e)
+    }
+}
+fn outer(m) {
+    inner(m + 1)
+}
+outer(1)
+`
+
+// TestBacktrace Checks that bt shows the calls of a traceback, without the frames of blocks and try statements.
+func TestBacktrace(t *testing.T) {
+	v := vm.New()
+	var out bytes.Buffer
+	v.SetOutput(&out)
+	bc, _, err := compiler.CompileSource([]byte(backtraceProgram), v.Globals()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := v.Debug(bc)
+	d.SetSource(script.NewSource("bt.ys", []byte(backtraceProgram)))
+
+	s := &debugger{d: d, out: &out}
+	s.loop(strings.NewReader("break 5\nrun\n"))
+	out.Reset()
+	s.backtrace(nil)
+
+	want := `#0 inner at bt.ys:5:17
+    println(k)
+      k = 4
+      inner = <fn inner(n)>
+      n = 2
+#1 outer at bt.ys:11:5
+    inner(m + 1)
+      m = 1
+      outer = <fn outer(m)>
+#2 main at bt.ys:13:1
+    outer(1)
+      g = 10
+      inner = <fn inner(n)>
+      outer = <fn outer(m)>
+`
+	if got := out.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"script"
+	"script/format"
+)
+
+// fmtCommand Implements ys fmt [-w] [-d] [paths...]. Without flags the formatted source is written to stdout.
+func fmtCommand(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	write := flags.Bool("w", false, "write the result to the source file instead of stdout")
+	showDiff := flags.Bool("d", false, "display diffs instead of rewriting files")
+	flags.Parse(args)
+
+	files, err := sourceFiles(flags.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 2
+	}
+
+	status := 0
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			status = 2
+			continue
+		}
+
+		out, err := format.Source(src)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, locate(script.NewSource(file, src), err))
+			status = 2
+			continue
+		}
+
+		switch {
+		case *showDiff:
+			if d := diff(file, src, out); d != "" {
+				fmt.Print(d)
+			}
+		case *write:
+			if bytes.Equal(src, out) {
+				continue
+			}
+			if err := os.WriteFile(file, out, 0644); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				status = 2
+			}
+		default:
+			os.Stdout.Write(out)
+		}
+	}
+	return status
+}
+
+// sourceFiles Returns the script files given by paths. Directories are walked recursively.
+func sourceFiles(paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	files := make([]string, 0)
+	for _, root := range paths {
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, root)
+			continue
+		}
+
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() && path != root && d.Name()[0] == '.' {
+				return filepath.SkipDir
+			}
+			if !d.IsDir() && filepath.Ext(path) == ".ys" {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
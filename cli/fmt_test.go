@@ -0,0 +1,25 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFmtError(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "invalid.ys")
+	if err := os.WriteFile(file, []byte("x := 1\ny := (1 +\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var code int
+	out := captureStderr(t, func() {
+		code = fmtCommand([]string{file})
+	})
+	if code != 2 {
+		t.Errorf("fmt exited with %d, want 2", code)
+	}
+	if want := file + ":2:10: (EOF: ): expected primary expression\n"; out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"script"
 	"script/ast"
 	"script/compiler"
 	"script/lexer"
@@ -16,11 +17,32 @@ var example string
 
 const debug = false
 
+// commands are the subcommands of ys. Without a subcommand the embedded example is run.
+var commands = map[string]func(args []string) int{
+	"test":  testCommand,
+	"fmt":   fmtCommand,
+	"lsp":   lspCommand,
+	"debug": debugCommand,
+	"dap":   dapCommand,
+	"run":   runCommand,
+	"build": buildCommand,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		command, ok := commands[os.Args[1]]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "unknown command %q\n", os.Args[1])
+			os.Exit(2)
+		}
+		os.Exit(command(os.Args[2:]))
+	}
+
 	//fmt.Println("### Script ###")
 	//reader := bufio.NewReader(os.Stdin)
 
 	v := vm.New()
+	v.SetSource(script.NewSource("example.ys", []byte(example)))
 
 	//for {
 	//	fmt.Print("> ")
@@ -50,7 +72,7 @@ func main() {
 	}
 
 	bytecode := make(vm.Bytecode, 0)
-	err := compiler.Compile(&bytecode, p)
+	_, err := compiler.Compile(&bytecode, p)
 	if err != nil {
 		fmt.Printf("error: %+v\n", err)
 		os.Exit(1)
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"script"
+	"script/compiler"
+	"script/vm"
+	"strings"
+)
+
+// bytecodeExt is the file extension of compiled scripts, see ys build.
+const bytecodeExt = ".ysc"
+
+// overflowModes are the values of the -overflow flag of ys run.
+var overflowModes = map[string]vm.Overflow{
+	"wrap":    vm.OverflowWrap,
+	"error":   vm.OverflowError,
+	"promote": vm.OverflowPromote,
+}
+
+// runCommand Implements ys run [-O] [-profile] [-overflow wrap|error|promote] [-strict] file.ys|file.ysc.
+func runCommand(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	optimize := flags.Bool("O", false, "optimize the bytecode of a source file")
+	profile := flags.Bool("profile", false, "print how often the code was executed to stderr")
+	overflow := flags.String("overflow", "wrap", "what integer arithmetic does on overflow: wrap, error or promote")
+	strict := flags.Bool("strict", false, "raise an error for array indices out of bounds")
+	flags.Parse(args)
+	mode, ok := overflowModes[*overflow]
+	if flags.NArg() != 1 || !ok {
+		fmt.Fprintln(os.Stderr, "usage: ys run [-O] [-profile] [-overflow wrap|error|promote] [-strict] file.ys|file"+bytecodeExt)
+		return 2
+	}
+
+	v := vm.New()
+	v.SetOverflow(mode)
+	v.SetStrict(*strict)
+	bc, src, err := load(flags.Arg(0), v.Globals())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *optimize {
+		bc = compiler.Optimize(bc)
+	}
+
+	v.SetSource(src)
+	var p *vm.Profile
+	if *profile {
+		p = v.Profile()
+	}
+
+	err = v.Execute(bc)
+	if p != nil {
+		p.Write(os.Stderr)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// buildCommand Implements ys build [-o file.ysc] file.ys. The optimized bytecode is written together with the source,
+// so that errors can be reported with source positions.
+func buildCommand(args []string) int {
+	flags := flag.NewFlagSet("build", flag.ExitOnError)
+	output := flags.String("o", "", "output file, defaults to the source file with the extension "+bytecodeExt)
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: ys build [-o file"+bytecodeExt+"] file.ys")
+		return 2
+	}
+
+	file := flags.Arg(0)
+	bc, src, err := load(file, vm.New().Globals())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	var buf bytes.Buffer
+	if err := vm.Encode(&buf, compiler.Optimize(bc), src); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	if *output == "" {
+		*output = strings.TrimSuffix(file, filepath.Ext(file)) + bytecodeExt
+	}
+	if err := os.WriteFile(*output, buf.Bytes(), 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// load Compiles a source file or decodes a bytecode file. The returned source is nil if a bytecode file has none.
+// Compiler warnings are printed to stderr.
+func load(file string, globals []string) (vm.Bytecode, *script.Source, error) {
+	if filepath.Ext(file) == bytecodeExt {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error: %w", err)
+		}
+		defer f.Close()
+		bc, src, err := vm.Decode(f)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", file, err)
+		}
+		return bc, src, nil
+	}
+
+	text, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error: %w", err)
+	}
+	src := script.NewSource(file, text)
+	bc, warnings, err := compiler.CompileSource(text, globals...)
+	for _, w := range warnings {
+		fmt.Fprintln(os.Stderr, "warning:", locate(src, w))
+	}
+	if err != nil {
+		return nil, nil, locate(src, err)
+	}
+	return bc, src, nil
+}
+
+// locate Returns err prefixed with its source location, or with the file name if the position is unknown.
+func locate(src *script.Source, err error) error {
+	var posErr *script.PosError
+	if errors.As(err, &posErr) && posErr.Pos >= 0 {
+		return fmt.Errorf("%s: %s", src.Position(posErr.Pos), posErr.Message)
+	}
+	return fmt.Errorf("%s: %w", src.File, err)
+}
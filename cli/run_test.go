@@ -0,0 +1,102 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"script/compiler"
+	"strings"
+	"testing"
+)
+
+func TestBuildAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "prog.ys")
+	if err := os.WriteFile(file, []byte("x := 2 * 3\ny := x / 0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := buildCommand([]string{file}); code != 0 {
+		t.Fatalf("build exited with %d", code)
+	}
+	compiled, _, err := load(file, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, src, err := load(filepath.Join(dir, "prog"+bytecodeExt), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, compiler.Optimize(compiled)) {
+		t.Errorf("got\n%s\nwant the optimized program\n%s", decoded.String(), compiled.String())
+	}
+	if src == nil || src.File != file {
+		t.Errorf("got source %v, want %s", src, file)
+	}
+
+	if code := runCommand([]string{filepath.Join(dir, "prog"+bytecodeExt)}); code != 1 {
+		t.Errorf("running a failing program exited with %d, want 1", code)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.ys")
+	if err := os.WriteFile(invalid, []byte("x := (1 +\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := load(invalid, nil); err == nil || !strings.HasPrefix(err.Error(), invalid+":") {
+		t.Errorf("got %v, want an error at a position in %s", err, invalid)
+	}
+
+	garbage := filepath.Join(dir, "garbage"+bytecodeExt)
+	if err := os.WriteFile(garbage, []byte("garbage"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := load(garbage, nil); err == nil || !strings.Contains(err.Error(), "invalid bytecode") {
+		t.Errorf("got %v, want invalid bytecode", err)
+	}
+}
+
+func TestLoadWarnings(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "unused.ys")
+	if err := os.WriteFile(file, []byte("fn f() {\n  x := 1\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var err error
+	out := captureStderr(t, func() {
+		_, _, err = load(file, nil)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "warning: " + file + ":2:3: x declared and not used\n"; out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+// captureStderr Returns what f writes to os.Stderr.
+func captureStderr(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := os.Stderr
+	os.Stderr = w
+	f()
+	os.Stderr = stderr
+	w.Close()
+	out, _ := io.ReadAll(r)
+	return string(out)
+}
+
+func TestRunUsage(t *testing.T) {
+	for _, args := range [][]string{nil, {"a.ys", "b.ys"}, {"-overflow", "saturate", "a.ys"}} {
+		if code := runCommand(args); code != 2 {
+			t.Errorf("run %q exited with %d, want 2", args, code)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"script/testrunner"
+)
+
+// testCommand Implements ys test [-format text|tap|junit] [-o file] [-v] [paths...].
+func testCommand(args []string) int {
+	flags := flag.NewFlagSet("test", flag.ExitOnError)
+	format := flags.String("format", "text", "report format: text, tap or junit")
+	output := flags.String("o", "", "write the report to this file instead of stdout")
+	verbose := flags.Bool("v", false, "show script output of passing tests")
+	flags.Parse(args)
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	files, err := testrunner.Discover(paths)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 2
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 2
+		}
+		defer file.Close()
+		w = file
+	}
+
+	results := testrunner.Run(files)
+
+	switch *format {
+	case "text":
+		err = testrunner.WriteText(w, results, *verbose)
+	case "tap":
+		err = testrunner.WriteTAP(w, results)
+	case "junit":
+		err = testrunner.WriteJUnit(w, results)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown format %q\n", *format)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 2
+	}
+
+	if _, failed, errored := testrunner.Summary(results); failed+errored > 0 {
+		return 1
+	}
+	return 0
+}
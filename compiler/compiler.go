@@ -1,41 +1,163 @@
 package compiler
 
 import (
+	"errors"
 	"fmt"
+	"script"
 	"script/ast"
 	"script/lexer"
+	"script/resolver"
 	"script/vm"
-	"strconv"
-	"strings"
+	"unicode/utf8"
 )
 
 type compiler struct {
 	bc        *vm.Bytecode
 	loopBegin stack[int]
 	loopEnd   stack[int]
+	// loopLabels holds the labels of the loops being compiled, innermost last, or "" for unlabeled loops.
+	loopLabels stack[string]
+	// binding is the name of the variable the function expression compiled next is bound to, see compileBound.
+	binding string
+	// functions counts the function expressions being compiled.
+	functions int
+	// tries holds the try statements being compiled, innermost last, see leaveTries.
+	tries stack[tryContext]
 }
 
-func Compile(bytecode *vm.Bytecode, program *ast.Program) error {
+// tryContext is a try statement whose body, or whose catch block followed by a finally block, is being compiled.
+type tryContext struct {
+	// handler is set if an exception handler is installed for the code being compiled.
+	handler bool
+	finally *ast.BlockStmt
+	// loops and function are the number of enclosing loops and functions of the statement.
+	loops, function int
+}
+
+// Compile Compiles program into bytecode. globals are the names the host declares in addition to the builtins of
+// vm.New, see vm.VM.Globals. The program is checked by the resolver first and its errors are returned joined. Its
+// warnings, such as unused variables, are returned as position errors.
+func Compile(bytecode *vm.Bytecode, program *ast.Program, globals ...string) (warnings []error, err error) {
+	_, diagnostics := resolver.Resolve(program, append(vm.Builtins(), globals...))
+	if errs := resolver.Errors(diagnostics); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	warnings = resolver.Warnings(diagnostics)
+
 	c := &compiler{
 		bc:        bytecode,
 		loopBegin: make(stack[int], 4),
 		loopEnd:   make(stack[int], 4),
 	}
 
+	// Functions declared at the top level are hoisted, so that they may call each other regardless of their order.
+	for _, stmt := range program.Statements {
+		if decl, ok := stmt.(*ast.FuncDeclStmt); ok {
+			if err := c.compileStmt(decl); err != nil {
+				return nil, err
+			}
+		}
+	}
 	for _, stmt := range program.Statements {
+		if _, ok := stmt.(*ast.FuncDeclStmt); ok {
+			continue
+		}
 		if err := c.compileStmt(stmt); err != nil {
-			return err
+			return nil, err
 		}
 	}
-	return nil
+	return warnings, nil
+}
+
+// CompileSource Tokenizes, parses and compiles src and returns the warnings of Compile. Position errors are returned
+// even if script.PanicOnError is set.
+func CompileSource(src []byte, globals ...string) (bc vm.Bytecode, warnings []error, err error) {
+	defer func() {
+		switch r := recover().(type) {
+		case nil:
+		case *script.PosError:
+			bc, warnings, err = nil, nil, r
+		default:
+			panic(r)
+		}
+	}()
+
+	tokens, errs := lexer.Tokenize(src)
+	if len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
+	}
+
+	program, errs := ast.Parse(tokens)
+	if len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
+	}
+
+	bc = make(vm.Bytecode, 0)
+	if warnings, err = Compile(&bc, program, globals...); err != nil {
+		return nil, nil, err
+	}
+	return bc, warnings, nil
+}
+
+// mark Sets the source span of all instructions emitted since start that do not have one yet.
+// Nodes are marked after their children, so every instruction keeps the span of the innermost node.
+func (out *compiler) mark(start int, node ast.Node) {
+	bc := *out.bc
+	span, computed := vm.NoSpan, false
+	for i := start; i < len(bc); i++ {
+		if bc[i].Span.Known() {
+			continue
+		}
+		if !computed {
+			span, computed = spanOf(node), true
+		}
+		bc[i].Span = span
+	}
+}
+
+// spanOf Returns the span of the tokens of node and its children.
+func spanOf(node ast.Node) vm.Span {
+	span := vm.NoSpan
+	add := func(tok lexer.Token) {
+		if tok.Pos >= 0 {
+			span = span.Union(vm.Span{Start: tok.Pos, End: tok.Pos + utf8.RuneCountInString(tok.Lexeme)})
+		}
+	}
+	ast.Inspect(node, func(n ast.Node) bool {
+		if n == nil {
+			return false
+		}
+		add(n.Tok())
+		if b, ok := n.(*ast.BlockStmt); ok {
+			add(b.Closing())
+		}
+		return true
+	})
+	return span
 }
 
 func (out *compiler) compileStmt(stmt ast.Stmt) error {
-	switch s := stmt.(type) {
+	defer out.mark(out.bc.Len(), stmt)
+
+	switch s := ast.Lower(stmt).(type) {
 	case *ast.DeclareStmt:
 		return out.compileDeclareStmt(s)
+	case *ast.FuncDeclStmt:
+		return out.compileFuncDeclStmt(s)
+	case *ast.TryStmt:
+		return out.compileTryStmt(s)
+	case *ast.ThrowStmt:
+		return out.compileThrowStmt(s)
+	case *ast.DeferStmt:
+		return out.compileDeferStmt(s)
+	case *ast.ForInStmt:
+		return out.compileForInStmt(s)
+	case *ast.MatchStmt:
+		return out.compileMatchStmt(s)
 	case *ast.AssignStmt:
 		return out.compileAssignStmt(s)
+	case *ast.ExprStmt:
+		return out.compileExprStmt(s)
 	case *ast.BlockStmt:
 		return out.compileBlockStmt(s, true)
 	case *ast.ConditionalStmt:
@@ -56,12 +178,194 @@ func (out *compiler) compileStmt(stmt ast.Stmt) error {
 }
 
 func (out *compiler) compileContinueStmt(s *ast.ContinueStmt) error {
-	out.bc.Instruction(vm.JUMP, out.loopBegin.top())
-	return nil
+	return out.compileLoopJump(s.Label, out.loopBegin)
 }
 
 func (out *compiler) compileBreakStmt(s *ast.BreakStmt) error {
-	out.bc.Instruction(vm.JUMP, out.loopEnd.top())
+	return out.compileLoopJump(s.Label, out.loopEnd)
+}
+
+// compileLoopJump Compiles a jump to the trampoline in targets of the loop with the given label, or of the innermost
+// loop if label is nil. The trampoline restores the anchor frame of its loop, so the anchors of the inner loops are
+// skipped with RESCUE first.
+func (out *compiler) compileLoopJump(label *ast.Identifier, targets stack[int]) error {
+	depth := 0
+	if label != nil {
+		depth = -1
+		for i := len(out.loopLabels) - 1; i >= 0; i-- {
+			if out.loopLabels[i] == label.Symbol {
+				depth = len(out.loopLabels) - 1 - i
+				break
+			}
+		}
+		if depth < 0 {
+			return ast.NewNodeError(label, fmt.Sprintf("unknown label %s", label.Symbol))
+		}
+	}
+
+	if err := out.leaveTries(len(out.loopEnd) - depth); err != nil {
+		return err
+	}
+	if depth > 0 {
+		out.bc.Instruction(vm.RESCUE, depth)
+	}
+	out.bc.Instruction(vm.JUMP, targets[len(targets)-1-depth])
+	return nil
+}
+
+// loopLabel Returns the name of a loop label, or "" if label is nil.
+func loopLabel(label *ast.Identifier) string {
+	if label == nil {
+		return ""
+	}
+	return label.Symbol
+}
+
+// compileTryStmt compiles a try statement. The handler is entered with the *vm.RuntimeError on the stack:
+//
+//	TRY handler; body; END_TRY; finally; JUMP end
+//	handler: CATCH; ENTER; DECLARE param; [TRY rethrow]; catch; [END_TRY]; LEAVE; finally; JUMP end
+//	[rethrow: LEAVE]; ENTER; DECLARE _exception; finally; LOAD _exception; THROW
+//	end:
+//
+// The part starting at rethrow exists if there is a finally block. Without a catch block, the handler is rethrow.
+func (out *compiler) compileTryStmt(s *ast.TryStmt) error {
+	tryIndex := out.bc.Len()
+	out.bc.Instruction(vm.TRY, -1)
+
+	if err := out.compileProtected(s, s.Body, func() error {
+		return out.compileBlockStmt(s.Body, true)
+	}); err != nil {
+		return err
+	}
+	endJumps := []int{out.bc.Len()}
+	out.bc.Instruction(vm.JUMP, -1)
+	out.bc.SetArg(tryIndex, out.bc.Len())
+
+	if s.Catch != nil {
+		out.bc.Instruction(vm.CATCH, nil)
+		out.bc.Instruction(vm.ENTER, nil)
+		if s.Param != nil {
+			out.bc.Instruction(vm.DECLARE, s.Param.Symbol)
+		} else {
+			out.bc.Instruction(vm.POP, nil)
+		}
+
+		catch := func() error {
+			return out.compileBlockStmt(s.Catch, false)
+		}
+		var err error
+		if s.Finally != nil {
+			rethrowIndex := out.bc.Len()
+			out.bc.Instruction(vm.TRY, -1)
+			err = out.compileProtected(s, s.Catch, func() error {
+				if err := catch(); err != nil {
+					return err
+				}
+				out.bc.Instruction(vm.LEAVE, nil)
+				return nil
+			})
+			endJumps = append(endJumps, out.bc.Len())
+			out.bc.Instruction(vm.JUMP, -1)
+			out.bc.SetArg(rethrowIndex, out.bc.Len())
+			out.bc.Instruction(vm.LEAVE, nil)
+		} else {
+			err = catch()
+			out.bc.Instruction(vm.LEAVE, nil)
+			endJumps = append(endJumps, out.bc.Len())
+			out.bc.Instruction(vm.JUMP, -1)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.Finally != nil {
+		out.bc.Instruction(vm.ENTER, nil)
+		out.bc.Instruction(vm.DECLARE, "_exception")
+		if err := out.compileBlockStmt(s.Finally, true); err != nil {
+			return err
+		}
+		out.bc.Instruction(vm.LOAD, "_exception")
+		out.bc.Instruction(vm.THROW, nil)
+	}
+
+	for _, index := range endJumps {
+		out.bc.SetArg(index, out.bc.Len())
+	}
+	return nil
+}
+
+// compileProtected Compiles the code of a try statement that is protected by the handler installed last, followed by
+// END_TRY and the finally block.
+func (out *compiler) compileProtected(s *ast.TryStmt, block *ast.BlockStmt, compile func() error) error {
+	out.tries.push(tryContext{
+		handler:  true,
+		finally:  s.Finally,
+		loops:    len(out.loopEnd),
+		function: out.functions,
+	})
+	err := compile()
+	out.tries.pop()
+	if err != nil {
+		return err
+	}
+
+	out.bc.Instruction(vm.END_TRY, nil)
+	if s.Finally != nil {
+		return out.compileBlockStmt(s.Finally, true)
+	}
+	return nil
+}
+
+// leaveTries Compiles the exit from the try statements a return statement, or a break or continue statement, jumps out
+// of. loops is the number of enclosing loops the jump stays in, 0 for return statements. The handlers are removed and
+// the finally blocks run, innermost first.
+func (out *compiler) leaveTries(loops int) error {
+	tries := out.tries
+	defer func() {
+		out.tries = tries
+	}()
+
+	for i := len(tries) - 1; i >= 0; i-- {
+		t := tries[i]
+		if t.function != out.functions || t.loops < loops {
+			break
+		}
+		if t.handler {
+			out.bc.Instruction(vm.END_TRY, nil)
+		}
+		if t.finally != nil {
+			// The finally block is outside of the statement and the ones nested in it.
+			out.tries = tries[:i]
+			if err := out.compileBlockStmt(t.finally, true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// compileDeferStmt Compiles the callee and the arguments like compileCallExpr, but DEFER takes the place of the call.
+func (out *compiler) compileDeferStmt(s *ast.DeferStmt) error {
+	for i := len(s.Call.Args) - 1; i >= 0; i-- {
+		if err := out.compileExpr(s.Call.Args[i]); err != nil {
+			return err
+		}
+	}
+	out.bc.Instruction(vm.PUSH, int64(len(s.Call.Args)))
+	if err := out.compileExpr(s.Call.Caller); err != nil {
+		return err
+	}
+	out.bc.Instruction(vm.DEFER, nil)
+	return nil
+}
+
+func (out *compiler) compileThrowStmt(s *ast.ThrowStmt) error {
+	if err := out.compileExpr(s.Expr); err != nil {
+		return err
+	}
+	out.bc.Instruction(vm.THROW, nil)
 	return nil
 }
 
@@ -95,7 +399,7 @@ func (out *compiler) compileConditionalStmt(s *ast.ConditionalStmt) error {
 }
 
 func (out *compiler) compileDeclareStmt(s *ast.DeclareStmt) error {
-	if err := out.compileExpr(s.Expr); err != nil {
+	if err := out.compileBound(s.Ident.Symbol, s.Expr); err != nil {
 		return err
 	}
 	out.bc.Instruction(vm.DECLARE, s.Ident.Symbol)
@@ -103,7 +407,11 @@ func (out *compiler) compileDeclareStmt(s *ast.DeclareStmt) error {
 }
 
 func (out *compiler) compileAssignStmt(s *ast.AssignStmt) error {
-	if err := out.compileExpr(s.Expr); err != nil {
+	name := ""
+	if s.Ident != nil {
+		name = s.Ident.Symbol
+	}
+	if err := out.compileBound(name, s.Expr); err != nil {
 		return err
 	}
 	if s.Ident != nil {
@@ -114,6 +422,23 @@ func (out *compiler) compileAssignStmt(s *ast.AssignStmt) error {
 	return nil
 }
 
+// compileBound Compiles the value of a declaration or assignment to name. A function expression is named after the
+// variable.
+func (out *compiler) compileBound(name string, expr ast.Expr) error {
+	if _, ok := expr.(*ast.FunctionExpr); ok {
+		out.binding = name
+	}
+	return out.compileExpr(expr)
+}
+
+func (out *compiler) compileExprStmt(s *ast.ExprStmt) error {
+	if err := out.compileExpr(s.Expr); err != nil {
+		return err
+	}
+	out.bc.Instruction(vm.POP, nil)
+	return nil
+}
+
 func (out *compiler) compileBlockStmt(s *ast.BlockStmt, scope bool) error {
 	if scope {
 		out.bc.Instruction(vm.ENTER, nil)
@@ -152,6 +477,9 @@ func (out *compiler) compileReturnStmt(s *ast.ReturnStmt) error {
 	} else {
 		out.bc.Instruction(vm.PUSH, nil)
 	}
+	if err := out.leaveTries(0); err != nil {
+		return err
+	}
 	out.bc.Instruction(vm.RET, nil)
 
 	return nil
@@ -160,6 +488,7 @@ func (out *compiler) compileReturnStmt(s *ast.ReturnStmt) error {
 // compileForStmt compiles a for statement. This is by far the messiest implementation. TODO make it better.
 func (out *compiler) compileForStmt(s *ast.ForStmt) error {
 	out.bc.Instruction(vm.ENTER, nil)
+	out.loopLabels.push(loopLabel(s.Label))
 
 	if s.Init != nil {
 		if err := out.compileStmt(s.Init); err != nil {
@@ -218,10 +547,345 @@ func (out *compiler) compileForStmt(s *ast.ForStmt) error {
 
 	out.bc.Instruction(vm.ANCHOR, false)
 	out.bc.Instruction(vm.LEAVE, nil)
+
+	out.loopBegin.pop()
+	out.loopEnd.pop()
+	out.loopLabels.pop()
 	return nil
 }
 
+// compileForInStmt Compiles a for-in loop with the trampolines of compileForStmt. The iterator is kept in a hidden
+// variable of the loop frame, which the trampolines restore.
+func (out *compiler) compileForInStmt(s *ast.ForInStmt) error {
+	out.bc.Instruction(vm.ENTER, nil)
+	out.loopLabels.push(loopLabel(s.Label))
+
+	if err := out.compileExpr(s.Iterable); err != nil {
+		return err
+	}
+	iterLabel := fmt.Sprintf("_iter%d", out.bc.Len())
+	out.bc.Instruction(vm.ITER, nil)
+	out.bc.Instruction(vm.DECLARE, iterLabel)
+
+	out.bc.Instruction(vm.ANCHOR, true)
+
+	// BREAK
+	skipBreakIndex := out.bc.Len()
+	out.bc.Instruction(vm.JUMP, -1)
+	endIndex := out.bc.Len()
+	out.loopEnd.push(endIndex)
+	out.bc.Instruction(vm.RESCUE, nil)
+	endJumpIndex := out.bc.Len()
+	out.bc.Instruction(vm.JUMP, -1)
+	out.bc.SetArg(skipBreakIndex, out.bc.Len())
+
+	// CONTINUE
+	startIndex := out.bc.Len()
+	out.loopBegin.push(startIndex)
+	out.bc.Instruction(vm.RESCUE, nil)
+
+	out.bc.Instruction(vm.LOAD, iterLabel)
+	out.bc.Instruction(vm.ITER_NEXT, s.Key != nil)
+	jumpIndex := out.bc.Len()
+	out.bc.Instruction(vm.JUMP_F, nil)
+	if s.Key != nil {
+		out.bc.Instruction(vm.DECLARE, s.Key.Symbol)
+	}
+	out.bc.Instruction(vm.DECLARE, s.Value.Symbol)
+
+	if err := out.compileStmt(s.Stmt); err != nil {
+		return err
+	}
+	out.bc.Instruction(vm.JUMP, startIndex)
+
+	out.bc.SetArg(jumpIndex, out.bc.Len())
+	out.bc.SetArg(endJumpIndex, out.bc.Len())
+
+	out.bc.Instruction(vm.ANCHOR, false)
+	out.bc.Instruction(vm.LEAVE, nil)
+
+	out.loopBegin.pop()
+	out.loopEnd.pop()
+	out.loopLabels.pop()
+	return nil
+}
+
+// Jump tables are used for at least minTableCases integer cases, which cover at least half of the integers from the
+// smallest to the largest one.
+const minTableCases = 4
+
+// compileMatchStmt Compiles a match statement. The subject is kept in a hidden variable of the statement's frame. Arms
+// are tried in order: the patterns are tested, then a frame with the bindings is entered and the guard is checked.
+// Statements with only integer literal cases compile to a SWITCH instead.
+func (out *compiler) compileMatchStmt(s *ast.MatchStmt) error {
+	out.bc.Instruction(vm.ENTER, nil)
+	if err := out.compileExpr(s.Subject); err != nil {
+		return err
+	}
+	subject := fmt.Sprintf("_match%d", out.bc.Len())
+	out.bc.Instruction(vm.DECLARE, subject)
+	load := func() {
+		out.bc.Instruction(vm.LOAD, subject)
+	}
+
+	var endJumps []int
+	var err error
+	if table, cases, ok := jumpTable(s); ok {
+		endJumps, err = out.compileMatchTable(s, table, cases, load)
+	} else {
+		endJumps, err = out.compileMatchArms(s, load)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, index := range endJumps {
+		out.bc.SetArg(index, out.bc.Len())
+	}
+	out.bc.Instruction(vm.LEAVE, nil)
+	return nil
+}
+
+// compileMatchTable Compiles the arms of a match statement to the targets of a SWITCH. cases are the integers of
+// every arm, see jumpTable. It returns the jumps to the end of the statement.
+func (out *compiler) compileMatchTable(s *ast.MatchStmt, table vm.JumpTable, cases [][]int64, load func()) ([]int, error) {
+	load()
+	switchIndex := out.bc.Len()
+	out.bc.Instruction(vm.SWITCH, nil)
+
+	var endJumps []int
+	for i, arm := range s.Arms {
+		address := out.bc.Len()
+		if cases[i] == nil {
+			table.Default = address
+		}
+		for _, v := range cases[i] {
+			// The first arm of an integer wins.
+			if table.Targets[v-table.Min] < 0 {
+				table.Targets[v-table.Min] = address
+			}
+		}
+		if err := out.compileStmt(arm.Body); err != nil {
+			return nil, err
+		}
+		endJumps = append(endJumps, out.bc.Len())
+		out.bc.Instruction(vm.JUMP, -1)
+	}
+
+	if table.Default < 0 {
+		table.Default = out.bc.Len()
+	}
+	for i, address := range table.Targets {
+		if address < 0 {
+			table.Targets[i] = table.Default
+		}
+	}
+	out.bc.SetArg(switchIndex, table)
+	return endJumps, nil
+}
+
+// compileMatchArms Compiles the arms of a match statement to sequential tests. It returns the jumps to the end of the
+// statement.
+func (out *compiler) compileMatchArms(s *ast.MatchStmt, load func()) ([]int, error) {
+	var endJumps []int
+	for _, arm := range s.Arms {
+		var matchedJumps []int
+		for _, pattern := range arm.Patterns {
+			var fails []int
+			if err := out.compilePattern(pattern, load, &fails); err != nil {
+				return nil, err
+			}
+			matchedJumps = append(matchedJumps, out.bc.Len())
+			out.bc.Instruction(vm.JUMP, -1)
+			for _, index := range fails {
+				out.bc.SetArg(index, out.bc.Len())
+			}
+		}
+		nextIndex := out.bc.Len()
+		out.bc.Instruction(vm.JUMP, -1)
+		for _, index := range matchedJumps {
+			out.bc.SetArg(index, out.bc.Len())
+		}
+
+		out.bc.Instruction(vm.ENTER, nil)
+		for _, pattern := range arm.Patterns {
+			if err := out.compileBindings(pattern, load, len(arm.Patterns) > 1); err != nil {
+				return nil, err
+			}
+		}
+		guardIndex := -1
+		if arm.Guard != nil {
+			if err := out.compileExpr(arm.Guard); err != nil {
+				return nil, err
+			}
+			guardIndex = out.bc.Len()
+			out.bc.Instruction(vm.JUMP_F, -1)
+		}
+		if err := out.compileStmt(arm.Body); err != nil {
+			return nil, err
+		}
+		out.bc.Instruction(vm.LEAVE, nil)
+		endJumps = append(endJumps, out.bc.Len())
+		out.bc.Instruction(vm.JUMP, -1)
+		if guardIndex >= 0 {
+			out.bc.SetArg(guardIndex, out.bc.Len())
+			out.bc.Instruction(vm.LEAVE, nil)
+		}
+
+		out.bc.SetArg(nextIndex, out.bc.Len())
+	}
+	return endJumps, nil
+}
+
+// compilePattern Compiles the test of pattern against the value load pushes. The code falls through if the value
+// matches and jumps to the JUMP_F instructions added to fails otherwise.
+func (out *compiler) compilePattern(pattern ast.Expr, load func(), fails *[]int) error {
+	fail := func() {
+		*fails = append(*fails, out.bc.Len())
+		out.bc.Instruction(vm.JUMP_F, -1)
+	}
+
+	switch p := pattern.(type) {
+	case *ast.Identifier:
+		if !p.IsBool() {
+			// Matches anything.
+			break
+		}
+		load()
+		if err := out.compileExpr(p); err != nil {
+			return err
+		}
+		out.bc.Instruction(vm.MATCH_EQ, nil)
+		fail()
+	case *ast.Number, *ast.String, *ast.UnaryExpr:
+		load()
+		if err := out.compileExpr(p); err != nil {
+			return err
+		}
+		out.bc.Instruction(vm.MATCH_EQ, nil)
+		fail()
+	case *ast.RangeExpr:
+		load()
+		if err := out.compileExpr(p.Start); err != nil {
+			return err
+		}
+		if err := out.compileExpr(p.End); err != nil {
+			return err
+		}
+		out.bc.Instruction(vm.MATCH_RANGE, nil)
+		fail()
+	case *ast.ArrayExpr:
+		load()
+		out.bc.Instruction(vm.MATCH_LEN, len(p.Elements))
+		fail()
+		for i, element := range p.Elements {
+			if err := out.compilePattern(element, out.elementLoader(load, i), fails); err != nil {
+				return err
+			}
+		}
+	default:
+		return ast.NewNodeError(pattern, fmt.Sprintf("invalid pattern %T", pattern))
+	}
+	return nil
+}
+
+// compileBindings Declares the identifiers of a matched pattern, see compilePattern. Patterns with alternatives must
+// not bind any, as the alternative that matched is not known.
+func (out *compiler) compileBindings(pattern ast.Expr, load func(), alternative bool) error {
+	switch p := pattern.(type) {
+	case *ast.Identifier:
+		if p.Symbol == "_" || p.IsBool() {
+			return nil
+		}
+		if alternative {
+			return ast.NewNodeError(p, fmt.Sprintf("cannot bind %s in alternative patterns", p.Symbol))
+		}
+		load()
+		out.bc.Instruction(vm.DECLARE, p.Symbol)
+	case *ast.ArrayExpr:
+		for i, element := range p.Elements {
+			if err := out.compileBindings(element, out.elementLoader(load, i), alternative); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// elementLoader Returns a function pushing the element i of the array load pushes.
+func (out *compiler) elementLoader(load func(), i int) func() {
+	return func() {
+		load()
+		out.bc.Instruction(vm.PUSH, int64(i))
+		out.bc.Instruction(vm.ARR_ID, nil)
+	}
+}
+
+// jumpTable Returns an empty table, with all targets -1, and the integers of every arm if the statement can be compiled
+// to a jump table: there are no guards, all patterns are integer literals, except for a final arm _, whose cases are
+// nil, and the integers are dense.
+func jumpTable(s *ast.MatchStmt) (vm.JumpTable, [][]int64, bool) {
+	cases := make([][]int64, len(s.Arms))
+	count, low, high := 0, int64(0), int64(0)
+	for i, arm := range s.Arms {
+		if arm.Guard != nil {
+			return vm.JumpTable{}, nil, false
+		}
+		ident, ok := arm.Patterns[0].(*ast.Identifier)
+		if ok && ident.Symbol == "_" && len(arm.Patterns) == 1 && i == len(s.Arms)-1 {
+			continue
+		}
+		for _, pattern := range arm.Patterns {
+			v, ok := intLiteral(pattern)
+			if !ok {
+				return vm.JumpTable{}, nil, false
+			}
+			if count == 0 || v < low {
+				low = v
+			}
+			if count == 0 || v > high {
+				high = v
+			}
+			cases[i] = append(cases[i], v)
+			count++
+		}
+	}
+	// The difference is converted, so that it cannot overflow.
+	if count < minTableCases || uint64(high-low) >= uint64(2*count) {
+		return vm.JumpTable{}, nil, false
+	}
+
+	table := vm.JumpTable{Min: low, Targets: make([]int, high-low+1), Default: -1}
+	for i := range table.Targets {
+		table.Targets[i] = -1
+	}
+	return table, cases, true
+}
+
+// intLiteral Returns the value of an integer literal, which may be negated.
+func intLiteral(e ast.Expr) (int64, bool) {
+	negative := false
+	if u, ok := e.(*ast.UnaryExpr); ok && u.Operator == lexer.MINUS {
+		negative, e = true, u.Expr
+	}
+	n, ok := e.(*ast.Number)
+	if !ok {
+		return 0, false
+	}
+	value, err := lexer.ParseNumber(n.Value)
+	v, ok := value.(int64)
+	if err != nil || !ok {
+		return 0, false
+	}
+	if negative {
+		v = -v
+	}
+	return v, true
+}
+
 func (out *compiler) compileExpr(expr ast.Expr) error {
+	defer out.mark(out.bc.Len(), expr)
+
 	switch e := expr.(type) {
 	case *ast.BinaryExpr:
 		if err := out.compileBinaryExpr(e); err != nil {
@@ -235,18 +899,43 @@ func (out *compiler) compileExpr(expr ast.Expr) error {
 		if err := out.compileNumber(e); err != nil {
 			return err
 		}
+	case *ast.String:
+		out.bc.Instruction(vm.PUSH, e.Value)
 	case *ast.Identifier:
 		out.bc.Instruction(vm.LOAD, e.Symbol)
 	case *ast.FunctionExpr:
-		if err := out.compileFunctionExpr(e); err != nil {
+		name := out.binding
+		out.binding = ""
+		if err := out.compileFunction(e, name, false); err != nil {
 			return err
 		}
 	case *ast.CallExpr:
 		if err := out.compileCallExpr(e); err != nil {
 			return err
 		}
-	case *ast.SubscriptExpr:
-		if err := out.compileSubscriptExpr(e); err != nil {
+	case *ast.RangeExpr:
+		if err := out.compileExpr(e.Start); err != nil {
+			return err
+		}
+		if err := out.compileExpr(e.End); err != nil {
+			return err
+		}
+		out.bc.Instruction(vm.RANGE, nil)
+	case *ast.YieldExpr:
+		if e.Value != nil {
+			if err := out.compileExpr(e.Value); err != nil {
+				return err
+			}
+		} else {
+			out.bc.Instruction(vm.PUSH, nil)
+		}
+		out.bc.Instruction(vm.YIELD, nil)
+	case *ast.ConditionalExpr:
+		if err := out.compileConditionalExpr(e); err != nil {
+			return err
+		}
+	case *ast.SubscriptExpr, *ast.SliceExpr, *ast.MemberExpr:
+		if err := out.compileChain(e); err != nil {
 			return err
 		}
 	case *ast.ArrayExpr:
@@ -278,20 +967,11 @@ func (out *compiler) compileArrayAssignStmt(s *ast.ArrayAssignStmt) error {
 }
 
 func (out *compiler) compileNumber(e *ast.Number) error {
-	if strings.Contains(e.Value, ".") {
-		f, err := strconv.ParseFloat(e.Value, 64)
-		if err != nil {
-			return err
-		}
-		out.bc.Instruction(vm.PUSH, f)
-	} else {
-		i, err := strconv.Atoi(e.Value)
-		if err != nil {
-			return err
-		}
-		out.bc.Instruction(vm.PUSH, i)
+	v, err := lexer.ParseNumber(e.Value)
+	if err != nil {
+		return ast.NewNodeError(e, err.Error())
 	}
-
+	out.bc.Instruction(vm.PUSH, v)
 	return nil
 }
 
@@ -349,6 +1029,21 @@ func (out *compiler) compileBinaryExpr(e *ast.BinaryExpr) error {
 		out.bc.Instruction(vm.PUSH, false) // <- jump here if false
 		out.bc.SetArg(exitIndex, out.bc.Len())
 
+		return nil
+	case lexer.QUESTION_QUESTION:
+		if err := out.compileExpr(e.Left); err != nil {
+			return err
+		}
+		jumpNotNilIndex := out.bc.Len()
+		out.bc.Instruction(vm.JUMP_NOT_NIL, -1)
+
+		// If nil
+		out.bc.Instruction(vm.POP, nil)
+		if err := out.compileExpr(e.Right); err != nil {
+			return err
+		}
+		out.bc.SetArg(jumpNotNilIndex, out.bc.Len())
+
 		return nil
 	default:
 	}
@@ -368,6 +1063,20 @@ func (out *compiler) compileBinaryExpr(e *ast.BinaryExpr) error {
 		out.bc.Instruction(vm.MUL, nil)
 	case lexer.SLASH:
 		out.bc.Instruction(vm.DIV, nil)
+	case lexer.PERCENT:
+		out.bc.Instruction(vm.MOD, nil)
+	case lexer.ASTERISK_ASTERISK:
+		out.bc.Instruction(vm.POW, nil)
+	case lexer.AND:
+		out.bc.Instruction(vm.BIT_AND, nil)
+	case lexer.PIPE:
+		out.bc.Instruction(vm.BIT_OR, nil)
+	case lexer.CIRCUMFLEX:
+		out.bc.Instruction(vm.BIT_XOR, nil)
+	case lexer.LESS_LESS:
+		out.bc.Instruction(vm.SHL, nil)
+	case lexer.GREATER_GREATER:
+		out.bc.Instruction(vm.SHR, nil)
 	case lexer.EQUALS_EQUALS:
 		out.bc.Instruction(vm.CMP, nil)
 	case lexer.EXCLAMATION_EQUALS:
@@ -398,6 +1107,8 @@ func (out *compiler) compileUnaryExpr(e *ast.UnaryExpr) error {
 		out.bc.Instruction(vm.NOT, nil)
 	case lexer.MINUS:
 		out.bc.Instruction(vm.NEG, nil)
+	case lexer.TILDE:
+		out.bc.Instruction(vm.BIT_NOT, nil)
 	case lexer.PLUS:
 		// Do nothing
 	default:
@@ -407,7 +1118,22 @@ func (out *compiler) compileUnaryExpr(e *ast.UnaryExpr) error {
 	return nil
 }
 
-func (out *compiler) compileFunctionExpr(e *ast.FunctionExpr) error {
+func (out *compiler) compileFuncDeclStmt(s *ast.FuncDeclStmt) error {
+	if err := out.compileFunction(s.Func, s.Name.Symbol, true); err != nil {
+		return err
+	}
+	out.bc.Instruction(vm.DECLARE, s.Name.Symbol)
+	return nil
+}
+
+// compileFunction Compiles a function named name. If self is set, the function declares its name in its call frame,
+// so that it can refer to itself wherever it is called from.
+func (out *compiler) compileFunction(e *ast.FunctionExpr, name string, self bool) error {
+	out.functions++
+	defer func() {
+		out.functions--
+	}()
+
 	jumpIndex := out.bc.Len()
 	out.bc.Instruction(vm.JUMP, -1)
 
@@ -415,29 +1141,31 @@ func (out *compiler) compileFunctionExpr(e *ast.FunctionExpr) error {
 
 	argCountLabel := fmt.Sprintf("_argcount%d", out.bc.Len())
 
+	params := make([]string, len(e.Params))
+	for i, param := range e.Params {
+		params[i] = param.Symbol
+	}
+	function := vm.Func{
+		Address:  jumpIndex + 1,
+		Name:     name,
+		Params:   params,
+		Variadic: e.IsVariadic,
+		Span:     spanOf(e),
+	}
+
+	if self {
+		out.bc.Instruction(vm.PUSH, function)
+		out.bc.Instruction(vm.DECLARE, name)
+	}
+
+	// The arg count was checked by CALL.
 	if !e.IsVariadic {
-		// Check if arg count matches exactly
-		out.bc.Instruction(vm.PUSH, len(e.Params))
-		out.bc.Instruction(vm.CMP, nil)
+		out.bc.Instruction(vm.POP, nil)
 	} else {
 		// Declare arg count
 		out.bc.Instruction(vm.DECLARE, argCountLabel)
-
-		out.bc.Instruction(vm.LOAD, argCountLabel)
-		out.bc.Instruction(vm.PUSH, len(e.Params)-1) // Variadic args do accept an empty array.
-		// Check if arg count matches or is greater
-		out.bc.Instruction(vm.CMP_GTE, nil)
 	}
 
-	checkSuccessIndex := out.bc.Len()
-	out.bc.Instruction(vm.JUMP_T, -1)
-
-	// Return arg count error
-	out.bc.Instruction(vm.PUSH, -1)
-	out.compilePanic("arg count mismatch")
-
-	out.bc.SetArg(checkSuccessIndex, out.bc.Len())
-
 	for i, param := range e.Params {
 		if e.IsVariadic && i >= len(e.Params)-1 {
 			break
@@ -453,7 +1181,7 @@ func (out *compiler) compileFunctionExpr(e *ast.FunctionExpr) error {
 
 		// Calculate array size
 		out.bc.Instruction(vm.LOAD, argCountLabel)
-		out.bc.Instruction(vm.PUSH, len(e.Params)-1)
+		out.bc.Instruction(vm.PUSH, int64(len(e.Params)-1))
 		out.bc.Instruction(vm.SUB, nil)
 
 		out.bc.Instruction(vm.ARR_CR, nil)
@@ -465,31 +1193,19 @@ func (out *compiler) compileFunctionExpr(e *ast.FunctionExpr) error {
 		return err
 	}
 
-	out.bc.Instruction(vm.LEAVE, nil)
-
-	// TODO Will need to inject the return statement later. It IS required.
-	//out.bc.Instruction(vm.PUSH, 0) // Returning no values
-	//
-	//out.bc.Instruction(vm.PUSH, nil) // Return nil
-	//out.bc.Instruction(vm.RET, "NOTHING")
+	// Falling off the end returns nil.
+	out.bc.Instruction(vm.PUSH, nil)
+	out.bc.Instruction(vm.RET, nil)
 
 	out.bc.SetArg(jumpIndex, out.bc.Len())
 
 	// Push index of func (out *compiler)tion start. It is basically a pointer.
 
-	function := vm.Func{
-		Address: jumpIndex + 1,
-	}
-
 	out.bc.Instruction(vm.PUSH, function)
 
 	return nil
 }
 
-func (out *compiler) compilePanic(s string) {
-	out.bc.Instruction(vm.PANIC, s)
-}
-
 func (out *compiler) compileCallExpr(e *ast.CallExpr) error {
 	// Push argument expressions in reverse to be declared in order in the call
 	for i := len(e.Args) - 1; i >= 0; i-- {
@@ -499,7 +1215,7 @@ func (out *compiler) compileCallExpr(e *ast.CallExpr) error {
 	}
 
 	// Arg count
-	out.bc.Instruction(vm.PUSH, len(e.Args))
+	out.bc.Instruction(vm.PUSH, int64(len(e.Args)))
 
 	if err := out.compileExpr(e.Caller); err != nil {
 		return err
@@ -522,19 +1238,96 @@ func (out *compiler) compileArrayExpr(e *ast.ArrayExpr) error {
 		}
 	}
 
-	out.bc.Instruction(vm.PUSH, len(e.Elements))
+	out.bc.Instruction(vm.PUSH, int64(len(e.Elements)))
 	out.bc.Instruction(vm.ARR_CR, nil)
 	return nil
 }
 
-func (out *compiler) compileSubscriptExpr(e *ast.SubscriptExpr) error {
-	if err := out.compileExpr(e.Array); err != nil {
+func (out *compiler) compileConditionalExpr(e *ast.ConditionalExpr) error {
+	if err := out.compileExpr(e.Cond); err != nil {
+		return err
+	}
+	jumpFalseIndex := out.bc.Len()
+	out.bc.Instruction(vm.JUMP_F, -1)
+
+	if err := out.compileExpr(e.Then); err != nil {
 		return err
 	}
-	if err := out.compileExpr(e.Index); err != nil {
+	exitIndex := out.bc.Len()
+	out.bc.Instruction(vm.JUMP, -1)
+
+	out.bc.SetArg(jumpFalseIndex, out.bc.Len())
+	if err := out.compileExpr(e.Else); err != nil {
 		return err
 	}
-	out.bc.Instruction(vm.ARR_ID, nil)
+	out.bc.SetArg(exitIndex, out.bc.Len())
+	return nil
+}
+
+// compileChain Compiles a chain of subscripts, slices and member accesses like a?.b[0].c. If an optional link finds nil, the
+// rest of the chain is skipped and the chain evaluates to nil.
+func (out *compiler) compileChain(e ast.Expr) error {
+	var nilJumps []int
+	if err := out.compileLink(e, &nilJumps); err != nil {
+		return err
+	}
+	for _, index := range nilJumps {
+		out.bc.SetArg(index, out.bc.Len())
+	}
+	return nil
+}
+
+// compileLink Compiles a link of a chain. The JUMP_NIL instructions of optional links are added to nilJumps.
+func (out *compiler) compileLink(e ast.Expr, nilJumps *[]int) error {
+	defer out.mark(out.bc.Len(), e)
+
+	optional := func() {
+		*nilJumps = append(*nilJumps, out.bc.Len())
+		out.bc.Instruction(vm.JUMP_NIL, -1)
+	}
+
+	switch e := e.(type) {
+	case *ast.SubscriptExpr:
+		if err := out.compileLink(e.Array, nilJumps); err != nil {
+			return err
+		}
+		if e.Optional {
+			optional()
+		}
+		if err := out.compileExpr(e.Index); err != nil {
+			return err
+		}
+		out.bc.Instruction(vm.ARR_ID, nil)
+	case *ast.SliceExpr:
+		if err := out.compileLink(e.Array, nilJumps); err != nil {
+			return err
+		}
+		if e.Optional {
+			optional()
+		}
+		// An omitted bound is nil.
+		for _, bound := range []ast.Expr{e.Low, e.High} {
+			if bound == nil {
+				out.bc.Instruction(vm.PUSH, nil)
+				continue
+			}
+			if err := out.compileExpr(bound); err != nil {
+				return err
+			}
+		}
+		out.bc.Instruction(vm.SLICE, nil)
+	case *ast.MemberExpr:
+		if err := out.compileLink(e.Object, nilJumps); err != nil {
+			return err
+		}
+		if e.Optional {
+			optional()
+		}
+		out.bc.Instruction(vm.PUSH, e.Name.Symbol)
+		out.bc.Instruction(vm.ARR_ID, nil)
+	default:
+		return out.compileExpr(e)
+	}
 	return nil
 }
 
@@ -546,7 +1339,7 @@ func (out *compiler) compileNewExpr(e *ast.NewExpr) error {
 				return err
 			}
 		} else {
-			out.bc.Instruction(vm.PUSH, 0)
+			out.bc.Instruction(vm.PUSH, int64(0))
 		}
 		out.bc.Instruction(vm.ARR_INIT, nil)
 	default:
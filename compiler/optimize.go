@@ -0,0 +1,190 @@
+package compiler
+
+import "script/vm"
+
+// Optimize Returns an optimized copy of bc. Arithmetic on constants is folded and jumps to the next instruction are
+// removed. Jump targets, jump tables, frame ends and function addresses are moved along, and a folded instruction gets
+// the union of the spans of the instructions it replaces, so that errors still point to the right source code.
+func Optimize(bc vm.Bytecode) vm.Bytecode {
+	targets := make(map[int]bool)
+	for _, instr := range bc {
+		if address, ok := addressOf(instr); ok {
+			targets[address] = true
+		}
+		if table, ok := instr.Arg.(vm.JumpTable); ok {
+			for _, address := range table.Targets {
+				targets[address] = true
+			}
+			targets[table.Default] = true
+		}
+	}
+
+	out := make(vm.Bytecode, 0, len(bc))
+	// first holds the index in bc of the first instruction every instruction of out was made of.
+	first := make([]int, 0, len(bc))
+	// index maps the indices of bc to the indices of out. It has an entry for the end of bc, a valid jump target.
+	index := make([]int, len(bc)+1)
+
+	// foldable Reports whether the last n instructions of out may be replaced. Only the first of them may be a
+	// jump target.
+	foldable := func(n int) bool {
+		if len(out) < n {
+			return false
+		}
+		for i := len(out) - n + 1; i < len(out); i++ {
+			if targets[first[i]] {
+				return false
+			}
+		}
+		for i := len(out) - n; i < len(out); i++ {
+			if !isConstant(out[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	for i, instr := range bc {
+		index[i] = len(out)
+
+		if instr.Op == vm.JUMP && instr.Arg == i+1 {
+			continue
+		}
+
+		if !targets[i] {
+			if v, ok := fold(instr.Op, out, foldable); ok {
+				n := arity(instr.Op)
+				span := instr.Span
+				for _, folded := range out[len(out)-n:] {
+					span = span.Union(folded.Span)
+				}
+				out = out[:len(out)-n]
+				index[i] = len(out)
+				out = append(out, vm.Instr{Op: vm.PUSH, Arg: v, Span: span})
+				first = first[:len(first)-n+1]
+				continue
+			}
+		}
+
+		out = append(out, instr)
+		first = append(first, i)
+	}
+	index[len(bc)] = len(out)
+
+	for i, instr := range out {
+		if table, ok := instr.Arg.(vm.JumpTable); ok {
+			remapped := vm.JumpTable{Min: table.Min, Targets: make([]int, len(table.Targets)), Default: index[table.Default]}
+			for j, address := range table.Targets {
+				remapped.Targets[j] = index[address]
+			}
+			out[i].Arg = remapped
+			continue
+		}
+		address, ok := addressOf(instr)
+		if !ok || address < 0 || address > len(bc) {
+			continue
+		}
+		if f, ok := instr.Arg.(vm.Func); ok {
+			f.Address = index[address]
+			out[i].Arg = f
+		} else {
+			out[i].Arg = index[address]
+		}
+	}
+	return out
+}
+
+// addressOf Returns the instruction index the argument of instr refers to.
+func addressOf(instr vm.Instr) (int, bool) {
+	switch instr.Op {
+	case vm.JUMP, vm.JUMP_T, vm.JUMP_F, vm.JUMP_NIL, vm.JUMP_NOT_NIL, vm.FRAME, vm.TRY:
+		address, ok := instr.Arg.(int)
+		return address, ok
+	case vm.PUSH:
+		if f, ok := instr.Arg.(vm.Func); ok {
+			return f.Address, true
+		}
+	}
+	return 0, false
+}
+
+// isConstant Reports whether instr pushes a value that can take part in folding.
+func isConstant(instr vm.Instr) bool {
+	if instr.Op != vm.PUSH {
+		return false
+	}
+	switch vm.TypeOf(instr.Arg) {
+	case vm.Int, vm.Float, vm.Decimal, vm.String, vm.Bool:
+		return true
+	}
+	return false
+}
+
+// arity Returns the number of operands op takes from the stack.
+func arity(op vm.OpCode) int {
+	switch op {
+	case vm.NEG, vm.NOT, vm.BIT_NOT:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// fold Computes the result of op applied to the constants at the end of out. It fails if they may not be folded, or
+// if the operation fails, so that the error is raised at runtime. An integer overflow fails as well, as it is handled
+// at runtime, see vm.Overflow.
+func fold(op vm.OpCode, out vm.Bytecode, foldable func(n int) bool) (any, bool) {
+	var (
+		v   any
+		err error
+	)
+	switch op {
+	case vm.ADD, vm.SUB, vm.MUL, vm.DIV, vm.MOD, vm.POW, vm.BIT_AND, vm.BIT_OR, vm.BIT_XOR, vm.SHL, vm.SHR:
+		if !foldable(2) {
+			return nil, false
+		}
+		a, b := out[len(out)-2].Arg, out[len(out)-1].Arg
+		switch op {
+		case vm.ADD:
+			v, err = vm.Add(a, b)
+		case vm.SUB:
+			v, err = vm.Sub(a, b)
+		case vm.MUL:
+			v, err = vm.Mul(a, b)
+		case vm.DIV:
+			v, err = vm.Div(a, b)
+		case vm.MOD:
+			v, err = vm.Mod(a, b)
+		case vm.POW:
+			v, err = vm.Pow(a, b)
+		case vm.BIT_AND:
+			v, err = vm.BitAnd(a, b)
+		case vm.BIT_OR:
+			v, err = vm.BitOr(a, b)
+		case vm.BIT_XOR:
+			v, err = vm.BitXor(a, b)
+		case vm.SHL:
+			v, err = vm.Shl(a, b)
+		case vm.SHR:
+			v, err = vm.Shr(a, b)
+		}
+	case vm.NEG:
+		if !foldable(1) {
+			return nil, false
+		}
+		v, err = vm.Neg(out[len(out)-1].Arg)
+	case vm.BIT_NOT:
+		if !foldable(1) {
+			return nil, false
+		}
+		v, err = vm.BitNot(out[len(out)-1].Arg)
+	case vm.NOT:
+		if !foldable(1) {
+			return nil, false
+		}
+		v = !vm.Truthy(out[len(out)-1].Arg)
+	default:
+		return nil, false
+	}
+	return v, err == nil
+}
@@ -0,0 +1,205 @@
+package compiler
+
+import (
+	"bytes"
+	"script/vm"
+	"testing"
+)
+
+func span(start, end int) vm.Span {
+	return vm.Span{Start: start, End: end}
+}
+
+func TestOptimizeFolds(t *testing.T) {
+	tests := []struct {
+		name string
+		bc   vm.Bytecode
+		want vm.Bytecode
+	}{
+		{
+			name: "binary",
+			bc: vm.Bytecode{
+				{Op: vm.PUSH, Arg: int64(1), Span: span(0, 1)},
+				{Op: vm.PUSH, Arg: int64(2), Span: span(4, 5)},
+				{Op: vm.ADD, Span: span(0, 5)},
+				{Op: vm.PUSH, Arg: int64(3), Span: span(8, 9)},
+				{Op: vm.MUL, Span: span(0, 9)},
+			},
+			want: vm.Bytecode{{Op: vm.PUSH, Arg: int64(9), Span: span(0, 9)}},
+		},
+		{
+			name: "unary",
+			bc: vm.Bytecode{
+				{Op: vm.PUSH, Arg: int64(2), Span: span(1, 2)},
+				{Op: vm.NEG, Span: span(0, 2)},
+				{Op: vm.PUSH, Arg: true, Span: span(4, 8)},
+				{Op: vm.NOT, Span: span(3, 8)},
+			},
+			want: vm.Bytecode{
+				{Op: vm.PUSH, Arg: int64(-2), Span: span(0, 2)},
+				{Op: vm.PUSH, Arg: false, Span: span(3, 8)},
+			},
+		},
+		{
+			name: "failing operations are kept",
+			bc: vm.Bytecode{
+				{Op: vm.PUSH, Arg: int64(1)},
+				{Op: vm.PUSH, Arg: int64(0)},
+				{Op: vm.DIV},
+				{Op: vm.PUSH, Arg: int64(1)},
+				{Op: vm.PUSH, Arg: int64(70)},
+				{Op: vm.SHL},
+			},
+			want: vm.Bytecode{
+				{Op: vm.PUSH, Arg: int64(1)},
+				{Op: vm.PUSH, Arg: int64(0)},
+				{Op: vm.DIV},
+				{Op: vm.PUSH, Arg: int64(1)},
+				{Op: vm.PUSH, Arg: int64(70)},
+				{Op: vm.SHL},
+			},
+		},
+		{
+			name: "variables are kept",
+			bc: vm.Bytecode{
+				{Op: vm.PUSH, Arg: int64(1)},
+				{Op: vm.LOAD, Arg: "x"},
+				{Op: vm.ADD},
+			},
+			want: vm.Bytecode{
+				{Op: vm.PUSH, Arg: int64(1)},
+				{Op: vm.LOAD, Arg: "x"},
+				{Op: vm.ADD},
+			},
+		},
+		{
+			name: "jump targets are not folded into",
+			bc: vm.Bytecode{
+				{Op: vm.PUSH, Arg: int64(1)},
+				{Op: vm.JUMP, Arg: 3},
+				{Op: vm.PUSH, Arg: int64(2)},
+				{Op: vm.PUSH, Arg: int64(3)},
+				{Op: vm.ADD},
+			},
+			want: vm.Bytecode{
+				{Op: vm.PUSH, Arg: int64(1)},
+				{Op: vm.JUMP, Arg: 3},
+				{Op: vm.PUSH, Arg: int64(2)},
+				{Op: vm.PUSH, Arg: int64(3)},
+				{Op: vm.ADD},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Optimize(test.bc)
+			if len(got) != len(test.want) {
+				t.Fatalf("got\n%swant\n%s", got.String(), test.want.String())
+			}
+			for i := range got {
+				if got[i].Op != test.want[i].Op || !vm.Equal(got[i].Arg, test.want[i].Arg) || got[i].Span != test.want[i].Span {
+					t.Errorf("instruction %d: got %v %v %v, want %v %v %v", i,
+						got[i].Op, got[i].Arg, got[i].Span, test.want[i].Op, test.want[i].Arg, test.want[i].Span)
+				}
+			}
+		})
+	}
+}
+
+func TestOptimizeRemapsAddresses(t *testing.T) {
+	bc := vm.Bytecode{
+		/* 0 */ {Op: vm.PUSH, Arg: int64(1)},
+		/* 1 */ {Op: vm.PUSH, Arg: int64(2)},
+		/* 2 */ {Op: vm.ADD},
+		/* 3 */ {Op: vm.JUMP, Arg: 4},
+		/* 4 */ {Op: vm.SWITCH, Arg: vm.JumpTable{Min: 0, Targets: []int{6, 9}, Default: 10}},
+		/* 5 */ {Op: vm.PUSH, Arg: vm.Func{Address: 9, Name: "f"}},
+		/* 6 */ {Op: vm.FRAME, Arg: 10},
+		/* 7 */ {Op: vm.JUMP_F, Arg: 10},
+		/* 8 */ {Op: vm.TRY, Arg: 9},
+		/* 9 */ {Op: vm.POP},
+	}
+	// The jump to the next instruction is removed and three instructions are folded into one.
+	want := []any{
+		int64(3),
+		vm.JumpTable{Min: 0, Targets: []int{3, 6}, Default: 7},
+		vm.Func{Address: 6, Name: "f"},
+		7,
+		7,
+		6,
+		nil,
+	}
+
+	got := Optimize(bc)
+	if len(got) != len(want) {
+		t.Fatalf("got\n%s", got.String())
+	}
+	for i := range got {
+		switch arg := got[i].Arg.(type) {
+		case vm.JumpTable:
+			w := want[i].(vm.JumpTable)
+			if arg.Default != w.Default || arg.Targets[0] != w.Targets[0] || arg.Targets[1] != w.Targets[1] {
+				t.Errorf("instruction %d: got table %+v, want %+v", i, arg, w)
+			}
+		case vm.Func:
+			if arg.Address != want[i].(vm.Func).Address {
+				t.Errorf("instruction %d: got function address %d, want %d", i, arg.Address, want[i].(vm.Func).Address)
+			}
+		default:
+			if arg != want[i] {
+				t.Errorf("instruction %d: got %v, want %v", i, arg, want[i])
+			}
+		}
+	}
+}
+
+// TestOptimizeKeepsBehavior Runs programs with and without optimization and compares their output.
+func TestOptimizeKeepsBehavior(t *testing.T) {
+	programs := []string{
+		`fn fib(n) {
+    if n < 2 {
+        return n
+    }
+    return fib(n - 1) + fib(n - 2)
+}
+println(fib(2 * 5), 2 ** 10 - 1, -(3 + 4))`,
+		`for i := 0, i < 2 + 3, i++ {
+    match i * 1 {
+        0 => println("zero")
+        1 => println("one")
+        2 => println("two")
+        _ => println(i + 10 * 2)
+    }
+}`,
+		`x := 1 + 2 > 2 ? "yes" : "no"
+try {
+    println(x, 1 / (1 - 1))
+} catch (e) {
+    println("caught", e)
+}`,
+	}
+	for _, src := range programs {
+		bc, _, err := CompileSource([]byte(src), vm.Builtins()...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, got := run(t, bc), run(t, Optimize(bc))
+		if got != want {
+			t.Errorf("optimized output of\n%s\ngot\n%s\nwant\n%s", src, got, want)
+		}
+		if len(Optimize(bc)) >= len(bc) {
+			t.Errorf("nothing optimized in\n%s", src)
+		}
+	}
+}
+
+func run(t *testing.T, bc vm.Bytecode) string {
+	t.Helper()
+	var out bytes.Buffer
+	v := vm.New()
+	v.SetOutput(&out)
+	if err := v.Execute(bc); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
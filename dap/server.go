@@ -0,0 +1,593 @@
+// Package dap implements a debug adapter for script files. It speaks the Debug Adapter Protocol, messages framed by
+// Content-Length headers, over stdio or a TCP connection and drives a vm.Debugger.
+//
+// Requests are handled one at a time. While the program runs, after continue or a step, no requests are read, so the
+// execution cannot be paused.
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"script"
+	"script/compiler"
+	"script/vm"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// threadID is the id of the only thread.
+const threadID = 1
+
+// errTerminate ends a session after a disconnect or terminate request.
+var errTerminate = errors.New("terminate")
+
+// Server is a debug adapter for a single debugging session.
+type Server struct {
+	r  *textproto.Reader
+	mu sync.Mutex
+	w  io.Writer
+
+	seq int
+
+	linesStartAt1, columnsStartAt1 bool
+
+	program     string
+	debugger    *vm.Debugger
+	stopOnEntry bool
+	noDebug     bool
+	// breakpoints holds the ids of the breakpoints set by setBreakpoints.
+	breakpoints []int
+	failed      bool
+
+	// calls and handles are the call stack and the variable references of the current stop. They are invalidated
+	// when the execution resumes.
+	calls   []vm.Call
+	handles []any
+}
+
+type handler func(s *Server, args json.RawMessage) (body any, after func(), err error)
+
+var handlers map[string]handler
+
+func init() {
+	handlers = map[string]handler{
+		"initialize":        (*Server).initialize,
+		"launch":            (*Server).launch,
+		"setBreakpoints":    (*Server).setBreakpoints,
+		"configurationDone": (*Server).configurationDone,
+		"threads":           (*Server).threads,
+		"continue":          resume("continue"),
+		"next":              resume("next"),
+		"stepIn":            resume("stepIn"),
+		"stepOut":           resume("stepOut"),
+		"stackTrace":        (*Server).stackTrace,
+		"scopes":            (*Server).scopes,
+		"variables":         (*Server).variables,
+		"evaluate":          (*Server).evaluate,
+		"disconnect":        (*Server).terminate,
+		"terminate":         (*Server).terminate,
+	}
+}
+
+func NewServer() *Server {
+	return &Server{linesStartAt1: true, columnsStartAt1: true}
+}
+
+// ServeListener Accepts connections on l and runs a session on each, one at a time, until l is closed.
+func ServeListener(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		err = NewServer().Serve(conn, conn)
+		conn.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Serve Reads requests from r and writes responses and events to w until the client disconnects or closes r.
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	s.r = textproto.NewReader(bufio.NewReader(r))
+	s.w = w
+
+	for {
+		req, err := s.read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		body, after, err := s.dispatch(req)
+		res := &Response{Type: "response", RequestSeq: req.Seq, Success: err == nil, Command: req.Command, Body: body}
+		if err != nil && err != errTerminate {
+			res.Message = err.Error()
+		}
+		if err == errTerminate {
+			res.Success = true
+		}
+		if werr := s.send(res); werr != nil {
+			return werr
+		}
+		if err == errTerminate {
+			return nil
+		}
+		if after != nil {
+			after()
+		}
+	}
+}
+
+func (s *Server) dispatch(req *Request) (body any, after func(), err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("internal error: %v", r)
+		}
+	}()
+
+	h, ok := handlers[req.Command]
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported command %q", req.Command)
+	}
+	return h(s, req.Arguments)
+}
+
+func (s *Server) read() (*Request, error) {
+	header, err := s.r.ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(strings.TrimSpace(header.Get("Content-Length")))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length: %w", err)
+	}
+
+	data := make([]byte, length)
+	if _, err = io.ReadFull(s.r.R, data); err != nil {
+		return nil, err
+	}
+
+	req := &Request{}
+	if err = json.Unmarshal(data, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// send Writes a response or an event. Its sequence number is set.
+func (s *Server) send(m any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	switch m := m.(type) {
+	case *Response:
+		m.Seq = s.seq
+	case *Event:
+		m.Seq = s.seq
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if _, err = fmt.Fprintf(s.w, "Content-Length: %d\r\n\r\n", len(data)); err != nil {
+		return err
+	}
+	_, err = s.w.Write(data)
+	return err
+}
+
+func (s *Server) event(name string, body any) {
+	s.send(&Event{Type: "event", Event: name, Body: body})
+}
+
+// output Sends what the program writes as output events.
+type output struct {
+	s        *Server
+	category string
+}
+
+func (o output) Write(p []byte) (int, error) {
+	o.s.event("output", &OutputEventBody{Category: o.category, Output: string(p)})
+	return len(p), nil
+}
+
+func decode[T any](args json.RawMessage) (*T, error) {
+	v := new(T)
+	if len(args) == 0 {
+		return v, nil
+	}
+	if err := json.Unmarshal(args, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (s *Server) initialize(raw json.RawMessage) (any, func(), error) {
+	args, err := decode[InitializeArguments](raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	if args.LinesStartAt1 != nil {
+		s.linesStartAt1 = *args.LinesStartAt1
+	}
+	if args.ColumnsStartAt1 != nil {
+		s.columnsStartAt1 = *args.ColumnsStartAt1
+	}
+
+	return &Capabilities{
+		SupportsConfigurationDoneRequest: true,
+		SupportsEvaluateForHovers:        true,
+		SupportsTerminateRequest:         true,
+	}, nil, nil
+}
+
+// launch Compiles the program. It starts running once the client sent the configuration, see configurationDone.
+func (s *Server) launch(raw json.RawMessage) (any, func(), error) {
+	args, err := decode[LaunchArguments](raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	if s.debugger != nil {
+		return nil, nil, errors.New("already launched")
+	}
+
+	program, err := filepath.Abs(args.Program)
+	if err != nil {
+		return nil, nil, err
+	}
+	src, err := os.ReadFile(program)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	v := vm.New()
+	v.SetOutput(output{s, "stdout"})
+	bc, _, err := compiler.CompileSource(src, v.Globals()...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", args.Program, err)
+	}
+
+	s.program = program
+	s.stopOnEntry, s.noDebug = args.StopOnEntry, args.NoDebug
+	s.debugger = v.Debug(bc)
+	s.debugger.SetSource(script.NewSource(program, src))
+
+	return nil, func() {
+		s.event("initialized", nil)
+	}, nil
+}
+
+func (s *Server) launched() error {
+	if s.debugger == nil {
+		return errors.New("no program launched")
+	}
+	return nil
+}
+
+func (s *Server) setBreakpoints(raw json.RawMessage) (any, func(), error) {
+	args, err := decode[SetBreakpointsArguments](raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = s.launched(); err != nil {
+		return nil, nil, err
+	}
+
+	breakpoints := make([]Breakpoint, len(args.Breakpoints))
+	path, _ := filepath.Abs(args.Source.Path)
+	if path != s.program {
+		for i, b := range args.Breakpoints {
+			breakpoints[i] = Breakpoint{Line: b.Line, Message: "not part of the program"}
+		}
+		return &SetBreakpointsResponseBody{Breakpoints: breakpoints}, nil, nil
+	}
+
+	// The arguments replace all breakpoints of the source.
+	for _, id := range s.breakpoints {
+		s.debugger.Clear(id)
+	}
+	s.breakpoints = nil
+
+	for i, b := range args.Breakpoints {
+		bp, err := s.debugger.BreakLine(s.lineIn(b.Line))
+		if err != nil {
+			breakpoints[i] = Breakpoint{Line: b.Line, Message: err.Error()}
+			continue
+		}
+		s.breakpoints = append(s.breakpoints, bp.ID)
+		breakpoints[i] = Breakpoint{ID: bp.ID, Verified: true, Source: s.source(), Line: s.lineOut(bp.Line)}
+	}
+	return &SetBreakpointsResponseBody{Breakpoints: breakpoints}, nil, nil
+}
+
+func (s *Server) configurationDone(json.RawMessage) (any, func(), error) {
+	if err := s.launched(); err != nil {
+		return nil, nil, err
+	}
+	return nil, func() {
+		switch {
+		case s.noDebug:
+			s.debugger.ClearAll()
+			s.run(s.debugger.Continue, "")
+		case s.stopOnEntry:
+			s.run(s.debugger.StepIn, "entry")
+		default:
+			s.run(s.debugger.Continue, "")
+		}
+	}, nil
+}
+
+func (s *Server) threads(json.RawMessage) (any, func(), error) {
+	return &ThreadsResponseBody{Threads: []Thread{{ID: threadID, Name: "main"}}}, nil, nil
+}
+
+// resume Returns the handler of continue, next, stepIn or stepOut. The execution starts after the response was sent.
+func resume(command string) handler {
+	return func(s *Server, _ json.RawMessage) (any, func(), error) {
+		return s.resume(command)
+	}
+}
+
+func (s *Server) resume(command string) (any, func(), error) {
+	if err := s.launched(); err != nil {
+		return nil, nil, err
+	}
+
+	var body any
+	step := s.debugger.Continue
+	switch command {
+	case "continue":
+		body = &ContinueResponseBody{AllThreadsContinued: true}
+	case "next":
+		step = s.debugger.StepOver
+	case "stepIn":
+		step = s.debugger.StepIn
+	case "stepOut":
+		step = s.debugger.StepOut
+	}
+	return body, func() {
+		s.run(step, "")
+	}, nil
+}
+
+// run Resumes the execution with step and reports why it stopped. reason overrides the reason of stopped events.
+func (s *Server) run(step func() (vm.StopReason, error), reason string) {
+	s.calls, s.handles = nil, nil
+
+	if s.failed {
+		// The program cannot continue after an exception.
+		s.exit(1)
+		return
+	}
+
+	stop, err := step()
+	body := &StoppedEventBody{ThreadID: threadID, AllThreadsStopped: true}
+	switch stop {
+	case vm.StopExited:
+		s.exit(0)
+		return
+	case vm.StopException:
+		s.failed = true
+		body.Reason, body.Description, body.Text = "exception", "Runtime error", err.Error()
+		s.event("output", &OutputEventBody{Category: "stderr", Output: err.Error() + "\n"})
+	case vm.StopBreakpoint:
+		body.Reason, body.HitBreakpointIDs = "breakpoint", []int{s.debugger.Hit().ID}
+	default:
+		body.Reason = "step"
+	}
+	if reason != "" {
+		body.Reason = reason
+	}
+	s.event("stopped", body)
+}
+
+func (s *Server) exit(code int) {
+	s.event("exited", &ExitedEventBody{ExitCode: code})
+	s.event("terminated", nil)
+}
+
+func (s *Server) stackTrace(raw json.RawMessage) (any, func(), error) {
+	args, err := decode[StackTraceArguments](raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = s.launched(); err != nil {
+		return nil, nil, err
+	}
+
+	if s.calls == nil {
+		s.calls = s.debugger.Calls()
+	}
+	frames := make([]StackFrame, len(s.calls))
+	for i, call := range s.calls {
+		name := call.Name
+		if name == "" {
+			name = "<anonymous>"
+		}
+		frames[i] = StackFrame{ID: i + 1, Name: name}
+		if p := s.debugger.PositionAt(call.Pointer); p.Line > 0 {
+			frames[i].Source = s.source()
+			frames[i].Line, frames[i].Column = s.lineOut(p.Line), s.columnOut(p.Column)
+		}
+	}
+
+	total := len(frames)
+	frames = frames[min(args.StartFrame, total):]
+	if args.Levels > 0 && args.Levels < len(frames) {
+		frames = frames[:args.Levels]
+	}
+	return &StackTraceResponseBody{StackFrames: frames, TotalFrames: total}, nil, nil
+}
+
+// call Returns the call a frame id of a stack trace refers to.
+func (s *Server) call(frameID int) (vm.Call, error) {
+	if s.calls == nil {
+		s.calls = s.debugger.Calls()
+	}
+	if frameID < 1 || frameID > len(s.calls) {
+		return vm.Call{}, fmt.Errorf("unknown frame %d", frameID)
+	}
+	return s.calls[frameID-1], nil
+}
+
+// scopes Returns the local variables of a call, made of the frames up to the call frame, and the globals.
+func (s *Server) scopes(raw json.RawMessage) (any, func(), error) {
+	args, err := decode[ScopesArguments](raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = s.launched(); err != nil {
+		return nil, nil, err
+	}
+	call, err := s.call(args.FrameID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	locals := make([]*vm.Frame, 0)
+	global := call.Frame
+	for f := call.Frame; f.Parent != nil; f = f.Parent {
+		locals = append(locals, f)
+		global = f.Parent
+		if f.IsCall() {
+			break
+		}
+	}
+	for global.Parent != nil {
+		global = global.Parent
+	}
+
+	scopes := make([]Scope, 0, 2)
+	if len(locals) > 0 {
+		scopes = append(scopes, Scope{Name: "Locals", PresentationHint: "locals", VariablesReference: s.reference(locals)})
+	}
+	scopes = append(scopes, Scope{Name: "Globals", VariablesReference: s.reference([]*vm.Frame{global})})
+	return &ScopesResponseBody{Scopes: scopes}, nil, nil
+}
+
+// reference Returns a variables reference for a list of frames or an array.
+func (s *Server) reference(v any) int {
+	s.handles = append(s.handles, v)
+	return len(s.handles)
+}
+
+func (s *Server) variables(raw json.RawMessage) (any, func(), error) {
+	args, err := decode[VariablesArguments](raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	if args.VariablesReference < 1 || args.VariablesReference > len(s.handles) {
+		return nil, nil, fmt.Errorf("unknown variables reference %d", args.VariablesReference)
+	}
+
+	variables := make([]Variable, 0)
+	switch v := s.handles[args.VariablesReference-1].(type) {
+	case []*vm.Frame:
+		// Inner frames shadow outer ones.
+		seen := make(map[string]bool)
+		builtins := vm.Builtins()
+		for _, f := range v {
+			for _, name := range f.Names() {
+				if seen[name] || f.Parent == nil && slices.Contains(builtins, name) {
+					continue
+				}
+				seen[name] = true
+				variables = append(variables, s.variable(name, f.Declared[name]))
+			}
+		}
+	case *vm.ArrayValue:
+		for i, element := range v.Elements() {
+			variables = append(variables, s.variable(fmt.Sprintf("[%d]", i), element))
+		}
+	}
+	return &VariablesResponseBody{Variables: variables}, nil, nil
+}
+
+// variable Describes a value. Arrays get a reference to expand their elements.
+func (s *Server) variable(name string, v any) Variable {
+	variable := Variable{Name: name, Value: vm.Repr(v), Type: strings.ToLower(vm.TypeOf(v).String())}
+	if arr, ok := v.(*vm.ArrayValue); ok {
+		variable.VariablesReference = s.reference(arr)
+		variable.IndexedVariables = arr.Len()
+	}
+	return variable
+}
+
+// evaluate Evaluates variable names in the context of a call, e.g. for hovers.
+func (s *Server) evaluate(raw json.RawMessage) (any, func(), error) {
+	args, err := decode[EvaluateArguments](raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = s.launched(); err != nil {
+		return nil, nil, err
+	}
+
+	frame := s.debugger.Frames()[0]
+	if args.FrameID > 0 {
+		call, err := s.call(args.FrameID)
+		if err != nil {
+			return nil, nil, err
+		}
+		frame = call.Frame
+	}
+
+	name := strings.TrimSpace(args.Expression)
+	v, ok := frame.Lookup(name)
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown variable %s", name)
+	}
+	variable := s.variable(name, v)
+	return &EvaluateResponseBody{
+		Result:             variable.Value,
+		Type:               variable.Type,
+		VariablesReference: variable.VariablesReference,
+	}, nil, nil
+}
+
+func (s *Server) terminate(json.RawMessage) (any, func(), error) {
+	return nil, nil, errTerminate
+}
+
+func (s *Server) source() *Source {
+	return &Source{Name: filepath.Base(s.program), Path: s.program}
+}
+
+func (s *Server) lineIn(line int) int {
+	if s.linesStartAt1 {
+		return line
+	}
+	return line + 1
+}
+
+func (s *Server) lineOut(line int) int {
+	if s.linesStartAt1 {
+		return line
+	}
+	return line - 1
+}
+
+func (s *Server) columnOut(column int) int {
+	if s.columnsStartAt1 {
+		return column
+	}
+	return column - 1
+}
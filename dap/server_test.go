@@ -0,0 +1,236 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// message is a response or an event read by the test client.
+type message struct {
+	Type       string          `json:"type"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Command    string          `json:"command"`
+	Message    string          `json:"message"`
+	Event      string          `json:"event"`
+	Body       json.RawMessage `json:"body"`
+}
+
+type client struct {
+	t   *testing.T
+	w   io.WriteCloser
+	r   *textproto.Reader
+	seq int
+	// events holds the events read while waiting for a response.
+	events []message
+}
+
+func start(t *testing.T) *client {
+	t.Helper()
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- NewServer().Serve(serverIn, serverOut)
+		serverOut.Close()
+	}()
+	t.Cleanup(func() {
+		clientOut.Close()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	})
+	return &client{t: t, w: clientOut, r: textproto.NewReader(bufio.NewReader(clientIn))}
+}
+
+func (c *client) read() message {
+	c.t.Helper()
+	header, err := c.r.ReadMIMEHeader()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	data := make([]byte, length)
+	if _, err = io.ReadFull(c.r.R, data); err != nil {
+		c.t.Fatal(err)
+	}
+	var m message
+	if err = json.Unmarshal(data, &m); err != nil {
+		c.t.Fatal(err)
+	}
+	return m
+}
+
+// request Sends a request and decodes the body of its successful response into body, unless it is nil.
+func (c *client) request(command string, args, body any) {
+	c.t.Helper()
+	c.seq++
+	data, err := json.Marshal(map[string]any{"seq": c.seq, "type": "request", "command": command, "arguments": args})
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if _, err = fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n%s", len(data), data); err != nil {
+		c.t.Fatal(err)
+	}
+
+	for {
+		m := c.read()
+		if m.Type == "event" {
+			c.events = append(c.events, m)
+			continue
+		}
+		if m.RequestSeq != c.seq || m.Command != command {
+			c.t.Fatalf("got response %+v to request %d %s", m, c.seq, command)
+		}
+		if !m.Success {
+			c.t.Fatalf("%s failed: %s", command, m.Message)
+		}
+		if body != nil {
+			if err = json.Unmarshal(m.Body, body); err != nil {
+				c.t.Fatal(err)
+			}
+		}
+		return
+	}
+}
+
+// event Returns the next event, which must be called name, and decodes its body into body, unless it is nil.
+func (c *client) event(name string, body any) {
+	c.t.Helper()
+	var m message
+	if len(c.events) > 0 {
+		m, c.events = c.events[0], c.events[1:]
+	} else {
+		m = c.read()
+	}
+	if m.Type != "event" || m.Event != name {
+		c.t.Fatalf("got %+v, want event %s", m, name)
+	}
+	if body != nil {
+		if err := json.Unmarshal(m.Body, body); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+}
+
+const program = `fn add(a, b) {
+    c := a + b
+    return c
+}
+x := add(1, 2)
+println(x)
+`
+
+func TestBreakpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "add.ys")
+	if err := os.WriteFile(path, []byte(program), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := start(t)
+
+	var capabilities Capabilities
+	c.request("initialize", InitializeArguments{AdapterID: "ys"}, &capabilities)
+	if !capabilities.SupportsConfigurationDoneRequest {
+		t.Errorf("got capabilities %+v", capabilities)
+	}
+	c.request("launch", LaunchArguments{Program: path}, nil)
+	c.event("initialized", nil)
+
+	var breakpoints SetBreakpointsResponseBody
+	c.request("setBreakpoints", SetBreakpointsArguments{
+		Source:      Source{Path: path},
+		Breakpoints: []SourceBreakpoint{{Line: 3}},
+	}, &breakpoints)
+	if len(breakpoints.Breakpoints) != 1 || !breakpoints.Breakpoints[0].Verified || breakpoints.Breakpoints[0].Line != 3 {
+		t.Fatalf("got breakpoints %+v", breakpoints.Breakpoints)
+	}
+
+	c.request("configurationDone", nil, nil)
+	var stopped StoppedEventBody
+	c.event("stopped", &stopped)
+	if stopped.Reason != "breakpoint" || len(stopped.HitBreakpointIDs) != 1 || stopped.HitBreakpointIDs[0] != breakpoints.Breakpoints[0].ID {
+		t.Fatalf("got stopped event %+v", stopped)
+	}
+
+	var trace StackTraceResponseBody
+	c.request("stackTrace", StackTraceArguments{ThreadID: threadID}, &trace)
+	var frames []string
+	for _, f := range trace.StackFrames {
+		if f.Source == nil || f.Source.Path != path {
+			t.Errorf("frame %s has source %+v", f.Name, f.Source)
+		}
+		frames = append(frames, fmt.Sprintf("%s %d:%d", f.Name, f.Line, f.Column))
+	}
+	if got, want := strings.Join(frames, ", "), "add 3:12, main 5:6"; got != want || trace.TotalFrames != 2 {
+		t.Errorf("got stack trace %s (%d frames), want %s", got, trace.TotalFrames, want)
+	}
+
+	var scopes ScopesResponseBody
+	c.request("scopes", ScopesArguments{FrameID: trace.StackFrames[0].ID}, &scopes)
+	if len(scopes.Scopes) != 2 || scopes.Scopes[0].Name != "Locals" {
+		t.Fatalf("got scopes %+v", scopes.Scopes)
+	}
+	var variables VariablesResponseBody
+	c.request("variables", VariablesArguments{VariablesReference: scopes.Scopes[0].VariablesReference}, &variables)
+	var locals []string
+	for _, v := range variables.Variables {
+		locals = append(locals, v.Name+"="+v.Value)
+	}
+	if got, want := strings.Join(locals, " "), "a=1 add=<fn add(a, b)> b=2 c=3"; got != want {
+		t.Errorf("got locals %s, want %s", got, want)
+	}
+
+	c.request("continue", ThreadArguments{ThreadID: threadID}, nil)
+	var output OutputEventBody
+	c.event("output", &output)
+	if output.Category != "stdout" || output.Output != "3\n" {
+		t.Errorf("got output %+v", output)
+	}
+	var exited ExitedEventBody
+	c.event("exited", &exited)
+	if exited.ExitCode != 0 {
+		t.Errorf("got exit code %d", exited.ExitCode)
+	}
+	c.event("terminated", nil)
+	c.request("disconnect", nil, nil)
+}
+
+func TestBreakpointErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "add.ys")
+	if err := os.WriteFile(path, []byte(program), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := start(t)
+
+	c.request("initialize", InitializeArguments{}, nil)
+	c.request("launch", LaunchArguments{Program: path}, nil)
+	c.event("initialized", nil)
+
+	var breakpoints SetBreakpointsResponseBody
+	c.request("setBreakpoints", SetBreakpointsArguments{
+		Source:      Source{Path: filepath.Join(filepath.Dir(path), "other.ys")},
+		Breakpoints: []SourceBreakpoint{{Line: 1}},
+	}, &breakpoints)
+	if b := breakpoints.Breakpoints; len(b) != 1 || b[0].Verified || b[0].Message != "not part of the program" {
+		t.Errorf("got breakpoints %+v for another source", b)
+	}
+	c.request("setBreakpoints", SetBreakpointsArguments{
+		Source:      Source{Path: path},
+		Breakpoints: []SourceBreakpoint{{Line: 100}},
+	}, &breakpoints)
+	if b := breakpoints.Breakpoints; len(b) != 1 || b[0].Verified || b[0].Message == "" {
+		t.Errorf("got breakpoints %+v for a line without code", b)
+	}
+	c.request("terminate", nil, nil)
+}
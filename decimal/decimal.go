@@ -0,0 +1,191 @@
+package decimal
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// QuoScale is the minimum number of decimal places of a quotient, see Decimal.Quo.
+const QuoScale = 16
+
+var ErrDivisionByZero = errors.New("division by zero")
+
+// Decimal is a fixed-point decimal number, the unscaled integer divided by 10 to the power of the scale. 12.50 has the
+// unscaled value 1250 and the scale 2. Sums, differences and products are exact. The zero value is 0. Decimals are
+// never modified once created.
+type Decimal struct {
+	unscaled *big.Int
+	scale    int32
+}
+
+// New Returns unscaled divided by 10 to the power of scale.
+func New(unscaled int64, scale int32) Decimal {
+	return Decimal{big.NewInt(unscaled), scale}
+}
+
+// FromBig Returns the integer i as decimal without decimal places.
+func FromBig(i *big.Int) Decimal {
+	return Decimal{new(big.Int).Set(i), 0}
+}
+
+// FromFloat Returns the shortest decimal that converts back to f, e.g. 0.1 for the float nearest to 0.1.
+func FromFloat(f float64) (Decimal, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return Decimal{}, fmt.Errorf("cannot convert %v to decimal", f)
+	}
+	return Parse(strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+// Parse Returns the decimal of s, an optionally signed number of decimal digits with an optional fraction, e.g. -12.50.
+// The scale is the number of digits of the fraction.
+func Parse(s string) (Decimal, error) {
+	digits := strings.TrimLeft(s, "+-")
+	if len(s)-len(digits) > 1 {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+	}
+	integer, fraction, _ := strings.Cut(digits, ".")
+	if integer+fraction == "" || strings.Trim(integer+fraction, "0123456789") != "" {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+	}
+	unscaled, _ := new(big.Int).SetString(integer+fraction, 10)
+	if strings.HasPrefix(s, "-") {
+		unscaled.Neg(unscaled)
+	}
+	return Decimal{unscaled, int32(len(fraction))}, nil
+}
+
+func (d Decimal) value() *big.Int {
+	if d.unscaled == nil {
+		return new(big.Int)
+	}
+	return d.unscaled
+}
+
+// Scale Returns the number of decimal places of d.
+func (d Decimal) Scale() int {
+	return int(d.scale)
+}
+
+// String Returns d with all of its decimal places, e.g. 12.50.
+func (d Decimal) String() string {
+	digits := new(big.Int).Abs(d.value()).String()
+	sign := ""
+	if d.Sign() < 0 {
+		sign = "-"
+	}
+	if d.scale == 0 {
+		return sign + digits
+	}
+	if n := int(d.scale) + 1 - len(digits); n > 0 {
+		digits = strings.Repeat("0", n) + digits
+	}
+	point := len(digits) - int(d.scale)
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// Sign Returns -1, 0 or 1 for a negative, zero or positive d.
+func (d Decimal) Sign() int {
+	return d.value().Sign()
+}
+
+// rescale Returns the unscaled value of d with the larger scale, which must not be less than the scale of d.
+func (d Decimal) rescale(scale int32) *big.Int {
+	if scale == d.scale {
+		return d.value()
+	}
+	return new(big.Int).Mul(d.value(), pow10(scale-d.scale))
+}
+
+// align Returns the unscaled values of d and e with the scale of the one with more decimal places.
+func align(d, e Decimal) (*big.Int, *big.Int, int32) {
+	scale := max(d.scale, e.scale)
+	return d.rescale(scale), e.rescale(scale), scale
+}
+
+func pow10(n int32) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+func (d Decimal) Add(e Decimal) Decimal {
+	x, y, scale := align(d, e)
+	return Decimal{new(big.Int).Add(x, y), scale}
+}
+
+func (d Decimal) Sub(e Decimal) Decimal {
+	x, y, scale := align(d, e)
+	return Decimal{new(big.Int).Sub(x, y), scale}
+}
+
+// Mul Returns the product of d and e, which has the decimal places of both.
+func (d Decimal) Mul(e Decimal) Decimal {
+	return Decimal{new(big.Int).Mul(d.value(), e.value()), d.scale + e.scale}
+}
+
+// Quo Returns the quotient of d and e rounded half to even to the scale of d or e, but at least to QuoScale decimal
+// places.
+func (d Decimal) Quo(e Decimal) (Decimal, error) {
+	if e.Sign() == 0 {
+		return Decimal{}, ErrDivisionByZero
+	}
+	scale := max(d.scale, e.scale, QuoScale)
+	// One more place than needed is computed, the remainder decides about the rounding of ties.
+	x := new(big.Int).Mul(d.value(), pow10(scale+1+e.scale-d.scale))
+	q, r := new(big.Int).QuoRem(x, e.value(), new(big.Int))
+	quotient := Decimal{q, scale + 1}
+	if r.Sign() != 0 {
+		// A remainder makes an exact tie a value above it, so add a digit that is not zero.
+		q.Mul(q, big.NewInt(10))
+		q.Add(q, big.NewInt(int64(r.Sign()*e.value().Sign())))
+		quotient.scale++
+	}
+	return quotient.Round(scale, HalfEven), nil
+}
+
+// Rem Returns the remainder of d / e truncated to an integer. It has the sign of d, like the remainder of integers.
+func (d Decimal) Rem(e Decimal) (Decimal, error) {
+	if e.Sign() == 0 {
+		return Decimal{}, ErrDivisionByZero
+	}
+	x, y, scale := align(d, e)
+	return Decimal{new(big.Int).Rem(x, y), scale}, nil
+}
+
+// Pow Returns d raised to the power of n. A negative power is the quotient of 1 and the positive one, see Quo.
+func (d Decimal) Pow(n int64) (Decimal, error) {
+	if n < 0 {
+		p, err := d.Pow(-n)
+		if err != nil {
+			return Decimal{}, err
+		}
+		return New(1, 0).Quo(p)
+	}
+	if int64(d.scale)*n > math.MaxInt32 {
+		return Decimal{}, fmt.Errorf("decimal power %d is too large", n)
+	}
+	return Decimal{new(big.Int).Exp(d.value(), big.NewInt(n), nil), d.scale * int32(n)}, nil
+}
+
+func (d Decimal) Neg() Decimal {
+	return Decimal{new(big.Int).Neg(d.value()), d.scale}
+}
+
+// Cmp Returns -1, 0 or 1 if d is less than, equal to or greater than e. Trailing zeros do not matter, 1.50 equals 1.5.
+func (d Decimal) Cmp(e Decimal) int {
+	x, y, _ := align(d, e)
+	return x.Cmp(y)
+}
+
+// Int Returns the integer part of d, truncated towards zero.
+func (d Decimal) Int() *big.Int {
+	return new(big.Int).Quo(d.value(), pow10(d.scale))
+}
+
+// Float64 Returns the float nearest to d.
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
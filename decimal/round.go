@@ -0,0 +1,76 @@
+package decimal
+
+import "math/big"
+
+// Mode is the way Round treats the digits it drops.
+type Mode uint8
+
+const (
+	// HalfEven Rounds to the nearest neighbour, ties to the even one. It is the default as it is not biased.
+	HalfEven Mode = iota
+	// HalfUp Rounds to the nearest neighbour, ties away from zero.
+	HalfUp
+	// HalfDown Rounds to the nearest neighbour, ties towards zero.
+	HalfDown
+	// Up Rounds away from zero.
+	Up
+	// Down Rounds towards zero, it truncates.
+	Down
+	// Ceiling Rounds towards positive infinity.
+	Ceiling
+	// Floor Rounds towards negative infinity.
+	Floor
+)
+
+// Modes maps the names of the rounding modes to them, e.g. "half_up" to HalfUp.
+var Modes = map[string]Mode{
+	"half_even": HalfEven,
+	"half_up":   HalfUp,
+	"half_down": HalfDown,
+	"up":        Up,
+	"down":      Down,
+	"ceiling":   Ceiling,
+	"floor":     Floor,
+}
+
+// Round Returns d with exactly places decimal places, which must not be negative. Dropped digits are rounded with
+// mode, missing ones are zeros.
+func (d Decimal) Round(places int32, mode Mode) Decimal {
+	if places >= d.scale {
+		return Decimal{d.rescale(places), places}
+	}
+
+	divisor := pow10(d.scale - places)
+	q, r := new(big.Int).QuoRem(d.value(), divisor, new(big.Int))
+	if r.Sign() == 0 {
+		return Decimal{q, places}
+	}
+
+	// The remainder has the sign of d. Compare twice its magnitude to the divisor to find ties.
+	sign := r.Sign()
+	half := new(big.Int).Abs(r)
+	half.Lsh(half, 1)
+	c := half.Cmp(divisor)
+
+	var away bool
+	switch mode {
+	case HalfEven:
+		away = c > 0 || c == 0 && q.Bit(0) == 1
+	case HalfUp:
+		away = c >= 0
+	case HalfDown:
+		away = c > 0
+	case Up:
+		away = true
+	case Down:
+		away = false
+	case Ceiling:
+		away = sign > 0
+	case Floor:
+		away = sign < 0
+	}
+	if away {
+		q.Add(q, big.NewInt(int64(sign)))
+	}
+	return Decimal{q, places}
+}
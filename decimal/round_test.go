@@ -0,0 +1,67 @@
+package decimal
+
+import "testing"
+
+func TestRound(t *testing.T) {
+	// Every mode rounds the same values to no decimal places: ties, values below and above a tie, negative ones and
+	// one that needs no rounding.
+	values := []string{"2.5", "3.5", "2.4", "2.6", "-2.5", "-2.4", "-2.6", "7"}
+	tests := map[Mode][]string{
+		HalfEven: {"2", "4", "2", "3", "-2", "-2", "-3", "7"},
+		HalfUp:   {"3", "4", "2", "3", "-3", "-2", "-3", "7"},
+		HalfDown: {"2", "3", "2", "3", "-2", "-2", "-3", "7"},
+		Up:       {"3", "4", "3", "3", "-3", "-3", "-3", "7"},
+		Down:     {"2", "3", "2", "2", "-2", "-2", "-2", "7"},
+		Ceiling:  {"3", "4", "3", "3", "-2", "-2", "-2", "7"},
+		Floor:    {"2", "3", "2", "2", "-3", "-3", "-3", "7"},
+	}
+	if len(tests) != len(Modes) {
+		t.Fatalf("%d modes are tested, but there are %d", len(tests), len(Modes))
+	}
+
+	for name, mode := range Modes {
+		t.Run(name, func(t *testing.T) {
+			want, ok := tests[mode]
+			if !ok {
+				t.Fatalf("mode %s is not tested", name)
+			}
+			for i, value := range values {
+				d, err := Parse(value)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := d.Round(0, mode).String(); got != want[i] {
+					t.Errorf("%s: got %s, want %s", value, got, want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRoundPlaces(t *testing.T) {
+	tests := []struct {
+		value  string
+		places int32
+		mode   Mode
+		want   string
+	}{
+		{"1.2345", 2, HalfEven, "1.23"},
+		{"1.2350", 2, HalfEven, "1.24"},
+		{"1.2250", 2, HalfEven, "1.22"},
+		{"1.2251", 2, HalfEven, "1.23"},
+		{"-1.2250", 2, HalfUp, "-1.23"},
+		{"1.2", 3, HalfEven, "1.200"},
+		{"0.0004", 3, Up, "0.001"},
+		{"-0.0004", 3, Ceiling, "0.000"},
+		{"125", 0, HalfDown, "125"},
+	}
+	for _, test := range tests {
+		d, err := Parse(test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := d.Round(test.places, test.mode).String(); got != test.want {
+			t.Errorf("round(%s, %d): got %s, want %s", test.value, test.places, got, test.want)
+		}
+	}
+}
@@ -4,7 +4,8 @@ import (
 	"fmt"
 )
 
-const PanicOnError = true
+// PanicOnError Makes position errors panic when they are created. Tools that report errors should disable it.
+var PanicOnError = true
 
 func NewPosError(pos int, message string) *PosError {
 	e := &PosError{
@@ -18,7 +19,9 @@ func NewPosError(pos int, message string) *PosError {
 }
 
 type PosError struct {
-	Pos     int
+	Pos int
+	// End is the position after the erroneous source, or 0 if only its start is known.
+	End     int
 	Message string
 }
 
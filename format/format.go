@@ -0,0 +1,575 @@
+// Package format prints an ast.Program as source code in the canonical style.
+//
+// The canonical style indents blocks with four spaces, puts single spaces around binary operators and after commas,
+// keeps at most one blank line between statements and always puts the statements of a block on their own lines.
+package format
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"script"
+	"script/ast"
+	"script/lexer"
+	"strconv"
+)
+
+const indent = "    "
+
+// Source Formats src. Comments are kept.
+func Source(src []byte) (out []byte, err error) {
+	defer func() {
+		switch r := recover().(type) {
+		case nil:
+		case *script.PosError:
+			err = r
+		default:
+			panic(r)
+		}
+	}()
+
+	tokens, comments, errs := lexer.Scan(src)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	program, errs := ast.Parse(tokens)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	var buf bytes.Buffer
+	p := &printer{
+		w:        &buf,
+		src:      []rune(string(src)),
+		comments: comments,
+	}
+	p.program(program)
+	return buf.Bytes(), nil
+}
+
+// Fprint Writes the canonical source of program to w. Use Source to keep comments.
+func Fprint(w io.Writer, program *ast.Program) error {
+	var buf bytes.Buffer
+	p := &printer{w: &buf}
+	p.program(program)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+type printer struct {
+	w     *bytes.Buffer
+	depth int
+	// src is the formatted source. It is used to keep blank lines and may be nil.
+	src      []rune
+	comments []lexer.Comment
+}
+
+func (p *printer) write(s ...string) {
+	for _, v := range s {
+		p.w.WriteString(v)
+	}
+}
+
+func (p *printer) newline() {
+	p.w.WriteByte('\n')
+}
+
+func (p *printer) indent() {
+	for i := 0; i < p.depth; i++ {
+		p.w.WriteString(indent)
+	}
+}
+
+// blankBefore Reports whether the source has an empty line right before the line containing pos.
+func (p *printer) blankBefore(pos int) bool {
+	if pos <= 0 || pos > len(p.src) {
+		return false
+	}
+	i := pos - 1
+	for i >= 0 && (p.src[i] == ' ' || p.src[i] == '\t') {
+		i--
+	}
+	if i < 0 || p.src[i] != '\n' {
+		return false
+	}
+	i--
+	for i >= 0 && (p.src[i] == ' ' || p.src[i] == '\t' || p.src[i] == '\r') {
+		i--
+	}
+	return i >= 0 && p.src[i] == '\n'
+}
+
+// atStart Reports whether nothing has been printed yet in the current block.
+func (p *printer) atStart() bool {
+	out := p.w.Bytes()
+	return len(out) == 0 || bytes.HasSuffix(out, []byte("{\n"))
+}
+
+// flush Prints all comments before pos. Trailing comments are appended to the last printed line.
+func (p *printer) flush(pos int) {
+	for len(p.comments) > 0 && (pos < 0 || p.comments[0].Pos < pos) {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+
+		if c.Trailing && p.w.Len() > 0 {
+			p.w.Truncate(p.w.Len() - 1)
+			p.write(" ", c.Text)
+			p.newline()
+			continue
+		}
+
+		if !p.atStart() && p.blankBefore(c.Pos) {
+			p.newline()
+		}
+		p.indent()
+		p.write(c.Text)
+		p.newline()
+	}
+}
+
+func (p *printer) program(program *ast.Program) {
+	for _, stmt := range program.Statements {
+		pos := stmt.Tok().Pos
+		p.flush(pos)
+		if !p.atStart() && p.blankBefore(pos) {
+			p.newline()
+		}
+		p.stmt(stmt)
+		p.newline()
+	}
+	p.flush(-1)
+}
+
+func (p *printer) block(b *ast.BlockStmt) {
+	closing := b.Closing().Pos
+	if len(b.Statements) == 0 && !p.hasComments(closing) {
+		p.write("{}")
+		return
+	}
+
+	p.write("{")
+	p.newline()
+	p.depth++
+	for _, stmt := range b.Statements {
+		pos := stmt.Tok().Pos
+		p.flush(pos)
+		if !p.atStart() && p.blankBefore(pos) {
+			p.newline()
+		}
+		p.indent()
+		p.stmt(stmt)
+		p.newline()
+	}
+	if closing >= 0 {
+		p.flush(closing)
+	}
+	p.depth--
+	p.indent()
+	p.write("}")
+}
+
+// hasComments Reports whether there are comments to print before pos.
+func (p *printer) hasComments(pos int) bool {
+	return pos >= 0 && len(p.comments) > 0 && p.comments[0].Pos < pos
+}
+
+func (p *printer) stmt(stmt ast.Stmt) {
+	switch s := stmt.(type) {
+	case *ast.DeclareStmt:
+		p.write(s.Ident.Symbol, " := ")
+		p.expr(s.Expr)
+	case *ast.FuncDeclStmt:
+		p.write("fn ", s.Name.Symbol)
+		p.function(s.Func)
+	case *ast.AssignStmt:
+		if s.Ident == nil {
+			p.expr(s.Expr)
+			return
+		}
+		p.write(s.Ident.Symbol, " = ")
+		p.expr(s.Expr)
+	case *ast.ExprStmt:
+		p.expr(s.Expr)
+	case *ast.CompoundAssignStmt:
+		p.expr(s.Target)
+		if s.Value == nil {
+			p.write(s.Operator().Lexeme)
+			return
+		}
+		p.write(" ", s.Operator().Lexeme, " ")
+		p.expr(s.Value)
+	case *ast.ArrayAssignStmt:
+		p.operand(s.Ident)
+		p.write("[")
+		p.expr(s.Index)
+		p.write("] = ")
+		p.expr(s.Expr)
+	case *ast.BlockStmt:
+		p.block(s)
+	case *ast.ConditionalStmt:
+		p.write("if ")
+		p.expr(s.Cond)
+		p.write(" ")
+		p.block(s.Block)
+		if s.Else != nil {
+			p.write(" else ")
+			p.stmt(s.Else)
+		}
+	case *ast.TryStmt:
+		p.write("try ")
+		p.block(s.Body)
+		if s.Catch != nil {
+			p.write(" catch ")
+			if s.Param != nil {
+				p.write("(", s.Param.Symbol, ") ")
+			}
+			p.block(s.Catch)
+		}
+		if s.Finally != nil {
+			p.write(" finally ")
+			p.block(s.Finally)
+		}
+	case *ast.ThrowStmt:
+		p.write("throw ")
+		p.expr(s.Expr)
+	case *ast.DeferStmt:
+		p.write("defer ")
+		p.expr(s.Call)
+	case *ast.MatchStmt:
+		p.write("match ")
+		p.expr(s.Subject)
+		p.write(" {")
+		p.newline()
+		p.depth++
+		for _, arm := range s.Arms {
+			pos := arm.Tok().Pos
+			p.flush(pos)
+			if !p.atStart() && p.blankBefore(pos) {
+				p.newline()
+			}
+			p.indent()
+			for i, pattern := range arm.Patterns {
+				if i > 0 {
+					p.write(", ")
+				}
+				p.expr(pattern)
+			}
+			if arm.Guard != nil {
+				p.write(" if ")
+				p.expr(arm.Guard)
+			}
+			p.write(" => ")
+			p.stmt(arm.Body)
+			p.newline()
+		}
+		if closing := s.Closing().Pos; closing >= 0 {
+			p.flush(closing)
+		}
+		p.depth--
+		p.indent()
+		p.write("}")
+	case *ast.ForInStmt:
+		if s.Label != nil {
+			p.write(s.Label.Symbol, ": ")
+		}
+		p.write("for ")
+		if s.Key != nil {
+			p.write(s.Key.Symbol, ", ")
+		}
+		p.write(s.Value.Symbol, " in ")
+		p.expr(s.Iterable)
+		p.write(" ")
+		p.block(s.Stmt)
+	case *ast.ReturnStmt:
+		p.write("return")
+		for i, e := range s.Returned {
+			if i == 0 {
+				p.write(" ")
+			} else {
+				p.write(", ")
+			}
+			p.expr(e)
+		}
+	case *ast.ForStmt:
+		if s.Label != nil {
+			p.write(s.Label.Symbol, ": ")
+		}
+		p.write("for ")
+		if s.Init != nil || s.Cond != nil || s.Update != nil {
+			if s.Init != nil {
+				p.stmt(s.Init)
+			}
+			p.write(",")
+			if s.Cond != nil {
+				p.write(" ")
+				p.expr(s.Cond)
+			}
+			p.write(",")
+			if s.Update != nil {
+				p.write(" ")
+				p.stmt(s.Update)
+			}
+			p.write(" ")
+		}
+		p.stmt(s.Stmt)
+	case *ast.BreakStmt:
+		p.write("break")
+		if s.Label != nil {
+			p.write(" ", s.Label.Symbol)
+		}
+	case *ast.ContinueStmt:
+		p.write("continue")
+		if s.Label != nil {
+			p.write(" ", s.Label.Symbol)
+		}
+	default:
+		p.write("/* unknown statement */")
+	}
+}
+
+// function Prints the parameters and the body of a function.
+func (p *printer) function(f *ast.FunctionExpr) {
+	p.write("(")
+	for i, param := range f.Params {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.write(param.Symbol)
+	}
+	if f.IsVariadic {
+		p.write("...")
+	}
+	p.write(") ")
+	p.block(f.Body)
+}
+
+// precedence Returns the binding strength of a binary operator. Higher binds tighter.
+func precedence(op lexer.TokenId) int {
+	switch op {
+	case lexer.QUESTION_QUESTION:
+		return 1
+	case lexer.PIPE_PIPE:
+		return 2
+	case lexer.AND_AND:
+		return 3
+	case lexer.PIPE:
+		return 4
+	case lexer.CIRCUMFLEX:
+		return 5
+	case lexer.AND:
+		return 6
+	case lexer.EQUALS_EQUALS, lexer.EXCLAMATION_EQUALS:
+		return 7
+	case lexer.LESS_THAN, lexer.GREATER_THAN, lexer.LESS_THAN_EQUALS, lexer.GREATER_THAN_EQUALS:
+		return 8
+	case lexer.LESS_LESS, lexer.GREATER_GREATER:
+		return 9
+	case lexer.PLUS, lexer.MINUS:
+		return 10
+	case lexer.ASTERISK, lexer.SLASH, lexer.PERCENT:
+		return 11
+	case lexer.ASTERISK_ASTERISK:
+		return 12
+	default:
+		return 0
+	}
+}
+
+var operators = map[lexer.TokenId]string{
+	lexer.QUESTION_QUESTION:   "??",
+	lexer.PIPE_PIPE:           "||",
+	lexer.AND_AND:             "&&",
+	lexer.PIPE:                "|",
+	lexer.CIRCUMFLEX:          "^",
+	lexer.AND:                 "&",
+	lexer.EQUALS_EQUALS:       "==",
+	lexer.EXCLAMATION_EQUALS:  "!=",
+	lexer.LESS_THAN:           "<",
+	lexer.GREATER_THAN:        ">",
+	lexer.LESS_THAN_EQUALS:    "<=",
+	lexer.GREATER_THAN_EQUALS: ">=",
+	lexer.LESS_LESS:           "<<",
+	lexer.GREATER_GREATER:     ">>",
+	lexer.PLUS:                "+",
+	lexer.MINUS:               "-",
+	lexer.ASTERISK:            "*",
+	lexer.SLASH:               "/",
+	lexer.PERCENT:             "%",
+	lexer.ASTERISK_ASTERISK:   "**",
+	lexer.EXCLAMATION:         "!",
+	lexer.TILDE:               "~",
+}
+
+func (p *printer) expr(expr ast.Expr) {
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		p.write(e.Symbol)
+	case *ast.Number:
+		p.write(e.Value)
+	case *ast.String:
+		if lexeme := e.Tok().Lexeme; e.Tok().Id == lexer.STRING {
+			p.write(lexeme)
+		} else {
+			p.write(strconv.Quote(e.Value))
+		}
+	case *ast.BinaryExpr:
+		prec := precedence(e.Operator)
+		p.binaryOperand(e.Left, prec, false)
+		p.write(" ", operators[e.Operator], " ")
+		p.binaryOperand(e.Right, prec, true)
+	case *ast.UnaryExpr:
+		p.write(operators[e.Operator])
+		// A binary operand needs parentheses. Only ** binds tighter than a unary operator, but -(a ** b) reads better.
+		switch e.Expr.(type) {
+		case *ast.BinaryExpr, *ast.ConditionalExpr, *ast.RangeExpr:
+			p.write("(")
+			p.expr(e.Expr)
+			p.write(")")
+		default:
+			p.expr(e.Expr)
+		}
+	case *ast.RangeExpr:
+		p.rangeOperand(e.Start)
+		p.write("..")
+		p.rangeOperand(e.End)
+	case *ast.ConditionalExpr:
+		// Conditional expressions associate to the right, so only a conditional condition needs parentheses.
+		if _, ok := e.Cond.(*ast.ConditionalExpr); ok {
+			p.write("(")
+			p.expr(e.Cond)
+			p.write(")")
+		} else {
+			p.expr(e.Cond)
+		}
+		p.write(" ? ")
+		p.expr(e.Then)
+		p.write(" : ")
+		p.expr(e.Else)
+	case *ast.YieldExpr:
+		p.write("yield")
+		if e.Value != nil {
+			p.write(" ")
+			p.expr(e.Value)
+		}
+	case *ast.FunctionExpr:
+		p.write("fn ")
+		p.function(e)
+	case *ast.CallExpr:
+		p.operand(e.Caller)
+		p.write("(")
+		p.list(e.Args)
+		p.write(")")
+	case *ast.SubscriptExpr:
+		p.operand(e.Array)
+		if e.Optional {
+			p.write("?")
+		}
+		p.write("[")
+		p.expr(e.Index)
+		p.write("]")
+	case *ast.SliceExpr:
+		p.operand(e.Array)
+		if e.Optional {
+			p.write("?")
+		}
+		p.write("[")
+		if e.Low != nil {
+			p.sliceBound(e.Low)
+		}
+		p.write(":")
+		if e.High != nil {
+			p.sliceBound(e.High)
+		}
+		p.write("]")
+	case *ast.MemberExpr:
+		p.operand(e.Object)
+		if e.Optional {
+			p.write("?.")
+		} else {
+			p.write(".")
+		}
+		p.write(e.Name.Symbol)
+	case *ast.ArrayExpr:
+		p.write("[")
+		p.list(e.Elements)
+		p.write("]")
+	case *ast.NewExpr:
+		p.write("new(", e.TypeName.Symbol)
+		if e.Expression != nil {
+			p.write(", ")
+			p.expr(e.Expression)
+		}
+		p.write(")")
+	default:
+		p.write("/* unknown expression */")
+	}
+}
+
+func (p *printer) list(exprs []ast.Expr) {
+	for i, e := range exprs {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.expr(e)
+	}
+}
+
+// operand Prints the operand of a call or subscript, which has to be parenthesized unless it is a primary expression.
+func (p *printer) operand(e ast.Expr) {
+	switch e.(type) {
+	case *ast.BinaryExpr, *ast.UnaryExpr, *ast.RangeExpr, *ast.ConditionalExpr, *ast.YieldExpr, *ast.FunctionExpr:
+		p.write("(")
+		p.expr(e)
+		p.write(")")
+	default:
+		p.expr(e)
+	}
+}
+
+// rangeOperand Prints the start or the end of a range. Only a conditional expression binds looser than a range.
+func (p *printer) rangeOperand(e ast.Expr) {
+	if _, ok := e.(*ast.ConditionalExpr); !ok {
+		p.expr(e)
+		return
+	}
+	p.write("(")
+	p.expr(e)
+	p.write(")")
+}
+
+// sliceBound Prints a bound of a slice. A conditional expression gets parentheses, so that its colon is not mistaken
+// for the one of the slice.
+func (p *printer) sliceBound(e ast.Expr) {
+	if _, ok := e.(*ast.ConditionalExpr); !ok {
+		p.expr(e)
+		return
+	}
+	p.write("(")
+	p.expr(e)
+	p.write(")")
+}
+
+// binaryOperand Prints an operand of a binary operator with the given precedence. The binary operators except ** are
+// left-associative, so a right operand of the same precedence needs parentheses, for ** a left one.
+func (p *printer) binaryOperand(e ast.Expr, prec int, right bool) {
+	paren := false
+	switch o := e.(type) {
+	case *ast.BinaryExpr:
+		inner := precedence(o.Operator)
+		paren = inner < prec || (right != (o.Operator == lexer.ASTERISK_ASTERISK) && inner == prec)
+	case *ast.UnaryExpr:
+		// Unary operators bind tighter than the binary operators except **, as in (-a) ** 2.
+		paren = !right && prec == precedence(lexer.ASTERISK_ASTERISK)
+	case *ast.RangeExpr, *ast.ConditionalExpr, *ast.YieldExpr:
+		paren = true
+	}
+
+	if !paren {
+		p.expr(e)
+		return
+	}
+	p.write("(")
+	p.expr(e)
+	p.write(")")
+}
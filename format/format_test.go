@@ -0,0 +1,72 @@
+package format
+
+import "testing"
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "spacing",
+			src:  "x:=1+2*3\ny:=[1,2,3]\n",
+			want: "x := 1 + 2 * 3\ny := [1, 2, 3]\n",
+		},
+		{
+			name: "comments",
+			src:  "// header\nx := 1 // trailing\n\n\n\ny := 2\n",
+			want: "// header\nx := 1 // trailing\n\ny := 2\n",
+		},
+		{
+			name: "blocks",
+			src:  "fn add(a,b){return a+b}\nif add(1,2)>1 {println(\"big\")} else {println(\"small\")}\n",
+			want: "fn add(a, b) {\n    return a + b\n}\nif add(1, 2) > 1 {\n    println(\"big\")\n} else {\n    println(\"small\")\n}\n",
+		},
+		{
+			name: "loops",
+			src:  "for i:=0,i<3,i++ {continue}\nfor k,v in [1,2] {println(k,v)}\n",
+			want: "for i := 0, i < 3, i++ {\n    continue\n}\nfor k, v in [1, 2] {\n    println(k, v)\n}\n",
+		},
+		{
+			name: "match",
+			src:  "match x {1,2=>println(\"a\")\n_ => {println(\"b\")}}\n",
+			want: "match x {\n    1, 2 => println(\"a\")\n    _ => {\n        println(\"b\")\n    }\n}\n",
+		},
+		{
+			name: "try",
+			src:  "try {throw \"e\"} catch (e) {println(e)} finally {println(\"f\")}\n",
+			want: "try {\n    throw \"e\"\n} catch (e) {\n    println(e)\n} finally {\n    println(\"f\")\n}\n",
+		},
+		{
+			name: "operators",
+			src:  "y:=x>2?\"a\":\"b\"\nprintln(-2**2,~x<<1,a?[1:2],a[:1],a?.b??c)\n",
+			want: "y := x > 2 ? \"a\" : \"b\"\nprintln(-(2 ** 2), ~x << 1, a?[1:2], a[:1], a?.b ?? c)\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Source([]byte(test.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.want {
+				t.Errorf("got\n%s\nwant\n%s", got, test.want)
+			}
+
+			again, err := Source(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(again) != string(got) {
+				t.Errorf("formatting is not idempotent, formatting again gives\n%s", again)
+			}
+		})
+	}
+}
+
+func TestSourceError(t *testing.T) {
+	if _, err := Source([]byte("x := (1 +\n")); err == nil {
+		t.Error("expected an error for invalid source")
+	}
+}
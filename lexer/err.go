@@ -3,11 +3,13 @@ package lexer
 import (
 	"fmt"
 	"script"
+	"unicode/utf8"
 )
 
 func NewTokError(tok Token, message string) *script.PosError {
 	e := &script.PosError{
 		Pos:     tok.Pos,
+		End:     tok.Pos + utf8.RuneCountInString(tok.Lexeme),
 		Message: fmt.Sprintf("(%s: %s): %s", tok.Id.String(), tok.Lexeme, message),
 	}
 	if script.PanicOnError {
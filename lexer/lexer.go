@@ -3,6 +3,7 @@ package lexer
 import (
 	"fmt"
 	"script"
+	"strings"
 	"unicode"
 )
 
@@ -29,11 +30,12 @@ func makeToken(pos int, id TokenId, lexeme string) Token {
 }
 
 type tokenizer struct {
-	input  []rune
-	pos    int
-	buffer Buffer
-	tokens []Token
-	errors []error
+	input    []rune
+	pos      int
+	buffer   Buffer
+	tokens   []Token
+	comments []Comment
+	errors   []error
 }
 
 // done Returns true if the tokenizer has reached EOF.
@@ -62,19 +64,21 @@ func (t *tokenizer) lex(start, end int) []rune {
 	return t.input[start:end]
 }
 
-// push Pushes a new token to the tokens list at the current position. Will automatically push the buffer first using pushBuffer.
+// push Pushes a new token to the tokens list. The lexeme must just have been read using lex, so it ends at the
+// current position. Will automatically push the buffer first using pushBuffer.
 func (t *tokenizer) push(id TokenId, lexeme []rune) {
-	t.pushBuffer()
-	t.tokens = append(t.tokens, makeToken(t.pos, id, string(lexeme)))
+	start := t.pos - len(lexeme)
+	t.pushBuffer(start)
+	t.tokens = append(t.tokens, makeToken(start, id, string(lexeme)))
 }
 
-// pushBuffer Pushes the current buffer to the tokens list at the current position minus buffer length.
-func (t *tokenizer) pushBuffer() {
+// pushBuffer Pushes the current buffer to the tokens list. The buffer ends at the given position.
+func (t *tokenizer) pushBuffer(end int) {
 	l := t.buffer.Len()
 	if l == 0 {
 		return
 	}
-	start := t.pos - l
+	start := end - l
 	lexeme := string(t.buffer)
 
 	id := IDENTIFIER
@@ -91,35 +95,105 @@ func (t *tokenizer) pushBuffer() {
 	})
 }
 
-// number Pushes a number token to the tokens list.
+// number Pushes a number token to the tokens list, see ParseNumber. Letters and digits directly following a literal
+// belong to it, so that 0b12 or 1x are reported as invalid literals.
 func (t *tokenizer) number() {
-	dots := 0
-	end := 1
-	for unicode.IsDigit(t.get(end)) || (t.get(end) == '.' && dots == 0) {
-		if t.get(end) == '.' && dots == 0 {
-			dots++
+	isDigit := func(r rune) bool {
+		return unicode.IsDigit(r) || r == '_'
+	}
+
+	end := 0
+	if base, _ := basePrefix(string([]rune{t.get(0), t.get(1)})); base != 10 {
+		end = 2
+	} else {
+		for isDigit(t.get(end)) {
+			end++
+		}
+		// A dot followed by another one is a range, as in 0..n.
+		if t.get(end) == '.' && t.get(end+1) != '.' {
+			end++
+			for isDigit(t.get(end)) {
+				end++
+			}
+		}
+		if r := t.get(end); r == 'e' || r == 'E' {
+			end++
+			if r := t.get(end); r == '+' || r == '-' {
+				end++
+			}
 		}
+	}
+	for r := t.get(end); unicode.IsLetter(r) || isDigit(r); r = t.get(end) {
+		end++
+	}
 
+	start := t.pos
+	lexeme := t.lex(0, end)
+	if _, err := ParseNumber(string(lexeme)); err != nil {
+		t.errors = append(t.errors, &script.PosError{Pos: start, End: t.pos, Message: err.Error()})
+	}
+	t.push(NUMBER, lexeme)
+}
+
+// comment Records a line comment. The line feed ending it is kept.
+func (t *tokenizer) comment() {
+	t.pushBuffer(t.pos)
+	end := 0
+	for t.pos+end < len(t.input) && t.get(end) != '\n' {
 		end++
 	}
+	trailing := len(t.tokens) > 0 && t.tokens[len(t.tokens)-1].Id != LF
+	t.comments = append(t.comments, Comment{
+		Pos:      t.pos,
+		Text:     strings.TrimRight(string(t.lex(0, end)), " \t\r"),
+		Trailing: trailing,
+	})
+}
 
-	t.push(NUMBER, t.lex(0, end))
+// string Pushes a string token to the tokens list. The lexeme keeps its quotes and escape sequences.
+func (t *tokenizer) string() {
+	end := 1
+	for {
+		switch t.get(end) {
+		case '"':
+			t.push(STRING, t.lex(0, end+1))
+			return
+		case '\\':
+			end += 2
+			continue
+		case 0, '\n':
+			t.errors = append(t.errors, &script.PosError{
+				Pos:     t.pos,
+				Message: "unterminated string literal",
+			})
+			t.pos += end
+			return
+		}
+		end++
+	}
 }
 
 func Tokenize(input []byte) ([]Token, []error) {
+	tokens, _, errs := Scan(input)
+	return tokens, errs
+}
+
+// Scan Works like Tokenize, but also returns the comments of the input.
+func Scan(input []byte) ([]Token, []Comment, []error) {
 	tr := &tokenizer{
-		input:  []rune(string(input)),
-		pos:    0,
-		buffer: make(Buffer, 0, 64),
-		tokens: make([]Token, 0, 1024),
-		errors: make([]error, 0),
+		input:    []rune(string(input)),
+		pos:      0,
+		buffer:   make(Buffer, 0, 64),
+		tokens:   make([]Token, 0, 1024),
+		comments: make([]Comment, 0),
+		errors:   make([]error, 0),
 	}
 
 	for !tr.done() {
 		r := tr.get(0)
 		switch r {
 		case ' ', '\t', '\r':
-			tr.pushBuffer()
+			tr.pushBuffer(tr.pos)
 			tr.pos++
 		case '\n':
 			tr.push(LF, tr.lex(0, 1))
@@ -128,22 +202,56 @@ func Tokenize(input []byte) ([]Token, []error) {
 				tr.push(PLUS_PLUS, tr.lex(0, 2))
 				continue
 			}
+			if tr.get(1) == '=' {
+				tr.push(PLUS_EQUALS, tr.lex(0, 2))
+				continue
+			}
 			tr.push(PLUS, tr.lex(0, 1))
 		case '-':
 			if tr.get(1) == '-' {
 				tr.push(MINUS_MINUS, tr.lex(0, 2))
 				continue
 			}
+			if tr.get(1) == '=' {
+				tr.push(MINUS_EQUALS, tr.lex(0, 2))
+				continue
+			}
 			tr.push(MINUS, tr.lex(0, 1))
 		case '*':
+			if tr.get(1) == '*' {
+				tr.push(ASTERISK_ASTERISK, tr.lex(0, 2))
+				continue
+			}
+			if tr.get(1) == '=' {
+				tr.push(ASTERISK_EQUALS, tr.lex(0, 2))
+				continue
+			}
 			tr.push(ASTERISK, tr.lex(0, 1))
 		case '/':
+			if tr.get(1) == '/' {
+				tr.comment()
+				continue
+			}
+			if tr.get(1) == '=' {
+				tr.push(SLASH_EQUALS, tr.lex(0, 2))
+				continue
+			}
 			tr.push(SLASH, tr.lex(0, 1))
+		case '%':
+			if tr.get(1) == '=' {
+				tr.push(PERCENT_EQUALS, tr.lex(0, 2))
+				continue
+			}
+			tr.push(PERCENT, tr.lex(0, 1))
 		case '=':
 			if tr.get(1) == '=' {
 				tr.push(EQUALS_EQUALS, tr.lex(0, 2))
 				continue
 			}
+			if tr.get(1) == '>' {
+				tr.push(ARROW, tr.lex(0, 2))
+				continue
+			}
 			tr.push(EQUALS, tr.lex(0, 1))
 		case ':':
 			if tr.get(1) == '=' {
@@ -164,12 +272,20 @@ func Tokenize(input []byte) ([]Token, []error) {
 		case ']':
 			tr.push(CLOSE_BRACKET, tr.lex(0, 1))
 		case '<':
+			if tr.get(1) == '<' {
+				tr.push(LESS_LESS, tr.lex(0, 2))
+				continue
+			}
 			if tr.get(1) == '=' {
 				tr.push(LESS_THAN_EQUALS, tr.lex(0, 2))
 				continue
 			}
 			tr.push(LESS_THAN, tr.lex(0, 1))
 		case '>':
+			if tr.get(1) == '>' {
+				tr.push(GREATER_GREATER, tr.lex(0, 2))
+				continue
+			}
 			if tr.get(1) == '=' {
 				tr.push(GREATER_THAN_EQUALS, tr.lex(0, 2))
 				continue
@@ -183,6 +299,8 @@ func Tokenize(input []byte) ([]Token, []error) {
 			tr.push(EXCLAMATION, tr.lex(0, 1))
 		case '^':
 			tr.push(CIRCUMFLEX, tr.lex(0, 1))
+		case '~':
+			tr.push(TILDE, tr.lex(0, 1))
 		case '&':
 			if tr.get(1) == '&' {
 				tr.push(AND_AND, tr.lex(0, 2))
@@ -197,6 +315,19 @@ func Tokenize(input []byte) ([]Token, []error) {
 			tr.push(PIPE, tr.lex(0, 1))
 		case ',':
 			tr.push(COMMA, tr.lex(0, 1))
+		case '?':
+			if tr.get(1) == '?' {
+				tr.push(QUESTION_QUESTION, tr.lex(0, 2))
+				continue
+			}
+			// c?.5:1 is a conditional expression with a number.
+			if tr.get(1) == '.' && !unicode.IsDigit(tr.get(2)) {
+				tr.push(QUESTION_DOT, tr.lex(0, 2))
+				continue
+			}
+			tr.push(QUESTION, tr.lex(0, 1))
+		case '"':
+			tr.string()
 		case '.':
 			if unicode.IsDigit(tr.get(1)) {
 				tr.number()
@@ -206,9 +337,13 @@ func Tokenize(input []byte) ([]Token, []error) {
 				tr.push(DOT_DOT_DOT, tr.lex(0, 3))
 				continue
 			}
+			if tr.get(1) == '.' {
+				tr.push(DOT_DOT, tr.lex(0, 2))
+				continue
+			}
 			tr.push(DOT, tr.lex(0, 1))
 		default:
-			if unicode.IsLetter(r) || (tr.buffer.Len() > 0 && unicode.IsDigit(r)) {
+			if unicode.IsLetter(r) || r == '_' || (tr.buffer.Len() > 0 && unicode.IsDigit(r)) {
 				tr.buffer.Append(r)
 				tr.pos++
 				continue
@@ -226,8 +361,8 @@ func Tokenize(input []byte) ([]Token, []error) {
 			tr.pos++
 		}
 	}
-	tr.pushBuffer()
+	tr.pushBuffer(tr.pos)
 	tr.push(EOF, tr.lex(-1, -1))
 
-	return tr.tokens, tr.errors
+	return tr.tokens, tr.comments, tr.errors
 }
@@ -0,0 +1,100 @@
+package lexer
+
+import (
+	"errors"
+	"fmt"
+	"script/decimal"
+	"strconv"
+	"strings"
+)
+
+// Number literals are decimal integers like 1_000, floats like 1.5, .5 or 1e-3, or integers with a base prefix:
+// 0x for hexadecimal, 0b for binary and 0o for octal. Decimals like 12.50d are digits with an optional fraction and the
+// suffix d. Underscores may separate digits.
+
+// basePrefix Returns the base of a literal with a base prefix and the digits after it, or 10 and the literal itself.
+func basePrefix(lexeme string) (int, string) {
+	if len(lexeme) >= 2 && lexeme[0] == '0' {
+		switch lexeme[1] {
+		case 'x', 'X':
+			return 16, lexeme[2:]
+		case 'b', 'B':
+			return 2, lexeme[2:]
+		case 'o', 'O':
+			return 8, lexeme[2:]
+		}
+	}
+	return 10, lexeme
+}
+
+// IsFloat Reports whether the number literal lexeme is a float literal, a base 10 literal with a dot or an exponent.
+func IsFloat(lexeme string) bool {
+	base, _ := basePrefix(lexeme)
+	return base == 10 && !IsDecimal(lexeme) && strings.ContainsAny(lexeme, ".eE")
+}
+
+// IsDecimal Reports whether the number literal lexeme is a decimal literal, a base 10 literal with the suffix d.
+func IsDecimal(lexeme string) bool {
+	base, _ := basePrefix(lexeme)
+	return base == 10 && strings.HasSuffix(lexeme, "d")
+}
+
+// ParseNumber Returns the value of the number literal lexeme, an int64, a float64 or a decimal.Decimal as decided by
+// IsFloat and IsDecimal.
+func ParseNumber(lexeme string) (any, error) {
+	base, digits := basePrefix(lexeme)
+	if IsDecimal(lexeme) {
+		digits = strings.TrimSuffix(digits, "d")
+	}
+	if !separated(digits, base) {
+		return nil, fmt.Errorf("invalid number literal %s", lexeme)
+	}
+	digits = strings.ReplaceAll(digits, "_", "")
+
+	if IsDecimal(lexeme) {
+		d, err := decimal.Parse(digits)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number literal %s", lexeme)
+		}
+		return d, nil
+	}
+
+	if IsFloat(lexeme) {
+		f, err := strconv.ParseFloat(digits, 64)
+		switch {
+		case errors.Is(err, strconv.ErrRange):
+			return nil, fmt.Errorf("float literal %s out of range", lexeme)
+		case err != nil:
+			return nil, fmt.Errorf("invalid number literal %s", lexeme)
+		}
+		return f, nil
+	}
+
+	i, err := strconv.ParseInt(digits, base, 64)
+	switch {
+	case errors.Is(err, strconv.ErrRange):
+		return nil, fmt.Errorf("integer literal %s out of range", lexeme)
+	case err != nil:
+		return nil, fmt.Errorf("invalid number literal %s", lexeme)
+	}
+	return i, nil
+}
+
+// separated Reports whether every underscore in digits is between two digits of the base. Like in Go, an underscore
+// may also follow a base prefix, as in 0x_ff.
+func separated(digits string, base int) bool {
+	isDigit := func(i int) bool {
+		if i < 0 || i >= len(digits) {
+			return false
+		}
+		_, err := strconv.ParseUint(digits[i:i+1], base, 8)
+		return err == nil
+	}
+	for i := range digits {
+		prefixed := i == 0 && base != 10
+		if digits[i] == '_' && (!(prefixed || isDigit(i-1)) || !isDigit(i+1)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,79 @@
+package lexer
+
+import (
+	"fmt"
+	"script"
+	"script/decimal"
+	"testing"
+)
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		lexeme string
+		want   any
+		err    string
+	}{
+		{lexeme: "0", want: int64(0)},
+		{lexeme: "1_000_000", want: int64(1000000)},
+		{lexeme: "0x_ff", want: int64(255)},
+		{lexeme: "0XFF", want: int64(255)},
+		{lexeme: "0b1010", want: int64(10)},
+		{lexeme: "0o17", want: int64(15)},
+		{lexeme: "9223372036854775807", want: int64(9223372036854775807)},
+		{lexeme: "1.5", want: 1.5},
+		{lexeme: ".5", want: 0.5},
+		{lexeme: "1e6", want: 1e6},
+		{lexeme: "1E-3", want: 1e-3},
+		{lexeme: "2.5e+2", want: 250.0},
+		{lexeme: "1_0.2_5", want: 10.25},
+		{lexeme: "12.50d", want: decimal.New(1250, 2)},
+		{lexeme: "1_000d", want: decimal.New(1000, 0)},
+		{lexeme: "9223372036854775808", err: "integer literal 9223372036854775808 out of range"},
+		{lexeme: "0x1_0000_0000_0000_0000", err: "integer literal 0x1_0000_0000_0000_0000 out of range"},
+		{lexeme: "1e400", err: "float literal 1e400 out of range"},
+		{lexeme: "0b12", err: "invalid number literal 0b12"},
+		{lexeme: "1x", err: "invalid number literal 1x"},
+		{lexeme: "1__0", err: "invalid number literal 1__0"},
+		{lexeme: "1_", err: "invalid number literal 1_"},
+		{lexeme: "1_.5", err: "invalid number literal 1_.5"},
+		{lexeme: "0x", err: "invalid number literal 0x"},
+		{lexeme: "1e", err: "invalid number literal 1e"},
+		{lexeme: "1.5e3d", err: "invalid number literal 1.5e3d"},
+	}
+	for _, test := range tests {
+		t.Run(test.lexeme, func(t *testing.T) {
+			v, err := ParseNumber(test.lexeme)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("got %v, %v, want error %s", v, err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := fmt.Sprintf("%T %v", v, v), fmt.Sprintf("%T %v", test.want, test.want); got != want {
+				t.Errorf("got %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+// TestNumberDiagnostic Checks that invalid literals are reported with the span of the whole literal.
+func TestNumberDiagnostic(t *testing.T) {
+	tokens, errs := Tokenize([]byte("x := 1 + 99999999999999999999 + 0b102\n"))
+	if len(errs) != 2 {
+		t.Fatalf("got %v, want 2 errors", errs)
+	}
+	for i, want := range []script.PosError{
+		{Pos: 9, End: 29, Message: "integer literal 99999999999999999999 out of range"},
+		{Pos: 32, End: 37, Message: "invalid number literal 0b102"},
+	} {
+		if got := *errs[i].(*script.PosError); got != want {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	}
+	if tokens[len(tokens)-3].Lexeme != "0b102" {
+		t.Errorf("got tokens %v, want the invalid literal as one token", tokens)
+	}
+}
@@ -19,53 +19,73 @@ func _() {
 	_ = x[MINUS-8]
 	_ = x[ASTERISK-9]
 	_ = x[SLASH-10]
-	_ = x[EQUALS-11]
-	_ = x[COLON-12]
-	_ = x[COMMA-13]
-	_ = x[OPEN_PAREN-14]
-	_ = x[CLOSE_PAREN-15]
-	_ = x[OPEN_BRACE-16]
-	_ = x[CLOSE_BRACE-17]
-	_ = x[OPEN_BRACKET-18]
-	_ = x[CLOSE_BRACKET-19]
-	_ = x[COLON_EQUALS-20]
-	_ = x[EXCLAMATION-21]
-	_ = x[EQUALS_EQUALS-22]
-	_ = x[EXCLAMATION_EQUALS-23]
-	_ = x[PLUS_EQUALS-24]
-	_ = x[MINUS_EQUALS-25]
-	_ = x[ASTERISK_EQUALS-26]
-	_ = x[SLASH_EQUALS-27]
-	_ = x[PLUS_PLUS-28]
-	_ = x[MINUS_MINUS-29]
-	_ = x[CIRCUMFLEX-30]
-	_ = x[PIPE-31]
-	_ = x[PIPE_PIPE-32]
-	_ = x[AND-33]
-	_ = x[AND_AND-34]
-	_ = x[DOT-35]
-	_ = x[DOT_DOT_DOT-36]
-	_ = x[LESS_THAN-37]
-	_ = x[GREATER_THAN-38]
-	_ = x[LESS_THAN_EQUALS-39]
-	_ = x[GREATER_THAN_EQUALS-40]
-	_ = x[IF-41]
-	_ = x[ELSE-42]
-	_ = x[RETURN-43]
-	_ = x[FOR-44]
-	_ = x[CONTINUE-45]
-	_ = x[BREAK-46]
-	_ = x[FN-47]
-	_ = x[NEW-48]
+	_ = x[PERCENT-11]
+	_ = x[EQUALS-12]
+	_ = x[COLON-13]
+	_ = x[COMMA-14]
+	_ = x[OPEN_PAREN-15]
+	_ = x[CLOSE_PAREN-16]
+	_ = x[OPEN_BRACE-17]
+	_ = x[CLOSE_BRACE-18]
+	_ = x[OPEN_BRACKET-19]
+	_ = x[CLOSE_BRACKET-20]
+	_ = x[COLON_EQUALS-21]
+	_ = x[EXCLAMATION-22]
+	_ = x[EQUALS_EQUALS-23]
+	_ = x[EXCLAMATION_EQUALS-24]
+	_ = x[PLUS_EQUALS-25]
+	_ = x[MINUS_EQUALS-26]
+	_ = x[ASTERISK_EQUALS-27]
+	_ = x[SLASH_EQUALS-28]
+	_ = x[PERCENT_EQUALS-29]
+	_ = x[PLUS_PLUS-30]
+	_ = x[MINUS_MINUS-31]
+	_ = x[CIRCUMFLEX-32]
+	_ = x[PIPE-33]
+	_ = x[PIPE_PIPE-34]
+	_ = x[AND-35]
+	_ = x[AND_AND-36]
+	_ = x[TILDE-37]
+	_ = x[ASTERISK_ASTERISK-38]
+	_ = x[LESS_LESS-39]
+	_ = x[GREATER_GREATER-40]
+	_ = x[DOT-41]
+	_ = x[DOT_DOT_DOT-42]
+	_ = x[DOT_DOT-43]
+	_ = x[ARROW-44]
+	_ = x[QUESTION-45]
+	_ = x[QUESTION_QUESTION-46]
+	_ = x[QUESTION_DOT-47]
+	_ = x[LESS_THAN-48]
+	_ = x[GREATER_THAN-49]
+	_ = x[LESS_THAN_EQUALS-50]
+	_ = x[GREATER_THAN_EQUALS-51]
+	_ = x[IF-52]
+	_ = x[ELSE-53]
+	_ = x[RETURN-54]
+	_ = x[FOR-55]
+	_ = x[CONTINUE-56]
+	_ = x[BREAK-57]
+	_ = x[FN-58]
+	_ = x[NEW-59]
+	_ = x[TRY-60]
+	_ = x[CATCH-61]
+	_ = x[FINALLY-62]
+	_ = x[THROW-63]
+	_ = x[DEFER-64]
+	_ = x[YIELD-65]
+	_ = x[IN-66]
+	_ = x[MATCH-67]
 }
 
-const _TokenId_name = "INVALIDEOFLFIDENTIFIERNUMBERSTRINGCHARPLUSMINUSASTERISKSLASHEQUALSCOLONCOMMAOPEN_PARENCLOSE_PARENOPEN_BRACECLOSE_BRACEOPEN_BRACKETCLOSE_BRACKETCOLON_EQUALSEXCLAMATIONEQUALS_EQUALSEXCLAMATION_EQUALSPLUS_EQUALSMINUS_EQUALSASTERISK_EQUALSSLASH_EQUALSPLUS_PLUSMINUS_MINUSCIRCUMFLEXPIPEPIPE_PIPEANDAND_ANDDOTDOT_DOT_DOTLESS_THANGREATER_THANLESS_THAN_EQUALSGREATER_THAN_EQUALSIFELSERETURNFORCONTINUEBREAKFNNEW"
+const _TokenId_name = "INVALIDEOFLFIDENTIFIERNUMBERSTRINGCHARPLUSMINUSASTERISKSLASHPERCENTEQUALSCOLONCOMMAOPEN_PARENCLOSE_PARENOPEN_BRACECLOSE_BRACEOPEN_BRACKETCLOSE_BRACKETCOLON_EQUALSEXCLAMATIONEQUALS_EQUALSEXCLAMATION_EQUALSPLUS_EQUALSMINUS_EQUALSASTERISK_EQUALSSLASH_EQUALSPERCENT_EQUALSPLUS_PLUSMINUS_MINUSCIRCUMFLEXPIPEPIPE_PIPEANDAND_ANDTILDEASTERISK_ASTERISKLESS_LESSGREATER_GREATERDOTDOT_DOT_DOTDOT_DOTARROWQUESTIONQUESTION_QUESTIONQUESTION_DOTLESS_THANGREATER_THANLESS_THAN_EQUALSGREATER_THAN_EQUALSIFELSERETURNFORCONTINUEBREAKFNNEWTRYCATCHFINALLYTHROWDEFERYIELDINMATCH"
 
-var _TokenId_index = [...]uint16{0, 7, 10, 12, 22, 28, 34, 38, 42, 47, 55, 60, 66, 71, 76, 86, 97, 107, 118, 130, 143, 155, 166, 179, 197, 208, 220, 235, 247, 256, 267, 277, 281, 290, 293, 300, 303, 314, 323, 335, 351, 370, 372, 376, 382, 385, 393, 398, 400, 403}
+var _TokenId_index = [...]uint16{0, 7, 10, 12, 22, 28, 34, 38, 42, 47, 55, 60, 67, 73, 78, 83, 93, 104, 114, 125, 137, 150, 162, 173, 186, 204, 215, 227, 242, 254, 268, 277, 288, 298, 302, 311, 314, 321, 326, 343, 352, 367, 370, 381, 388, 393, 401, 418, 430, 439, 451, 467, 486, 488, 492, 498, 501, 509, 514, 516, 519, 522, 527, 534, 539, 544, 549, 551, 556}
 
 func (i TokenId) String() string {
-	if i < 0 || i >= TokenId(len(_TokenId_index)-1) {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_TokenId_index)-1 {
 		return "TokenId(" + strconv.FormatInt(int64(i), 10) + ")"
 	}
-	return _TokenId_name[_TokenId_index[i]:_TokenId_index[i+1]]
+	return _TokenId_name[_TokenId_index[idx]:_TokenId_index[idx+1]]
 }
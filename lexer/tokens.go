@@ -1,6 +1,9 @@
 package lexer
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+)
 
 type Token struct {
 	Pos    int
@@ -12,6 +15,15 @@ func (t Token) String() string {
 	return fmt.Sprintf("(%s: %s)", t.Id.String(), t.Lexeme)
 }
 
+// Comment is a line comment. Comments are not part of the token stream.
+type Comment struct {
+	Pos int
+	// Text is the comment including the leading slashes.
+	Text string
+	// Trailing is true if the comment follows a token on the same line.
+	Trailing bool
+}
+
 //go:generate stringer -type=TokenId
 type TokenId int
 
@@ -29,6 +41,7 @@ const (
 	MINUS
 	ASTERISK
 	SLASH
+	PERCENT
 	EQUALS
 	COLON
 	COMMA
@@ -51,6 +64,7 @@ const (
 	MINUS_EQUALS
 	ASTERISK_EQUALS
 	SLASH_EQUALS
+	PERCENT_EQUALS
 
 	PLUS_PLUS
 	MINUS_MINUS
@@ -60,9 +74,19 @@ const (
 	PIPE_PIPE
 	AND
 	AND_AND
+	TILDE
+	ASTERISK_ASTERISK // **
+	LESS_LESS         // <<
+	GREATER_GREATER   // >>
 
 	DOT
 	DOT_DOT_DOT
+	DOT_DOT
+	ARROW
+
+	QUESTION
+	QUESTION_QUESTION
+	QUESTION_DOT
 
 	LESS_THAN
 	GREATER_THAN
@@ -77,6 +101,14 @@ const (
 	BREAK
 	FN
 	NEW
+	TRY
+	CATCH
+	FINALLY
+	THROW
+	DEFER
+	YIELD
+	IN
+	MATCH
 )
 
 var keywords = map[string]TokenId{
@@ -88,4 +120,22 @@ var keywords = map[string]TokenId{
 	"break":    BREAK,
 	"fn":       FN,
 	"new":      NEW,
+	"try":      TRY,
+	"catch":    CATCH,
+	"finally":  FINALLY,
+	"throw":    THROW,
+	"defer":    DEFER,
+	"yield":    YIELD,
+	"in":       IN,
+	"match":    MATCH,
+}
+
+// Keywords Returns the sorted reserved words.
+func Keywords() []string {
+	words := make([]string, 0, len(keywords))
+	for word := range keywords {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
 }
@@ -0,0 +1,138 @@
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// Notification is a message sent by the server without expecting a response, e.g. textDocument/publishDiagnostics.
+type Notification struct {
+	Method string
+	Params json.RawMessage
+}
+
+// Client is a minimal language client. It is used to talk to a Server running in the same process, e.g. in tests.
+type Client struct {
+	conn   *conn
+	closer func() error
+
+	mu      sync.Mutex
+	nextID  int
+	pending map[string]chan *message
+	err     error
+
+	// Notifications receives the notifications sent by the server. Notifications are dropped if it is full.
+	Notifications chan Notification
+}
+
+// NewClient Creates a client reading responses from r and writing requests to w.
+func NewClient(r io.Reader, w io.Writer) *Client {
+	c := &Client{
+		conn:          newConn(r, w),
+		pending:       make(map[string]chan *message),
+		Notifications: make(chan Notification, 64),
+	}
+	go c.receive()
+	return c
+}
+
+// Pipe Runs s in a goroutine and returns a client connected to it. Closing the client stops the server.
+func Pipe(s *Server) *Client {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	go func() {
+		err := s.Serve(serverIn, serverOut)
+		serverIn.CloseWithError(err)
+		serverOut.CloseWithError(err)
+	}()
+	c := NewClient(clientIn, clientOut)
+	c.closer = func() error {
+		clientOut.Close()
+		return clientIn.Close()
+	}
+	return c
+}
+
+func (c *Client) receive() {
+	for {
+		m, err := c.conn.read()
+		if err != nil {
+			c.mu.Lock()
+			c.err = err
+			for id, ch := range c.pending {
+				close(ch)
+				delete(c.pending, id)
+			}
+			c.mu.Unlock()
+			close(c.Notifications)
+			return
+		}
+
+		if !m.isResponse() {
+			select {
+			case c.Notifications <- Notification{Method: m.Method, Params: m.Params}:
+			default:
+			}
+			continue
+		}
+
+		c.mu.Lock()
+		ch, ok := c.pending[string(m.ID)]
+		delete(c.pending, string(m.ID))
+		c.mu.Unlock()
+		if ok {
+			ch <- m
+		}
+	}
+}
+
+// Call Sends a request and waits for its response. The result is decoded into result unless it is nil.
+func (c *Client) Call(method string, params, result any) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.nextID++
+	id := json.RawMessage(strconv.Itoa(c.nextID))
+	ch := make(chan *message, 1)
+	c.pending[string(id)] = ch
+	c.mu.Unlock()
+
+	if err = c.conn.write(&message{JSONRPC: "2.0", ID: id, Method: method, Params: data}); err != nil {
+		return err
+	}
+
+	m, ok := <-ch
+	if !ok {
+		return errors.New("connection closed")
+	}
+	if m.Error != nil {
+		return m.Error
+	}
+	if result == nil || m.Result == nil {
+		return nil
+	}
+	return json.Unmarshal(m.Result, result)
+}
+
+// Notify Sends a notification.
+func (c *Client) Notify(method string, params any) error {
+	return c.conn.notify(method, params)
+}
+
+// Close Closes the connection created by Pipe.
+func (c *Client) Close() error {
+	if c.closer == nil {
+		return nil
+	}
+	return c.closer()
+}
@@ -0,0 +1,277 @@
+package lsp
+
+import (
+	"errors"
+	"script"
+	"script/ast"
+	"script/compiler"
+	"script/lexer"
+	"script/resolver"
+	"script/vm"
+	"sort"
+	"unicode"
+)
+
+// document is an open text document and the result of analyzing it.
+type document struct {
+	uri  DocumentURI
+	text string
+	src  []rune
+	// lines holds the rune offset at which every line starts.
+	lines []int
+
+	// program is nil if the document could not be tokenized.
+	program *ast.Program
+	// info is nil if program is nil.
+	info *resolver.Info
+	// decls maps the identifiers declared with := to their initializers.
+	decls       map[*ast.Identifier]ast.Expr
+	diagnostics []Diagnostic
+
+	// globals is the VM declaring the globals the document is resolved against.
+	globals *vm.VM
+}
+
+func newDocument(uri DocumentURI, text string, globals *vm.VM) *document {
+	d := &document{uri: uri, globals: globals}
+	d.update(text)
+	return d
+}
+
+// update Replaces the text of the document and analyzes it.
+func (d *document) update(text string) {
+	d.text = text
+	d.src = []rune(text)
+	d.lines = []int{0}
+	for i, r := range d.src {
+		if r == '\n' {
+			d.lines = append(d.lines, i+1)
+		}
+	}
+	d.analyze()
+}
+
+// analyze Collects the diagnostics reported by the lexer, the parser, the resolver and the compiler. Later stages only
+// run if the earlier ones succeeded, to avoid reporting errors caused by other errors.
+func (d *document) analyze() {
+	d.program, d.info = nil, nil
+	d.decls = make(map[*ast.Identifier]ast.Expr)
+	d.diagnostics = make([]Diagnostic, 0)
+
+	var tokens []lexer.Token
+	if errs := catch(func() (errs []error) {
+		tokens, _, errs = lexer.Scan([]byte(d.text))
+		return
+	}); len(errs) > 0 {
+		d.report(SeverityError, errs...)
+		return
+	}
+
+	var program *ast.Program
+	parseErrs := catch(func() (errs []error) {
+		program, errs = ast.Parse(tokens)
+		return
+	})
+	d.report(SeverityError, parseErrs...)
+	if program == nil {
+		return
+	}
+	d.program = program
+
+	ast.Inspect(program, func(n ast.Node) bool {
+		switch decl := n.(type) {
+		case *ast.DeclareStmt:
+			d.decls[decl.Ident] = decl.Expr
+		case *ast.FuncDeclStmt:
+			d.decls[decl.Name] = decl.Func
+		}
+		return true
+	})
+
+	var diagnostics []resolver.Diagnostic
+	d.info, diagnostics = resolver.Resolve(program, d.globals.Globals())
+	if len(parseErrs) > 0 {
+		// The program is incomplete, so names may be missing.
+		return
+	}
+	for _, diag := range diagnostics {
+		severity := SeverityError
+		if diag.Severity == resolver.Warning {
+			severity = SeverityWarning
+		}
+		d.report(severity, diag.Err())
+	}
+	if len(resolver.Errors(diagnostics)) > 0 {
+		return
+	}
+
+	d.report(SeverityError, catch(func() []error {
+		bc := make(vm.Bytecode, 0)
+		if _, err := compiler.Compile(&bc, program, d.globals.Globals()...); err != nil {
+			return []error{err}
+		}
+		return nil
+	})...)
+}
+
+// catch Calls f and adds a position error f panics with to the errors it returns, see script.PanicOnError.
+func catch(f func() []error) (errs []error) {
+	defer func() {
+		switch r := recover().(type) {
+		case nil:
+		case *script.PosError:
+			errs = append(errs, r)
+		default:
+			panic(r)
+		}
+	}()
+	return f()
+}
+
+func (d *document) report(severity DiagnosticSeverity, errs ...error) {
+	for _, err := range errs {
+		r := d.word(0)
+		var posErr *script.PosError
+		if errors.As(err, &posErr) {
+			r = d.word(posErr.Pos)
+			if posErr.End > posErr.Pos {
+				r = d.span(posErr.Pos, posErr.End)
+			}
+			err = errors.New(posErr.Message)
+		}
+		d.diagnostics = append(d.diagnostics, Diagnostic{
+			Range:    r,
+			Severity: severity,
+			Source:   "ys",
+			Message:  err.Error(),
+		})
+	}
+}
+
+// position Converts a rune offset into a protocol position.
+func (d *document) position(offset int) Position {
+	offset = max(0, min(offset, len(d.src)))
+	line := sort.Search(len(d.lines), func(i int) bool {
+		return d.lines[i] > offset
+	}) - 1
+
+	character := 0
+	for _, r := range d.src[d.lines[line]:offset] {
+		character += utf16Len(r)
+	}
+	return Position{Line: line, Character: character}
+}
+
+// offset Converts a protocol position into a rune offset.
+func (d *document) offset(p Position) int {
+	if p.Line < 0 {
+		return 0
+	}
+	if p.Line >= len(d.lines) {
+		return len(d.src)
+	}
+
+	offset := d.lines[p.Line]
+	for character := 0; offset < len(d.src) && d.src[offset] != '\n' && character < p.Character; offset++ {
+		character += utf16Len(d.src[offset])
+	}
+	return offset
+}
+
+func (d *document) span(start, end int) Range {
+	return Range{Start: d.position(start), End: d.position(end)}
+}
+
+// word Returns the range of the identifier or number starting at the rune offset pos, or of the single character at
+// pos. A negative pos stands for the start of the document.
+func (d *document) word(pos int) Range {
+	pos = max(pos, 0)
+	end := pos
+	for end < len(d.src) && (unicode.IsLetter(d.src[end]) || unicode.IsDigit(d.src[end])) {
+		end++
+	}
+	if end == pos && end < len(d.src) {
+		end++
+	}
+	return d.span(pos, end)
+}
+
+// identRange Returns the range of ident.
+func (d *document) identRange(ident *ast.Identifier) Range {
+	pos := ident.Tok().Pos
+	return d.span(pos, pos+len([]rune(ident.Symbol)))
+}
+
+// identAt Returns the resolved identifier at p and its symbol, or nil.
+func (d *document) identAt(p Position) (*ast.Identifier, *resolver.Symbol) {
+	if d.info == nil {
+		return nil, nil
+	}
+	ident := d.info.IdentAt(d.offset(p))
+	if ident == nil {
+		return nil, nil
+	}
+	return ident, d.info.SymbolOf(ident)
+}
+
+// extent Returns the rune offsets of the first and the last character of the code a scope of node spans.
+func (d *document) extent(node ast.Node) (int, int) {
+	switch n := node.(type) {
+	case *ast.FunctionExpr:
+		return n.Tok().Pos, n.Body.Closing().Pos
+	case *ast.ForStmt:
+		if body, ok := n.Stmt.(*ast.BlockStmt); ok {
+			return n.Tok().Pos, body.Closing().Pos
+		}
+	case *ast.ForInStmt:
+		return n.Tok().Pos, n.Stmt.Closing().Pos
+	case *ast.MatchArm:
+		end := n.Tok().Pos
+		ast.Inspect(n, func(node ast.Node) bool {
+			if node != nil {
+				end = max(end, node.Tok().Pos)
+			}
+			if b, ok := node.(*ast.BlockStmt); ok {
+				end = max(end, b.Closing().Pos)
+			}
+			return true
+		})
+		return n.Tok().Pos, end
+	case *ast.BlockStmt:
+		if start := n.Tok().Pos; start >= 0 {
+			return start, n.Closing().Pos
+		}
+		return n.Closing().Pos, n.Closing().Pos
+	}
+	return 0, len(d.src)
+}
+
+// scopeAt Returns the innermost scope containing the rune offset pos.
+func (d *document) scopeAt(pos int) *resolver.Scope {
+	if d.info == nil {
+		return nil
+	}
+	scope := d.info.Scopes[d.program]
+	for {
+		inner := scope
+		for _, child := range scope.Children {
+			start, end := d.extent(child.Node)
+			if pos >= start && pos <= end {
+				inner = child
+				break
+			}
+		}
+		if inner == scope {
+			return scope
+		}
+		scope = inner
+	}
+}
+
+// utf16Len Returns the number of UTF-16 code units encoding r, in which protocol positions are measured.
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
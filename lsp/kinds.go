@@ -0,0 +1,110 @@
+package lsp
+
+import (
+	"script/ast"
+	"script/lexer"
+	"script/resolver"
+	"script/vm"
+	"strings"
+)
+
+// kindAny is the kind of values that are only known at runtime.
+const kindAny = "any"
+
+// maxDepth limits following declarations while inferring kinds, e.g. for a := b, b := a.
+const maxDepth = 16
+
+// symbolKind Describes the value sym holds, e.g. "int" or "fn (a, b)". Variables take the kind of their initializer.
+func (d *document) symbolKind(sym *resolver.Symbol, depth int) string {
+	switch sym.Kind {
+	case resolver.Global:
+		v, _ := d.globals.Global(sym.Name)
+		return valueKind(v)
+	case resolver.Variable, resolver.Function:
+		if depth < maxDepth {
+			return d.exprKind(d.decls[sym.Decl], depth+1)
+		}
+	}
+	return kindAny
+}
+
+// exprKind Describes the value expr evaluates to.
+func (d *document) exprKind(expr ast.Expr, depth int) string {
+	switch e := expr.(type) {
+	case *ast.Number:
+		switch {
+		case lexer.IsFloat(e.Value):
+			return "float"
+		case lexer.IsDecimal(e.Value):
+			return "decimal"
+		}
+		return "int"
+	case *ast.String:
+		return "string"
+	case *ast.ArrayExpr, *ast.NewExpr, *ast.SliceExpr:
+		return "array"
+	case *ast.FunctionExpr:
+		return signature(e)
+	case *ast.Identifier:
+		if sym := d.info.SymbolOf(e); sym != nil {
+			return d.symbolKind(sym, depth)
+		}
+	case *ast.UnaryExpr:
+		if e.Operator == lexer.EXCLAMATION {
+			return "bool"
+		}
+		return d.exprKind(e.Expr, depth)
+	case *ast.BinaryExpr:
+		switch e.Operator {
+		case lexer.EQUALS_EQUALS, lexer.EXCLAMATION_EQUALS, lexer.LESS_THAN, lexer.GREATER_THAN,
+			lexer.LESS_THAN_EQUALS, lexer.GREATER_THAN_EQUALS, lexer.AND_AND, lexer.PIPE_PIPE:
+			return "bool"
+		}
+		left, right := d.exprKind(e.Left, depth), d.exprKind(e.Right, depth)
+		switch {
+		case left == right:
+			return left
+		case left == "float" && right == "int", left == "int" && right == "float":
+			return "float"
+		case left == "decimal" && right == "int", left == "int" && right == "decimal":
+			return "decimal"
+		}
+	case *ast.ConditionalExpr:
+		if then := d.exprKind(e.Then, depth); then == d.exprKind(e.Else, depth) {
+			return then
+		}
+	case *ast.CallExpr:
+		// Calling a type casts the argument.
+		if ident, ok := e.Caller.(*ast.Identifier); ok {
+			if kind := d.exprKind(ident, depth); strings.HasPrefix(kind, "type ") {
+				return strings.TrimPrefix(kind, "type ")
+			}
+		}
+	}
+	return kindAny
+}
+
+// valueKind Describes a value declared by the host.
+func valueKind(v any) string {
+	switch v := v.(type) {
+	case vm.Type:
+		return "type " + strings.ToLower(v.Id.String())
+	case vm.ExternalFunc:
+		return "native fn"
+	case vm.Func:
+		return "fn"
+	}
+	return strings.ToLower(vm.TypeOf(v).String())
+}
+
+// signature Returns the header of a function expression, e.g. fn (a, b...).
+func signature(f *ast.FunctionExpr) string {
+	params := make([]string, len(f.Params))
+	for i, param := range f.Params {
+		params[i] = param.Symbol
+	}
+	if f.IsVariadic && len(params) > 0 {
+		params[len(params)-1] += "..."
+	}
+	return "fn (" + strings.Join(params, ", ") + ")"
+}
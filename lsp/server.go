@@ -0,0 +1,418 @@
+// Package lsp implements a language server for script files. It speaks JSON-RPC 2.0 framed by Content-Length
+// headers, usually over stdio, and provides diagnostics, hover, go to definition, references, completion, document
+// symbols and formatting.
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"script/ast"
+	"script/format"
+	"script/lexer"
+	"script/resolver"
+	"script/vm"
+	"sort"
+	"strings"
+)
+
+// CodeRequestFailed is returned if a valid request could not be completed, e.g. formatting a document with errors.
+const CodeRequestFailed = -32803
+
+// Server is a language server. Messages are handled one at a time, in the order they are received.
+type Server struct {
+	globals  *vm.VM
+	docs     map[DocumentURI]*document
+	conn     *conn
+	shutdown bool
+}
+
+type handler func(s *Server, params json.RawMessage) (any, error)
+
+var handlers map[string]handler
+
+func init() {
+	handlers = map[string]handler{
+		"initialize":  (*Server).initialize,
+		"initialized": ignore,
+		"shutdown":    (*Server).shutdownRequest,
+
+		"textDocument/didOpen":   (*Server).didOpen,
+		"textDocument/didChange": (*Server).didChange,
+		"textDocument/didClose":  (*Server).didClose,
+		"textDocument/didSave":   ignore,
+
+		"textDocument/hover":          (*Server).hover,
+		"textDocument/definition":     (*Server).definition,
+		"textDocument/references":     (*Server).references,
+		"textDocument/completion":     (*Server).completion,
+		"textDocument/documentSymbol": (*Server).documentSymbol,
+		"textDocument/formatting":     (*Server).formatting,
+	}
+}
+
+// NewServer Creates a server resolving documents against the globals of v, which may declare host functions in
+// addition to the builtins. If v is nil, a new VM is used.
+func NewServer(v *vm.VM) *Server {
+	if v == nil {
+		v = vm.New()
+	}
+	return &Server{
+		globals: v,
+		docs:    make(map[DocumentURI]*document),
+	}
+}
+
+// Serve Reads requests from r and writes responses to w until the client sends exit or closes r.
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	s.conn = newConn(r, w)
+	for {
+		m, err := s.conn.read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		var rpcErr *ResponseError
+		if errors.As(err, &rpcErr) {
+			s.conn.write(&response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: rpcErr})
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if m.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit without shutdown")
+			}
+			return nil
+		}
+		if m.isResponse() {
+			// The server sends no requests.
+			continue
+		}
+
+		result, err := s.handle(m)
+		if !m.isRequest() {
+			continue
+		}
+
+		res := &response{JSONRPC: "2.0", ID: m.ID, Result: result}
+		if err != nil {
+			if !errors.As(err, &rpcErr) {
+				rpcErr = &ResponseError{Code: CodeInternalError, Message: err.Error()}
+			}
+			res.Result, res.Error = nil, rpcErr
+		}
+		if err = s.conn.write(res); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(m *message) (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &ResponseError{Code: CodeInternalError, Message: fmt.Sprint(r)}
+		}
+	}()
+
+	h, ok := handlers[m.Method]
+	if !ok {
+		return nil, &ResponseError{Code: CodeMethodNotFound, Message: "method not found: " + m.Method}
+	}
+	if s.shutdown {
+		return nil, &ResponseError{Code: CodeInvalidRequest, Message: "server is shut down"}
+	}
+	return h(s, m.Params)
+}
+
+func decode[T any](params json.RawMessage) (*T, error) {
+	v := new(T)
+	if err := json.Unmarshal(params, v); err != nil {
+		return nil, &ResponseError{Code: CodeInvalidParams, Message: err.Error()}
+	}
+	return v, nil
+}
+
+func ignore(*Server, json.RawMessage) (any, error) {
+	return nil, nil
+}
+
+func (s *Server) document(uri DocumentURI) (*document, error) {
+	d, ok := s.docs[uri]
+	if !ok {
+		return nil, &ResponseError{Code: CodeInvalidParams, Message: "unknown document " + string(uri)}
+	}
+	return d, nil
+}
+
+func (s *Server) publish(d *document) error {
+	return s.conn.notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{
+		URI:         d.uri,
+		Diagnostics: d.diagnostics,
+	})
+}
+
+func (s *Server) initialize(json.RawMessage) (any, error) {
+	return &InitializeResult{
+		Capabilities: ServerCapabilities{
+			TextDocumentSync:           TextDocumentSyncFull,
+			HoverProvider:              true,
+			DefinitionProvider:         true,
+			ReferencesProvider:         true,
+			CompletionProvider:         &CompletionOptions{},
+			DocumentSymbolProvider:     true,
+			DocumentFormattingProvider: true,
+		},
+		ServerInfo: ServerInfo{Name: "ys"},
+	}, nil
+}
+
+func (s *Server) shutdownRequest(json.RawMessage) (any, error) {
+	defer func() { s.shutdown = true }()
+	return nil, nil
+}
+
+func (s *Server) didOpen(raw json.RawMessage) (any, error) {
+	params, err := decode[DidOpenTextDocumentParams](raw)
+	if err != nil {
+		return nil, err
+	}
+	d := newDocument(params.TextDocument.URI, params.TextDocument.Text, s.globals)
+	s.docs[d.uri] = d
+	return nil, s.publish(d)
+}
+
+func (s *Server) didChange(raw json.RawMessage) (any, error) {
+	params, err := decode[DidChangeTextDocumentParams](raw)
+	if err != nil {
+		return nil, err
+	}
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil || len(params.ContentChanges) == 0 {
+		return nil, err
+	}
+	d.update(params.ContentChanges[len(params.ContentChanges)-1].Text)
+	return nil, s.publish(d)
+}
+
+func (s *Server) didClose(raw json.RawMessage) (any, error) {
+	params, err := decode[DidCloseTextDocumentParams](raw)
+	if err != nil {
+		return nil, err
+	}
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	delete(s.docs, d.uri)
+	d.diagnostics = make([]Diagnostic, 0)
+	return nil, s.publish(d)
+}
+
+func (s *Server) hover(raw json.RawMessage) (any, error) {
+	params, err := decode[TextDocumentPositionParams](raw)
+	if err != nil {
+		return nil, err
+	}
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	ident, sym := d.identAt(params.Position)
+	if sym == nil {
+		return nil, nil
+	}
+	r := d.identRange(ident)
+	return &Hover{
+		Contents: MarkupContent{
+			Kind:  "markdown",
+			Value: fmt.Sprintf("```\n%s %s: %s\n```", describe(sym.Kind), sym.Name, d.symbolKind(sym, 0)),
+		},
+		Range: &r,
+	}, nil
+}
+
+// describe Returns the word used for symbols of kind in hovers, e.g. "parameter".
+func describe(kind resolver.SymbolKind) string {
+	switch kind {
+	case resolver.Global:
+		return "global"
+	case resolver.Parameter:
+		return "parameter"
+	case resolver.Function:
+		return "function"
+	}
+	return "variable"
+}
+
+func (s *Server) definition(raw json.RawMessage) (any, error) {
+	params, err := decode[TextDocumentPositionParams](raw)
+	if err != nil {
+		return nil, err
+	}
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	_, sym := d.identAt(params.Position)
+	if sym == nil || sym.Decl == nil {
+		return nil, nil
+	}
+	return &Location{URI: d.uri, Range: d.identRange(sym.Decl)}, nil
+}
+
+func (s *Server) references(raw json.RawMessage) (any, error) {
+	params, err := decode[ReferenceParams](raw)
+	if err != nil {
+		return nil, err
+	}
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	_, sym := d.identAt(params.Position)
+	if sym == nil {
+		return nil, nil
+	}
+
+	idents := make([]*ast.Identifier, 0, len(sym.Uses)+1)
+	if params.Context.IncludeDeclaration && sym.Decl != nil {
+		idents = append(idents, sym.Decl)
+	}
+	idents = append(idents, sym.Uses...)
+	sort.Slice(idents, func(i, j int) bool {
+		return idents[i].Tok().Pos < idents[j].Tok().Pos
+	})
+
+	locations := make([]Location, len(idents))
+	for i, ident := range idents {
+		locations[i] = Location{URI: d.uri, Range: d.identRange(ident)}
+	}
+	return locations, nil
+}
+
+func (s *Server) completion(raw json.RawMessage) (any, error) {
+	params, err := decode[TextDocumentPositionParams](raw)
+	if err != nil {
+		return nil, err
+	}
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]CompletionItem, 0)
+	seen := make(map[string]bool)
+	add := func(item CompletionItem) {
+		if !seen[item.Label] {
+			seen[item.Label] = true
+			items = append(items, item)
+		}
+	}
+
+	// Inner declarations shadow outer ones, so they are added first.
+	for scope := d.scopeAt(d.offset(params.Position)); scope != nil && scope.Parent != nil; scope = scope.Parent {
+		for _, name := range scope.Names() {
+			kind := d.symbolKind(scope.Symbols[name], 0)
+			add(CompletionItem{Label: name, Kind: completionKind(kind), Detail: kind})
+		}
+	}
+	for _, name := range s.globals.Globals() {
+		v, _ := s.globals.Global(name)
+		kind := valueKind(v)
+		add(CompletionItem{Label: name, Kind: completionKind(kind), Detail: kind})
+	}
+	for _, keyword := range lexer.Keywords() {
+		add(CompletionItem{Label: keyword, Kind: CompletionKeyword})
+	}
+	return &CompletionList{Items: items}, nil
+}
+
+func completionKind(kind string) CompletionItemKind {
+	switch {
+	case kind == "native fn" || strings.HasPrefix(kind, "fn "):
+		return CompletionFunction
+	case strings.HasPrefix(kind, "type "):
+		return CompletionClass
+	case kind == "bool":
+		return CompletionConstant
+	}
+	return CompletionVariable
+}
+
+func (s *Server) documentSymbol(raw json.RawMessage) (any, error) {
+	params, err := decode[DocumentSymbolParams](raw)
+	if err != nil {
+		return nil, err
+	}
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	if d.program == nil {
+		return []DocumentSymbol{}, nil
+	}
+	return d.functions(d.program.Statements), nil
+}
+
+// functions Returns the functions declared with fn name() {} or name := fn () {} in stmts. Functions declared in the
+// body of a function are its children.
+func (d *document) functions(stmts []ast.Stmt) []DocumentSymbol {
+	symbols := make([]DocumentSymbol, 0)
+	for _, stmt := range stmts {
+		ast.Inspect(stmt, func(n ast.Node) bool {
+			var (
+				ident *ast.Identifier
+				f     *ast.FunctionExpr
+				start int
+			)
+			switch decl := n.(type) {
+			case *ast.FuncDeclStmt:
+				ident, f, start = decl.Name, decl.Func, decl.Tok().Pos
+			case *ast.DeclareStmt:
+				e, ok := decl.Expr.(*ast.FunctionExpr)
+				if !ok {
+					return true
+				}
+				ident, f, start = decl.Ident, e, decl.Ident.Tok().Pos
+			default:
+				return true
+			}
+			symbols = append(symbols, DocumentSymbol{
+				Name:           ident.Symbol,
+				Detail:         signature(f),
+				Kind:           SymbolFunction,
+				Range:          d.span(start, f.Body.Closing().Pos+1),
+				SelectionRange: d.identRange(ident),
+				Children:       d.functions(f.Body.Statements),
+			})
+			return false
+		})
+	}
+	return symbols
+}
+
+func (s *Server) formatting(raw json.RawMessage) (any, error) {
+	params, err := decode[DocumentFormattingParams](raw)
+	if err != nil {
+		return nil, err
+	}
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := format.Source([]byte(d.text))
+	if err != nil {
+		return nil, &ResponseError{Code: CodeRequestFailed, Message: err.Error()}
+	}
+	if string(out) == d.text {
+		return []TextEdit{}, nil
+	}
+	return []TextEdit{{Range: d.span(0, len(d.src)), NewText: string(out)}}, nil
+}
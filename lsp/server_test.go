@@ -0,0 +1,142 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+const testURI DocumentURI = "file:///test.ys"
+
+// session Returns a client connected to a new, initialized server.
+func session(t *testing.T) *Client {
+	t.Helper()
+	c := Pipe(NewServer(nil))
+	t.Cleanup(func() { c.Close() })
+
+	var result InitializeResult
+	if err := c.Call("initialize", InitializeParams{ProcessID: 1}, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.ServerInfo.Name != "ys" || result.Capabilities.TextDocumentSync != TextDocumentSyncFull {
+		t.Fatalf("unexpected initialize result %+v", result)
+	}
+	if err := c.Notify("initialized", struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// diagnostics Waits for the next diagnostics the server publishes.
+func diagnostics(t *testing.T, c *Client) PublishDiagnosticsParams {
+	t.Helper()
+	select {
+	case n, ok := <-c.Notifications:
+		if !ok {
+			t.Fatal("connection closed")
+		}
+		if n.Method != "textDocument/publishDiagnostics" {
+			t.Fatalf("got notification %s, want diagnostics", n.Method)
+		}
+		var params PublishDiagnosticsParams
+		if err := json.Unmarshal(n.Params, &params); err != nil {
+			t.Fatal(err)
+		}
+		return params
+	case <-time.After(5 * time.Second):
+		t.Fatal("no diagnostics published")
+	}
+	return PublishDiagnosticsParams{}
+}
+
+func TestDiagnostics(t *testing.T) {
+	c := session(t)
+
+	err := c.Notify("textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: testURI, LanguageID: "ys", Version: 1, Text: "x := 1\nprintln(x, y)\n"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	published := diagnostics(t, c)
+	want := Diagnostic{
+		Range:    Range{Start: Position{Line: 1, Character: 11}, End: Position{Line: 1, Character: 12}},
+		Severity: SeverityError,
+		Source:   "ys",
+		Message:  "undeclared variable y",
+	}
+	if published.URI != testURI || len(published.Diagnostics) != 1 || published.Diagnostics[0] != want {
+		t.Fatalf("got %+v, want a single %+v", published, want)
+	}
+
+	err = c.Notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   TextDocumentIdentifier{URI: testURI},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: "y := 1\nprintln(y)\n"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if published := diagnostics(t, c); len(published.Diagnostics) != 0 {
+		t.Errorf("got %+v after fixing the document, want none", published.Diagnostics)
+	}
+
+	var location Location
+	err = c.Call("textDocument/definition", TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: testURI},
+		Position:     Position{Line: 1, Character: 8},
+	}, &location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantRange := Range{Start: Position{Line: 0, Character: 0}, End: Position{Line: 0, Character: 1}}
+	if location.URI != testURI || location.Range != wantRange {
+		t.Errorf("got definition %+v, want %v", location, wantRange)
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	c := session(t)
+
+	err := c.Notify("textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: testURI, Version: 1, Text: "x:=1+2\n"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	diagnostics(t, c)
+
+	var edits []TextEdit
+	if err := c.Call("textDocument/formatting", DocumentFormattingParams{TextDocument: TextDocumentIdentifier{URI: testURI}}, &edits); err != nil {
+		t.Fatal(err)
+	}
+	if len(edits) != 1 || edits[0].NewText != "x := 1 + 2\n" {
+		t.Errorf("got edits %+v", edits)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	c := session(t)
+
+	err := c.Call("textDocument/hover", TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: testURI}}, nil)
+	if rpcErr, ok := err.(*ResponseError); !ok || rpcErr.Code != CodeInvalidParams {
+		t.Errorf("got %v for an unknown document, want invalid params", err)
+	}
+	err = c.Call("workspace/unknown", nil, nil)
+	if rpcErr, ok := err.(*ResponseError); !ok || rpcErr.Code != CodeMethodNotFound {
+		t.Errorf("got %v for an unknown method, want method not found", err)
+	}
+
+	if err := c.Call("shutdown", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	err = c.Call("textDocument/hover", TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: testURI}}, nil)
+	if rpcErr, ok := err.(*ResponseError); !ok || rpcErr.Code != CodeInvalidRequest {
+		t.Errorf("got %v after shutdown, want invalid request", err)
+	}
+	if err := c.Notify("exit", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-c.Notifications; ok {
+		t.Error("the server did not stop after exit")
+	}
+}
@@ -0,0 +1,65 @@
+package script
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Position is a human-readable source location. Line and Column start at 1.
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	if p.File == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// Source is a source file with an index of its lines.
+type Source struct {
+	File string
+	Text []rune
+	// lines holds the rune offset at which every line starts.
+	lines []int
+}
+
+func NewSource(file string, text []byte) *Source {
+	s := &Source{File: file, Text: []rune(string(text)), lines: []int{0}}
+	for i, r := range s.Text {
+		if r == '\n' {
+			s.lines = append(s.lines, i+1)
+		}
+	}
+	return s
+}
+
+// Position Converts the rune offset pos into a Position. Offsets out of range are clamped.
+func (s *Source) Position(pos int) Position {
+	pos = max(0, min(pos, len(s.Text)))
+	line := sort.Search(len(s.lines), func(i int) bool {
+		return s.lines[i] > pos
+	})
+	return Position{File: s.File, Line: line, Column: pos - s.lines[line-1] + 1}
+}
+
+// Lines Returns the number of lines.
+func (s *Source) Lines() int {
+	return len(s.lines)
+}
+
+// Line Returns the text of the line with the given number, starting at 1, without the line feed.
+func (s *Source) Line(line int) string {
+	if line < 1 || line > len(s.lines) {
+		return ""
+	}
+	end := len(s.Text)
+	if line < len(s.lines) {
+		end = s.lines[line] - 1
+	}
+	return strings.TrimSuffix(string(s.Text[s.lines[line-1]:end]), "\r")
+}
@@ -0,0 +1,431 @@
+// Package resolver performs the semantic analysis of a program. It binds every identifier to its declaration and
+// reports undeclared variables, redeclarations, uses before declaration, misplaced break and continue statements and
+// unused variables.
+package resolver
+
+import (
+	"fmt"
+	"script"
+	"script/ast"
+	"script/lexer"
+	"sort"
+)
+
+//go:generate stringer -type=Severity
+type Severity uint8
+
+const (
+	Error Severity = iota
+	Warning
+)
+
+// Diagnostic is a problem found by Resolve.
+type Diagnostic struct {
+	Pos      int
+	Severity Severity
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s at %d: %s", d.Severity, d.Pos, d.Message)
+}
+
+// Err Converts the diagnostic into a position error.
+func (d Diagnostic) Err() *script.PosError {
+	return &script.PosError{Pos: d.Pos, Message: d.Message}
+}
+
+// Errors Returns the diagnostics with severity Error as position errors.
+func Errors(diagnostics []Diagnostic) []error {
+	errs := make([]error, 0)
+	for _, d := range diagnostics {
+		if d.Severity == Error {
+			errs = append(errs, d.Err())
+		}
+	}
+	return errs
+}
+
+// Warnings Returns the diagnostics with severity Warning as position errors.
+func Warnings(diagnostics []Diagnostic) []error {
+	warnings := make([]error, 0)
+	for _, d := range diagnostics {
+		if d.Severity == Warning {
+			warnings = append(warnings, d.Err())
+		}
+	}
+	return warnings
+}
+
+type resolver struct {
+	info        *Info
+	scope       *Scope
+	loops       int
+	functions   int
+	diagnostics []Diagnostic
+	// deferred holds, for every open scope, the function bodies to resolve when it is closed.
+	deferred [][]func()
+	// labels holds the labels of the enclosing loops of the current function.
+	labels []*ast.Identifier
+}
+
+// Resolve Resolves all identifiers of program. globals are the names declared by the host, see vm.VM.Globals.
+// Diagnostics are sorted by position.
+func Resolve(program *ast.Program, globals []string) (*Info, []Diagnostic) {
+	r := &resolver{
+		info: &Info{
+			Defs:   make(map[*ast.Identifier]*Symbol),
+			Uses:   make(map[*ast.Identifier]*Symbol),
+			Scopes: make(map[ast.Node]*Scope),
+		},
+	}
+
+	r.info.Universe = newScope(nil, nil)
+	for _, name := range globals {
+		r.info.Universe.Symbols[name] = &Symbol{Name: name, Kind: Global, Scope: r.info.Universe}
+	}
+	r.scope = r.info.Universe
+
+	r.open(program, program.Statements)
+	// Functions declared at the top level are hoisted.
+	for _, stmt := range program.Statements {
+		if d, ok := stmt.(*ast.FuncDeclStmt); ok {
+			r.declare(d.Name, Function)
+		}
+	}
+	r.stmts(program.Statements)
+	r.close()
+
+	for _, sym := range r.info.Symbols() {
+		if sym.Kind == Variable && sym.Reads == 0 {
+			r.report(sym.Pos(), Warning, "%s declared and not used", sym.Name)
+		}
+	}
+
+	sort.SliceStable(r.diagnostics, func(i, j int) bool {
+		return r.diagnostics[i].Pos < r.diagnostics[j].Pos
+	})
+	return r.info, r.diagnostics
+}
+
+func (r *resolver) report(pos int, severity Severity, format string, args ...any) {
+	r.diagnostics = append(r.diagnostics, Diagnostic{
+		Pos:      pos,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// open Opens a new scope for node. stmts are the statements of the scope, used to detect uses before declaration.
+func (r *resolver) open(node ast.Node, stmts []ast.Stmt) {
+	r.scope = newScope(r.scope, node)
+	r.info.Scopes[node] = r.scope
+	for _, stmt := range stmts {
+		switch d := stmt.(type) {
+		case *ast.DeclareStmt:
+			r.scope.pending[d.Ident.Symbol] = true
+		case *ast.FuncDeclStmt:
+			r.scope.pending[d.Name.Symbol] = true
+		}
+	}
+	r.deferred = append(r.deferred, nil)
+}
+
+// close Resolves the function bodies deferred in the current scope, which sees all of its declarations by now, and
+// closes it.
+func (r *resolver) close() {
+	for i := 0; i < len(r.deferred[len(r.deferred)-1]); i++ {
+		r.deferred[len(r.deferred)-1][i]()
+	}
+	r.deferred = r.deferred[:len(r.deferred)-1]
+	r.scope = r.scope.Parent
+}
+
+func (r *resolver) declare(ident *ast.Identifier, kind SymbolKind) {
+	if _, ok := r.scope.Symbols[ident.Symbol]; ok {
+		r.report(ident.Tok().Pos, Error, "%s redeclared in this scope", ident.Symbol)
+	}
+	sym := &Symbol{Name: ident.Symbol, Kind: kind, Decl: ident, Scope: r.scope}
+	r.scope.Symbols[ident.Symbol] = sym
+	delete(r.scope.pending, ident.Symbol)
+	r.info.Defs[ident] = sym
+}
+
+func (r *resolver) use(ident *ast.Identifier, read bool) {
+	for s := r.scope; s != nil; s = s.Parent {
+		if sym, ok := s.Symbols[ident.Symbol]; ok {
+			sym.Uses = append(sym.Uses, ident)
+			if read {
+				sym.Reads++
+			}
+			r.info.Uses[ident] = sym
+			return
+		}
+		if s.pending[ident.Symbol] {
+			r.report(ident.Tok().Pos, Error, "%s used before declaration", ident.Symbol)
+			return
+		}
+	}
+
+	if read {
+		r.report(ident.Tok().Pos, Error, "undeclared variable %s", ident.Symbol)
+	} else {
+		r.report(ident.Tok().Pos, Error, "assignment to undeclared variable %s", ident.Symbol)
+	}
+}
+
+func (r *resolver) stmts(stmts []ast.Stmt) {
+	for _, stmt := range stmts {
+		r.stmt(stmt)
+	}
+}
+
+func (r *resolver) stmt(stmt ast.Stmt) {
+	switch s := stmt.(type) {
+	case nil:
+	case *ast.DeclareStmt:
+		r.expr(s.Expr)
+		r.declare(s.Ident, Variable)
+	case *ast.FuncDeclStmt:
+		if _, hoisted := r.info.Defs[s.Name]; !hoisted {
+			r.declare(s.Name, Function)
+		}
+		r.function(s.Func)
+	case *ast.AssignStmt:
+		r.expr(s.Expr)
+		if s.Ident != nil {
+			r.use(s.Ident, false)
+		}
+	case *ast.ExprStmt:
+		r.expr(s.Expr)
+	case *ast.CompoundAssignStmt:
+		r.expr(s.Value)
+		// The target is read before it is assigned.
+		r.expr(s.Target)
+	case *ast.ArrayAssignStmt:
+		r.expr(s.Expr)
+		r.expr(s.Ident)
+		r.expr(s.Index)
+	case *ast.BlockStmt:
+		r.open(s, s.Statements)
+		r.stmts(s.Statements)
+		r.close()
+	case *ast.ConditionalStmt:
+		r.expr(s.Cond)
+		r.stmt(s.Block)
+		r.stmt(s.Else)
+	case *ast.ReturnStmt:
+		if r.functions == 0 {
+			r.report(s.Tok().Pos, Error, "return outside of a function")
+		}
+		for _, e := range s.Returned {
+			r.expr(e)
+		}
+	case *ast.ForStmt:
+		r.open(s, []ast.Stmt{s.Init})
+		r.stmt(s.Init)
+		r.expr(s.Cond)
+		r.stmt(s.Update)
+		r.loop(s.Label, s.Stmt)
+		r.close()
+	case *ast.TryStmt:
+		r.stmt(s.Body)
+		if s.Catch != nil {
+			r.open(s.Catch, s.Catch.Statements)
+			if s.Param != nil {
+				r.declare(s.Param, Parameter)
+			}
+			r.stmts(s.Catch.Statements)
+			r.close()
+		}
+		if s.Finally != nil {
+			r.stmt(s.Finally)
+		}
+	case *ast.ThrowStmt:
+		r.expr(s.Expr)
+	case *ast.DeferStmt:
+		if r.functions == 0 {
+			r.report(s.Tok().Pos, Error, "defer outside of a function")
+		}
+		r.expr(s.Call)
+	case *ast.ForInStmt:
+		r.expr(s.Iterable)
+		r.open(s, nil)
+		if s.Key != nil {
+			r.declare(s.Key, Variable)
+		}
+		r.declare(s.Value, Variable)
+		r.loop(s.Label, s.Stmt)
+		r.close()
+	case *ast.MatchStmt:
+		r.expr(s.Subject)
+		for _, arm := range s.Arms {
+			r.open(arm, nil)
+			for _, pattern := range arm.Patterns {
+				r.pattern(pattern, len(arm.Patterns) > 1)
+			}
+			r.expr(arm.Guard)
+			r.stmt(arm.Body)
+			r.close()
+		}
+	case *ast.BreakStmt:
+		if r.loops == 0 {
+			r.report(s.Tok().Pos, Error, "break is not in a loop")
+		}
+		r.label(s.Label)
+	case *ast.ContinueStmt:
+		if r.loops == 0 {
+			r.report(s.Tok().Pos, Error, "continue is not in a loop")
+		}
+		r.label(s.Label)
+	default:
+		r.report(stmt.Tok().Pos, Error, "unknown statement type %T", stmt)
+	}
+}
+
+// pattern Declares the bindings of a match pattern and reports invalid patterns. Bindings are not allowed if the arm has
+// alternative patterns. true and false are not bindings, they refer to the builtins.
+func (r *resolver) pattern(pattern ast.Expr, alternative bool) {
+	switch p := pattern.(type) {
+	case *ast.Identifier:
+		switch {
+		case p.Symbol == "_":
+		case p.IsBool():
+			r.expr(p)
+		case alternative:
+			r.report(p.Tok().Pos, Error, "cannot bind %s in alternative patterns", p.Symbol)
+		default:
+			r.declare(p, Variable)
+		}
+	case *ast.Number, *ast.String:
+	case *ast.UnaryExpr:
+		if _, ok := p.Expr.(*ast.Number); !ok || p.Operator != lexer.MINUS {
+			r.report(p.Tok().Pos, Error, "invalid pattern")
+		}
+	case *ast.RangeExpr:
+		if !isIntLiteral(p.Start) || !isIntLiteral(p.End) {
+			r.report(p.Tok().Pos, Error, "range patterns expect integer literals")
+		}
+	case *ast.ArrayExpr:
+		for _, element := range p.Elements {
+			r.pattern(element, alternative)
+		}
+	default:
+		r.report(pattern.Tok().Pos, Error, "invalid pattern")
+	}
+}
+
+// isIntLiteral Reports whether e is an integer literal, which may be negated.
+func isIntLiteral(e ast.Expr) bool {
+	if u, ok := e.(*ast.UnaryExpr); ok && u.Operator == lexer.MINUS {
+		e = u.Expr
+	}
+	n, ok := e.(*ast.Number)
+	return ok && !lexer.IsFloat(n.Value) && !lexer.IsDecimal(n.Value)
+}
+
+// loop Resolves the body of a loop with the optional label.
+func (r *resolver) loop(label *ast.Identifier, body ast.Stmt) {
+	if label != nil {
+		for _, l := range r.labels {
+			if l.Symbol == label.Symbol {
+				r.report(label.Tok().Pos, Error, "label %s is already defined", label.Symbol)
+			}
+		}
+		r.labels = append(r.labels, label)
+	}
+	r.loops++
+	r.stmt(body)
+	r.loops--
+	if label != nil {
+		r.labels = r.labels[:len(r.labels)-1]
+	}
+}
+
+// label Reports a break or continue label that does not belong to an enclosing loop.
+func (r *resolver) label(label *ast.Identifier) {
+	if label == nil {
+		return
+	}
+	for _, l := range r.labels {
+		if l.Symbol == label.Symbol {
+			return
+		}
+	}
+	r.report(label.Tok().Pos, Error, "unknown label %s", label.Symbol)
+}
+
+func (r *resolver) expr(expr ast.Expr) {
+	switch e := expr.(type) {
+	case nil:
+	case *ast.Identifier:
+		r.use(e, true)
+	case *ast.Number, *ast.String:
+	case *ast.BinaryExpr:
+		r.expr(e.Left)
+		r.expr(e.Right)
+	case *ast.UnaryExpr:
+		r.expr(e.Expr)
+	case *ast.RangeExpr:
+		r.expr(e.Start)
+		r.expr(e.End)
+	case *ast.YieldExpr:
+		if r.functions == 0 {
+			r.report(e.Tok().Pos, Error, "yield outside of a function")
+		}
+		r.expr(e.Value)
+	case *ast.CallExpr:
+		r.expr(e.Caller)
+		for _, arg := range e.Args {
+			r.expr(arg)
+		}
+	case *ast.ConditionalExpr:
+		r.expr(e.Cond)
+		r.expr(e.Then)
+		r.expr(e.Else)
+	case *ast.SubscriptExpr:
+		r.expr(e.Array)
+		r.expr(e.Index)
+	case *ast.SliceExpr:
+		r.expr(e.Array)
+		r.expr(e.Low)
+		r.expr(e.High)
+	case *ast.MemberExpr:
+		// The member name is not a variable.
+		r.expr(e.Object)
+	case *ast.ArrayExpr:
+		for _, element := range e.Elements {
+			r.expr(element)
+		}
+	case *ast.NewExpr:
+		// The type name is not a variable.
+		r.expr(e.Expression)
+	case *ast.FunctionExpr:
+		r.function(e)
+	default:
+		r.report(expr.Tok().Pos, Error, "unknown expression type %T", expr)
+	}
+}
+
+// function Defers the resolution of a function body until the enclosing scope is closed. Functions are called after
+// they are declared, so they may use any variable of the enclosing scopes, including the one they are assigned to.
+func (r *resolver) function(e *ast.FunctionExpr) {
+	scope := r.scope
+	last := len(r.deferred) - 1
+	r.deferred[last] = append(r.deferred[last], func() {
+		savedScope, savedLoops, savedLabels := r.scope, r.loops, r.labels
+		r.scope, r.loops, r.labels = scope, 0, nil
+		r.functions++
+
+		r.open(e, e.Body.Statements)
+		for _, param := range e.Params {
+			r.declare(param, Parameter)
+		}
+		r.info.Scopes[e.Body] = r.scope
+		r.stmts(e.Body.Statements)
+		r.close()
+
+		r.functions--
+		r.scope, r.loops, r.labels = savedScope, savedLoops, savedLabels
+	})
+}
@@ -0,0 +1,189 @@
+package resolver
+
+import (
+	"fmt"
+	"script"
+	"script/ast"
+	"script/lexer"
+	"strings"
+	"testing"
+)
+
+func resolve(t *testing.T, text string) (*Info, []Diagnostic, *script.Source) {
+	t.Helper()
+	tokens, errs := lexer.Tokenize([]byte(text))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	program, errs := ast.Parse(tokens)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	info, diagnostics := Resolve(program, []string{"println", "true", "false"})
+	return info, diagnostics, script.NewSource("", []byte(text))
+}
+
+func TestDiagnostics(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"valid", "x := 1\nprintln(x)\n", nil},
+		{"undeclared", "println(x)\n", []string{"1:9 Error: undeclared variable x"}},
+		{"assignment", "x = 1\n", []string{"1:1 Error: assignment to undeclared variable x"}},
+		{"redeclared", "x := 1\nx := 2\nprintln(x)\n", []string{
+			"1:1 Warning: x declared and not used",
+			"2:1 Error: x redeclared in this scope",
+		}},
+		{"shadowed", "x := 1\n{\n  x := 2\n  println(x)\n}\nprintln(x)\n", nil},
+		{"before declaration", "println(x)\nx := 1\n", []string{
+			"1:9 Error: x used before declaration",
+			"2:1 Warning: x declared and not used",
+		}},
+		{"outer before declaration", "x := 1\n{\n  println(x)\n  x := 2\n  println(x)\n}\nprintln(x)\n", []string{
+			"3:11 Error: x used before declaration",
+		}},
+		{"hoisted", "even(2)\nfn even(n) {\n  return n == 0 || odd(n - 1)\n}\nfn odd(n) {\n  return n != 0 && even(n - 1)\n}\n", nil},
+		{"later variable", "fn f() {\n  return y\n}\ny := 1\nprintln(f())\n", nil},
+		{"unused", "x := 1\nfn f(a) {\n  y := a\n}\nf(x)\n", []string{"3:3 Warning: y declared and not used"}},
+		{"assigned only", "x := 1\nx = 2\n", []string{"1:1 Warning: x declared and not used"}},
+		{"return", "return 1\n", []string{"1:1 Error: return outside of a function"}},
+		{"defer", "defer println(1)\n", []string{"1:1 Error: defer outside of a function"}},
+		{"yield", "x := yield(1)\nprintln(x)\n", []string{"1:6 Error: yield outside of a function"}},
+		{"break", "break\n", []string{"1:1 Error: break is not in a loop"}},
+		{"continue", "continue\n", []string{"1:1 Error: continue is not in a loop"}},
+		{"break in function", "for , true, {\n  fn f() {\n    break\n  }\n  f()\n}\n", []string{
+			"3:5 Error: break is not in a loop",
+		}},
+		{"labels", "outer: for i in [1] {\n  for j in [i] {\n    continue outer\n  }\n}\n", []string{
+			"2:7 Warning: j declared and not used",
+		}},
+		{"unknown label", "for i in [1] {\n  break outer\n}\n", []string{
+			"1:5 Warning: i declared and not used",
+			"2:9 Error: unknown label outer",
+		}},
+		{"label of function", "outer: for , true, {\n  fn f() {\n    for , true, {\n      break outer\n    }\n  }\n  f()\n}\n", []string{
+			"4:13 Error: unknown label outer",
+		}},
+		{"label defined", "l: for , true, {\n  l: for , true, {\n    break l\n  }\n}\n", []string{
+			"2:3 Error: label l is already defined",
+		}},
+		{"alternative binding", "match 1 {\n  0, x => println(0)\n}\n", []string{
+			"2:6 Error: cannot bind x in alternative patterns",
+		}},
+		{"invalid pattern", "match 1 {\n  1 + 1 => println(0)\n}\n", []string{"2:3 Error: invalid pattern"}},
+		{"range pattern", "match 1 {\n  1.5..2 => println(0)\n}\n", []string{
+			"2:6 Error: range patterns expect integer literals",
+		}},
+		{"bool pattern", "match 1 {\n  true, false => println(0)\n  x => println(x)\n}\n", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, diagnostics, src := resolve(t, test.text)
+			got := make([]string, len(diagnostics))
+			for i, d := range diagnostics {
+				pos := src.Position(d.Pos)
+				got[i] = fmt.Sprintf("%d:%d %v: %s", pos.Line, pos.Column, d.Severity, d.Message)
+			}
+			if strings.Join(got, "\n") != strings.Join(test.want, "\n") {
+				t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(test.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestErrorsAndWarnings(t *testing.T) {
+	_, diagnostics, _ := resolve(t, "x := 1\nprintln(y)\n")
+	if errs := Errors(diagnostics); len(errs) != 1 || errs[0].Error() != "get character 15: undeclared variable y" {
+		t.Errorf("got errors %v", errs)
+	}
+	if warnings := Warnings(diagnostics); len(warnings) != 1 || warnings[0].Error() != "get character 0: x declared and not used" {
+		t.Errorf("got warnings %v", warnings)
+	}
+}
+
+// TestDefsAndUses Checks the declaration every identifier resolves to, as used for go-to-definition.
+func TestDefsAndUses(t *testing.T) {
+	text := "x := 1\nfn f(a) {\n  y := a + x\n  return y\n}\nx = f(x)\nprintln(x)\n"
+	info, diagnostics, src := resolve(t, text)
+	if len(diagnostics) > 0 {
+		t.Fatal(diagnostics)
+	}
+
+	location := func(ident *ast.Identifier) string {
+		pos := src.Position(ident.Tok().Pos)
+		return fmt.Sprintf("%s %d:%d", ident.Symbol, pos.Line, pos.Column)
+	}
+	got := make(map[string]string)
+	for ident, sym := range info.Uses {
+		decl := "global"
+		if sym.Decl != nil {
+			decl = location(sym.Decl)
+		}
+		got[location(ident)] = decl
+	}
+	want := map[string]string{
+		"a 3:8":       "a 2:6",
+		"x 3:12":      "x 1:1",
+		"y 4:10":      "y 3:3",
+		"x 6:1":       "x 1:1",
+		"f 6:5":       "f 2:4",
+		"x 6:7":       "x 1:1",
+		"println 7:1": "global",
+		"x 7:9":       "x 1:1",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got uses\n%v\nwant\n%v", got, want)
+	}
+
+	defs := make([]string, 0, len(info.Defs))
+	for _, sym := range info.Symbols() {
+		defs = append(defs, fmt.Sprintf("%s %v", location(sym.Decl), sym.Kind))
+	}
+	wantDefs := "x 1:1 Variable, f 2:4 Function, a 2:6 Parameter, y 3:3 Variable"
+	if got := strings.Join(defs, ", "); got != wantDefs {
+		t.Errorf("got defs %s, want %s", got, wantDefs)
+	}
+
+	x := info.Universe.Lookup("println")
+	if x == nil || x.Kind != Global || x.Reads != 1 {
+		t.Errorf("got println symbol %+v", x)
+	}
+	if sym := info.SymbolOf(info.IdentAt(len("x := 1\nfn f(a) {\n  y := a + "))); sym == nil || sym.Pos() != 0 {
+		t.Errorf("IdentAt the x read in f resolves to %+v, want the global x", sym)
+	}
+	if sym := info.Uses[findIdent(info, "x", 6, 1, src)]; sym == nil || len(sym.Uses) != 4 || sym.Reads != 3 {
+		t.Errorf("got global x %+v, want 4 uses and 3 reads", sym)
+	}
+}
+
+func findIdent(info *Info, name string, line, column int, src *script.Source) *ast.Identifier {
+	for ident := range info.Uses {
+		pos := src.Position(ident.Tok().Pos)
+		if ident.Symbol == name && pos.Line == line && pos.Column == column {
+			return ident
+		}
+	}
+	return nil
+}
+
+func TestScopes(t *testing.T) {
+	info, _, _ := resolve(t, "x := 1\nfn f(a) {\n  b := a\n  return b\n}\nfor i in [x] {\n  println(f(i))\n}\n")
+	if len(info.Universe.Children) != 1 {
+		t.Fatalf("universe has %d children, want the program scope", len(info.Universe.Children))
+	}
+	program := info.Universe.Children[0]
+	if got := strings.Join(program.Names(), " "); got != "f x" {
+		t.Errorf("program scope declares %s, want f x", got)
+	}
+
+	var names []string
+	for _, child := range program.Children {
+		names = append(names, fmt.Sprintf("%T %s", child.Node, strings.Join(child.Names(), " ")))
+	}
+	want := "*ast.ForInStmt i, *ast.FunctionExpr a b"
+	if got := strings.Join(names, ", "); got != want {
+		t.Errorf("got scopes %s, want %s", got, want)
+	}
+}
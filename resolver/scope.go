@@ -0,0 +1,132 @@
+package resolver
+
+import (
+	"script/ast"
+	"sort"
+)
+
+//go:generate stringer -type=SymbolKind
+type SymbolKind uint8
+
+const (
+	// Global is a variable declared by the host before the script runs, e.g. a builtin.
+	Global SymbolKind = iota
+	// Variable is declared with :=.
+	Variable
+	// Parameter is a function parameter.
+	Parameter
+	// Function is declared with fn name() {}.
+	Function
+)
+
+// Symbol is a declared name.
+type Symbol struct {
+	Name string
+	Kind SymbolKind
+	// Decl is the declaring identifier. It is nil for globals.
+	Decl  *ast.Identifier
+	Scope *Scope
+	// Uses holds every identifier reading or assigning the symbol, in source order.
+	Uses []*ast.Identifier
+	// Reads counts the uses that read the symbol.
+	Reads int
+}
+
+// Pos Returns the position of the declaration, or -1 for globals.
+func (s *Symbol) Pos() int {
+	if s.Decl == nil {
+		return -1
+	}
+	return s.Decl.Tok().Pos
+}
+
+// Scope is a lexical scope. Scopes are created for the program, functions, blocks and for statements, which matches
+// the frames the VM creates at runtime.
+type Scope struct {
+	Parent   *Scope
+	Children []*Scope
+	// Node is the node introducing the scope. It is nil for the universe scope holding the globals.
+	Node    ast.Node
+	Symbols map[string]*Symbol
+
+	// pending holds the names that will be declared later on in the scope.
+	pending map[string]bool
+}
+
+func newScope(parent *Scope, node ast.Node) *Scope {
+	s := &Scope{
+		Parent:  parent,
+		Node:    node,
+		Symbols: make(map[string]*Symbol),
+		pending: make(map[string]bool),
+	}
+	if parent != nil {
+		parent.Children = append(parent.Children, s)
+	}
+	return s
+}
+
+// Lookup Returns the symbol name resolves to in s or one of its parents.
+func (s *Scope) Lookup(name string) *Symbol {
+	for ; s != nil; s = s.Parent {
+		if sym, ok := s.Symbols[name]; ok {
+			return sym
+		}
+	}
+	return nil
+}
+
+// Names Returns the sorted names declared directly in s.
+func (s *Scope) Names() []string {
+	names := make([]string, 0, len(s.Symbols))
+	for name := range s.Symbols {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Info is the result of resolving a program.
+type Info struct {
+	// Universe is the root scope holding the globals. The program scope is its only child.
+	Universe *Scope
+	// Defs maps declaring identifiers to their symbols.
+	Defs map[*ast.Identifier]*Symbol
+	// Uses maps identifiers reading or assigning a variable to their symbols.
+	Uses map[*ast.Identifier]*Symbol
+	// Scopes maps nodes to the scopes they introduce.
+	Scopes map[ast.Node]*Scope
+}
+
+// SymbolOf Returns the symbol ident declares or refers to, or nil.
+func (info *Info) SymbolOf(ident *ast.Identifier) *Symbol {
+	if sym, ok := info.Defs[ident]; ok {
+		return sym
+	}
+	return info.Uses[ident]
+}
+
+// IdentAt Returns the identifier containing the rune offset pos, or nil.
+func (info *Info) IdentAt(pos int) *ast.Identifier {
+	for _, m := range []map[*ast.Identifier]*Symbol{info.Defs, info.Uses} {
+		for ident := range m {
+			start := ident.Tok().Pos
+			if start >= 0 && pos >= start && pos < start+len([]rune(ident.Symbol)) {
+				return ident
+			}
+		}
+	}
+	return nil
+}
+
+// Symbols Returns all symbols declared by the program, ordered by their declaration.
+func (info *Info) Symbols() []*Symbol {
+	symbols := make([]*Symbol, 0, len(info.Defs))
+	for _, sym := range info.Defs {
+		symbols = append(symbols, sym)
+	}
+	sort.Slice(symbols, func(i, j int) bool {
+		return symbols[i].Pos() < symbols[j].Pos()
+	})
+	return symbols
+}
@@ -0,0 +1,27 @@
+// Code generated by "stringer -type=SymbolKind"; DO NOT EDIT.
+
+package resolver
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[Global-0]
+	_ = x[Variable-1]
+	_ = x[Parameter-2]
+	_ = x[Function-3]
+}
+
+const _SymbolKind_name = "GlobalVariableParameterFunction"
+
+var _SymbolKind_index = [...]uint8{0, 6, 14, 23, 31}
+
+func (i SymbolKind) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_SymbolKind_index)-1 {
+		return "SymbolKind(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _SymbolKind_name[_SymbolKind_index[idx]:_SymbolKind_index[idx+1]]
+}
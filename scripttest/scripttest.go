@@ -0,0 +1,178 @@
+// Package scripttest runs script files as Go subtests.
+//
+// Every .ys file below a directory becomes a subtest named after its path. A script passes if it compiles and
+// executes without error, unless annotated otherwise. Annotations are line comments:
+//
+//	// stdout: <line>   the script must print exactly the lines given by all stdout annotations, in order
+//	// error: <text>    compiling or executing the script must fail with an error containing text
+//
+// If Options.Golden is set, the bytecode listing of every script is compared with a golden file next to it, named
+// after the script with the .yasm extension. Run go test with -update to rewrite the golden files.
+package scripttest
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"script"
+	"script/compiler"
+	"script/vm"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden bytecode listings of script tests")
+
+const (
+	Ext       = ".ys"
+	GoldenExt = ".yasm"
+
+	stdoutAnnotation = "stdout:"
+	errorAnnotation  = "error:"
+)
+
+type Options struct {
+	// Golden enables the comparison of bytecode listings with golden files.
+	Golden bool
+	// Setup is called with the VM of every script before it is executed, e.g. to declare host functions.
+	Setup func(t *testing.T, v *vm.VM)
+}
+
+// Run Runs every script below dir as a subtest of t.
+func Run(t *testing.T, dir string, opts Options) {
+	t.Helper()
+
+	files := make([]string, 0)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && filepath.Ext(path) == Ext {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("no %s files in %s", Ext, dir)
+	}
+
+	for _, file := range files {
+		name, err := filepath.Rel(dir, file)
+		if err != nil {
+			name = file
+		}
+		name = strings.TrimSuffix(filepath.ToSlash(name), Ext)
+
+		t.Run(name, func(t *testing.T) {
+			RunFile(t, file, opts)
+		})
+	}
+}
+
+// RunFile Runs a single script as part of t.
+func RunFile(t *testing.T, file string, opts Options) {
+	t.Helper()
+
+	text, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := parseAnnotations(text)
+	src := script.NewSource(file, text)
+
+	var out bytes.Buffer
+	v := vm.New()
+	v.SetOutput(&out)
+	if opts.Setup != nil {
+		opts.Setup(t, v)
+	}
+
+	bc, _, err := compiler.CompileSource(text, v.Globals()...)
+	if err != nil {
+		if !want.matchError(err) {
+			t.Fatalf("%s: compile: %v", position(src, err), err)
+		}
+		return
+	}
+
+	if opts.Golden {
+		checkGolden(t, strings.TrimSuffix(file, Ext)+GoldenExt, bc)
+	}
+
+	err = v.Execute(bc)
+	switch {
+	case err != nil && !want.matchError(err):
+		t.Errorf("%s: %v", src.Position(v.Pos()), err)
+	case err == nil && want.err != "":
+		t.Errorf("expected error containing %q, got none", want.err)
+	}
+
+	if want.stdout != nil {
+		if got := out.String(); got != strings.Join(want.stdout, "") {
+			t.Errorf("stdout mismatch\n--- got ---\n%s--- want ---\n%s", got, strings.Join(want.stdout, ""))
+		}
+	}
+}
+
+type annotations struct {
+	// stdout holds the expected output lines including their line feed, or nil if output is not checked.
+	stdout []string
+	err    string
+}
+
+func parseAnnotations(src []byte) annotations {
+	a := annotations{}
+	for _, line := range strings.Split(string(src), "\n") {
+		line = strings.TrimSpace(line)
+		comment, ok := strings.CutPrefix(line, "//")
+		if !ok {
+			continue
+		}
+		comment = strings.TrimPrefix(comment, " ")
+
+		if text, ok := strings.CutPrefix(comment, stdoutAnnotation); ok {
+			a.stdout = append(a.stdout, strings.TrimPrefix(text, " ")+"\n")
+		} else if text, ok := strings.CutPrefix(comment, errorAnnotation); ok {
+			a.err = strings.TrimSpace(text)
+		}
+	}
+	return a
+}
+
+func (a annotations) matchError(err error) bool {
+	return a.err != "" && strings.Contains(err.Error(), a.err)
+}
+
+func checkGolden(t *testing.T, path string, bc vm.Bytecode) {
+	t.Helper()
+
+	got := bc.String()
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create it)", err)
+	}
+	if got != string(want) {
+		t.Errorf("bytecode differs from %s (run with -update to rewrite it)\n--- got ---\n%s--- want ---\n%s", path, got, want)
+	}
+}
+
+func position(src *script.Source, err error) string {
+	var posErr *script.PosError
+	if errors.As(err, &posErr) && posErr.Pos >= 0 {
+		return src.Position(posErr.Pos).String()
+	}
+	return src.File
+}
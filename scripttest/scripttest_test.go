@@ -0,0 +1,30 @@
+package scripttest
+
+import (
+	"script/vm"
+	"slices"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	Run(t, "testdata", Options{
+		Golden: true,
+		Setup: func(t *testing.T, v *vm.VM) {
+			v.Declare("host", vm.NewExternalFunc(func() int { return 1 }))
+		},
+	})
+}
+
+func TestParseAnnotations(t *testing.T) {
+	a := parseAnnotations([]byte("x := 1\n  // stdout: 1\n//stdout:two  words\n// error:  boom \n// other: ignored\n"))
+	if want := []string{"1\n", "two  words\n"}; !slices.Equal(a.stdout, want) {
+		t.Errorf("got stdout %q, want %q", a.stdout, want)
+	}
+	if a.err != "boom" {
+		t.Errorf("got error %q, want %q", a.err, "boom")
+	}
+
+	if a := parseAnnotations([]byte("println(1)\n")); a.stdout != nil || a.err != "" {
+		t.Errorf("got %+v for a script without annotations", a)
+	}
+}
@@ -0,0 +1,71 @@
+package testrunner
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	Ext        = ".ys"
+	TestSuffix = "_test" + Ext
+	TestsDir   = "tests"
+)
+
+// IsTestFile Reports whether path names a script test: either a *_test.ys file or a .ys file inside a tests directory.
+func IsTestFile(path string) bool {
+	if strings.HasSuffix(path, TestSuffix) {
+		return true
+	}
+	return filepath.Ext(path) == Ext && filepath.Base(filepath.Dir(path)) == TestsDir
+}
+
+// skipDir Reports whether Discover skips the directory name.
+func skipDir(name string) bool {
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata"
+}
+
+// Discover Returns the sorted test files found in paths. Directories are walked recursively, skipping hidden ones,
+// ones starting with _ and testdata directories, like go test does. Paths given explicitly are always included.
+func Discover(paths []string) ([]string, error) {
+	found := make(map[string]bool)
+
+	for _, root := range paths {
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			found[filepath.Clean(root)] = true
+			continue
+		}
+
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path != root && skipDir(d.Name()) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if IsTestFile(path) {
+				found[path] = true
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	files := make([]string, 0, len(found))
+	for file := range found {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files, nil
+}
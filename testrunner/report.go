@@ -0,0 +1,187 @@
+package testrunner
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Summary Counts the results by status.
+func Summary(results []Result) (passed, failed, errored int) {
+	for _, r := range results {
+		switch r.Status {
+		case Pass:
+			passed++
+		case Fail:
+			failed++
+		case Error:
+			errored++
+		}
+	}
+	return
+}
+
+func (r Result) location() string {
+	if r.Pos.Line == 0 {
+		return r.File
+	}
+	return r.Pos.String()
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// WriteText Writes a human-readable report. Script output is only shown for tests that did not pass, unless verbose is set.
+// Compiler warnings precede the result of their file.
+func WriteText(w io.Writer, results []Result, verbose bool) error {
+	var total time.Duration
+	for _, r := range results {
+		total += r.Duration
+		for _, warning := range r.Warnings {
+			fmt.Fprintf(w, "WARN  %s\n", warning)
+		}
+		switch r.Status {
+		case Pass:
+			fmt.Fprintf(w, "ok    %s (%ss)\n", r.File, seconds(r.Duration))
+		case Fail:
+			fmt.Fprintf(w, "FAIL  %s: %s\n", r.location(), r.Message)
+		case Error:
+			fmt.Fprintf(w, "ERROR %s: %s\n", r.location(), r.Message)
+		}
+		if r.Output != "" && (verbose || r.Status != Pass) {
+			for _, line := range strings.Split(strings.TrimSuffix(r.Output, "\n"), "\n") {
+				fmt.Fprintf(w, "      | %s\n", line)
+			}
+		}
+	}
+
+	passed, failed, errored := Summary(results)
+	status := "PASS"
+	if failed+errored > 0 {
+		status = "FAIL"
+	}
+	_, err := fmt.Fprintf(w, "%s: %d passed, %d failed, %d errors (%ss)\n", status, passed, failed, errored, seconds(total))
+	return err
+}
+
+// WriteTAP Writes a report in the Test Anything Protocol, version 13.
+func WriteTAP(w io.Writer, results []Result) error {
+	fmt.Fprintln(w, "TAP version 13")
+	fmt.Fprintf(w, "1..%d\n", len(results))
+	for i, r := range results {
+		if r.Status == Pass {
+			fmt.Fprintf(w, "ok %d - %s\n", i+1, r.File)
+		} else {
+			fmt.Fprintf(w, "not ok %d - %s\n", i+1, r.File)
+			fmt.Fprintln(w, "  ---")
+			fmt.Fprintf(w, "  message: %q\n", r.Message)
+			fmt.Fprintf(w, "  severity: %s\n", strings.ToLower(r.Status.String()))
+			fmt.Fprintf(w, "  at: %q\n", r.location())
+			fmt.Fprintf(w, "  duration_ms: %d\n", r.Duration.Milliseconds())
+			fmt.Fprintln(w, "  ...")
+		}
+		if r.Output != "" {
+			for _, line := range strings.Split(strings.TrimSuffix(r.Output, "\n"), "\n") {
+				fmt.Fprintf(w, "# %s\n", line)
+			}
+		}
+	}
+	return nil
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit Writes a JUnit XML report. Every directory becomes a test suite and every file a test case.
+func WriteJUnit(w io.Writer, results []Result) error {
+	report := junitSuites{}
+	index := make(map[string]int)
+	durations := make([]time.Duration, 0)
+
+	var total time.Duration
+	for _, r := range results {
+		dir := filepath.ToSlash(filepath.Dir(r.File))
+		i, ok := index[dir]
+		if !ok {
+			i = len(report.Suites)
+			index[dir] = i
+			report.Suites = append(report.Suites, junitSuite{Name: dir})
+			durations = append(durations, 0)
+		}
+		suite := &report.Suites[i]
+
+		c := junitCase{
+			Name:      filepath.Base(r.File),
+			ClassName: dir,
+			Time:      seconds(r.Duration),
+			SystemOut: r.Output,
+		}
+		problem := &junitProblem{Message: r.Message, Type: strings.ToLower(r.Status.String()), Text: r.location() + ": " + r.Message}
+		switch r.Status {
+		case Fail:
+			c.Failure = problem
+			suite.Failures++
+			report.Failures++
+		case Error:
+			c.Error = problem
+			suite.Errors++
+			report.Errors++
+		}
+
+		suite.Tests++
+		suite.Cases = append(suite.Cases, c)
+		report.Tests++
+		durations[i] += r.Duration
+		total += r.Duration
+	}
+
+	report.Time = seconds(total)
+	for i, d := range durations {
+		report.Suites[i].Time = seconds(d)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
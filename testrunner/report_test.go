@@ -0,0 +1,98 @@
+package testrunner
+
+import (
+	"bytes"
+	"script"
+	"testing"
+	"time"
+)
+
+var reportResults = []Result{
+	{File: "tests/pass.ys", Status: Pass, Output: "hello\n", Duration: 1500 * time.Microsecond},
+	{
+		File:     "tests/math/fail.ys",
+		Status:   Fail,
+		Message:  "assertion failed: sum",
+		Pos:      script.Position{File: "tests/math/fail.ys", Line: 3, Column: 1},
+		Warnings: []string{"tests/math/fail.ys:1:1: x declared and not used"},
+		Duration: 2 * time.Millisecond,
+	},
+	{File: "tests/math/error.ys", Status: Error, Message: "expected primary expression", Output: "a\nb\n"},
+}
+
+func TestWriteText(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteText(&b, reportResults, false); err != nil {
+		t.Fatal(err)
+	}
+	want := `ok    tests/pass.ys (0.002s)
+WARN  tests/math/fail.ys:1:1: x declared and not used
+FAIL  tests/math/fail.ys:3:1: assertion failed: sum
+ERROR tests/math/error.ys: expected primary expression
+      | a
+      | b
+FAIL: 1 passed, 1 failed, 1 errors (0.004s)
+`
+	if got := b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteTAP(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteTAP(&b, reportResults); err != nil {
+		t.Fatal(err)
+	}
+	want := `TAP version 13
+1..3
+ok 1 - tests/pass.ys
+# hello
+not ok 2 - tests/math/fail.ys
+  ---
+  message: "assertion failed: sum"
+  severity: fail
+  at: "tests/math/fail.ys:3:1"
+  duration_ms: 2
+  ...
+not ok 3 - tests/math/error.ys
+  ---
+  message: "expected primary expression"
+  severity: error
+  at: "tests/math/error.ys"
+  duration_ms: 0
+  ...
+# a
+# b
+`
+	if got := b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteJUnit(&b, reportResults); err != nil {
+		t.Fatal(err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites tests="3" failures="1" errors="1" time="0.004">
+  <testsuite name="tests" tests="1" failures="0" errors="0" time="0.002">
+    <testcase name="pass.ys" classname="tests" time="0.002">
+      <system-out>hello&#xA;</system-out>
+    </testcase>
+  </testsuite>
+  <testsuite name="tests/math" tests="2" failures="1" errors="1" time="0.002">
+    <testcase name="fail.ys" classname="tests/math" time="0.002">
+      <failure message="assertion failed: sum" type="fail">tests/math/fail.ys:3:1: assertion failed: sum</failure>
+    </testcase>
+    <testcase name="error.ys" classname="tests/math" time="0.000">
+      <error message="expected primary expression" type="error">tests/math/error.ys: expected primary expression</error>
+      <system-out>a&#xA;b&#xA;</system-out>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+	if got := b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
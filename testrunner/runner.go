@@ -0,0 +1,113 @@
+package testrunner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"script"
+	"script/compiler"
+	"script/vm"
+	"time"
+)
+
+//go:generate stringer -type=Status
+type Status uint8
+
+const (
+	Pass Status = iota
+	// Fail means an assertion did not hold.
+	Fail
+	// Error means the script could not be compiled or aborted with a runtime error.
+	Error
+)
+
+// Result is the outcome of running a single test file.
+type Result struct {
+	File   string
+	Status Status
+	// Message describes the failure. It is empty if the test passed.
+	Message string
+	// Pos is the location of the failure. Line is 0 if the location is unknown.
+	Pos script.Position
+	// Warnings are the compiler warnings of the file, e.g. unused variables, prefixed with their location.
+	Warnings []string
+	Output   string
+	Duration time.Duration
+}
+
+// Run Runs all files in order and returns their results.
+func Run(files []string) []Result {
+	results := make([]Result, len(files))
+	for i, file := range files {
+		results[i] = RunFile(file)
+	}
+	return results
+}
+
+// RunFile Compiles and executes a test file in a fresh VM.
+func RunFile(file string) Result {
+	start := time.Now()
+	r := run(file)
+	r.Duration = time.Since(start)
+	return r
+}
+
+// locate Returns the message of err prefixed with its source location, e.g. "a.ys:3:1: x declared and not used".
+func locate(src *script.Source, err error) string {
+	var posErr *script.PosError
+	if errors.As(err, &posErr) && posErr.Pos >= 0 {
+		return fmt.Sprintf("%s: %s", src.Position(posErr.Pos), posErr.Message)
+	}
+	return fmt.Sprintf("%s: %v", src.File, err)
+}
+
+func run(file string) Result {
+	r := Result{File: file}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		r.Status = Error
+		r.Message = err.Error()
+		return r
+	}
+	src := script.NewSource(file, data)
+
+	v := vm.New()
+	bc, warnings, err := compiler.CompileSource(data, v.Globals()...)
+	for _, w := range warnings {
+		r.Warnings = append(r.Warnings, locate(src, w))
+	}
+	if err != nil {
+		r.Status = Error
+		r.Message = err.Error()
+		var posErr *script.PosError
+		if errors.As(err, &posErr) && posErr.Pos >= 0 {
+			r.Message = posErr.Message
+			r.Pos = src.Position(posErr.Pos)
+		}
+		return r
+	}
+
+	var out bytes.Buffer
+	v.SetOutput(&out)
+	err = v.Execute(bc)
+	r.Output = out.String()
+	if err == nil {
+		return r
+	}
+
+	r.Status = Error
+	if errors.Is(err, vm.ErrAssertion) {
+		r.Status = Fail
+	}
+	r.Message = err.Error()
+	var runtimeErr *vm.RuntimeError
+	if errors.As(err, &runtimeErr) {
+		r.Message = runtimeErr.Message
+	}
+	if pos := v.Pos(); pos >= 0 {
+		r.Pos = src.Position(pos)
+	}
+	return r
+}
@@ -0,0 +1,77 @@
+package testrunner
+
+import (
+	"path/filepath"
+	"script"
+	"strings"
+	"testing"
+)
+
+func TestDiscover(t *testing.T) {
+	files, err := Discover([]string{"testdata"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join("testdata", "lib", "runtime_test.ys"),
+		filepath.Join("testdata", "lib", "syntax_test.ys"),
+		filepath.Join("testdata", "tests", "fail.ys"),
+		filepath.Join("testdata", "tests", "pass.ys"),
+		filepath.Join("testdata", "tests", "unused.ys"),
+	}
+	if len(files) != len(want) {
+		t.Fatalf("got %v, want %v", files, want)
+	}
+	for i := range want {
+		if files[i] != want[i] {
+			t.Errorf("file %d: got %s, want %s", i, files[i], want[i])
+		}
+	}
+}
+
+func TestDiscoverSkipsTestdata(t *testing.T) {
+	files, err := Discover([]string{"."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) > 0 {
+		t.Errorf("got %v, want no files outside of testdata", files)
+	}
+}
+
+func TestRunFile(t *testing.T) {
+	tests := []struct {
+		file    string
+		status  Status
+		message string
+		line    int
+		column  int
+		output  string
+		warning string
+	}{
+		{file: "testdata/tests/pass.ys", status: Pass, output: "hi\n"},
+		{file: "testdata/tests/fail.ys", status: Fail, message: "assertion failed: x is 2", line: 2, column: 1},
+		{file: "testdata/lib/syntax_test.ys", status: Error, message: "(EOF: ): expected primary expression", line: 1, column: 6},
+		{file: "testdata/lib/runtime_test.ys", status: Error, message: "division by zero", line: 1, column: 9},
+		{file: "testdata/tests/unused.ys", status: Pass, warning: "testdata/tests/unused.ys:2:3: x declared and not used"},
+	}
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			r := RunFile(test.file)
+			if r.Status != test.status || r.Message != test.message || r.Output != test.output {
+				t.Errorf("got %v %q with output %q, want %v %q with output %q",
+					r.Status, r.Message, r.Output, test.status, test.message, test.output)
+			}
+			want := script.Position{}
+			if test.line > 0 {
+				want = script.Position{File: test.file, Line: test.line, Column: test.column}
+			}
+			if r.Pos != want {
+				t.Errorf("got position %v, want %v", r.Pos, want)
+			}
+			if got := strings.Join(r.Warnings, "\n"); got != test.warning {
+				t.Errorf("got warnings %q, want %q", got, test.warning)
+			}
+		})
+	}
+}
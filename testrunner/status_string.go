@@ -0,0 +1,26 @@
+// Code generated by "stringer -type=Status"; DO NOT EDIT.
+
+package testrunner
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[Pass-0]
+	_ = x[Fail-1]
+	_ = x[Error-2]
+}
+
+const _Status_name = "PassFailError"
+
+var _Status_index = [...]uint8{0, 4, 8, 13}
+
+func (i Status) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_Status_index)-1 {
+		return "Status(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _Status_name[_Status_index[idx]:_Status_index[idx+1]]
+}
@@ -0,0 +1,116 @@
+package vm
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ArrayValue is the value of array expressions like [1, 2]. Arrays are passed by reference, push, pop, insert and
+// remove change the array they are called with.
+//
+// A slice like a[1:3] shares the storage of the array it is taken from: assigning an element of either of them changes
+// the element of the other one. Once either of them changes its length, it gets storage of its own, and the two are
+// independent. Sharing is not tracked per slice: an array stays shared after its slices are gone, so the first change
+// of its length copies it even then. Later changes do not copy it again.
+type ArrayValue struct {
+	elements []any
+	// shared is set if the storage of elements may be used by another array, see Slice.
+	shared bool
+}
+
+// NewArray Returns an array of elements. The array keeps the slice, so that changes to elements are seen by both.
+func NewArray(elements []any) *ArrayValue {
+	return &ArrayValue{elements: elements}
+}
+
+// Elements Returns the elements of a. The slice shares the storage of a until its length changes.
+func (a *ArrayValue) Elements() []any {
+	return a.elements
+}
+
+// Len Returns the number of elements of a.
+func (a *ArrayValue) Len() int {
+	return len(a.elements)
+}
+
+// String Returns the elements like fmt prints a slice, e.g. [1 2 3]. An array containing itself is printed as [...]
+// where it repeats.
+func (a *ArrayValue) String() string {
+	return a.join(" ", func(v any) string { return fmt.Sprint(v) }, nil)
+}
+
+// join Returns the elements of a formatted with format, separated by sep and enclosed in brackets. Nested arrays are
+// joined the same way, except for the ones in outer, which are being joined already and are formatted as [...].
+func (a *ArrayValue) join(sep string, format func(any) string, outer []*ArrayValue) string {
+	if slices.Contains(outer, a) {
+		return "[...]"
+	}
+	outer = append(outer, a)
+	elements := make([]string, len(a.elements))
+	for i, e := range a.elements {
+		if arr, ok := e.(*ArrayValue); ok {
+			elements[i] = arr.join(sep, format, outer)
+			continue
+		}
+		elements[i] = format(e)
+	}
+	return "[" + strings.Join(elements, sep) + "]"
+}
+
+// index Returns the element index i refers to, counting from the end if it is negative, and whether it is in bounds.
+// With end set, the length of a is in bounds as well, as it is for slice bounds and insert.
+func (a *ArrayValue) index(i int64, end bool) (int, bool) {
+	n := int64(len(a.elements))
+	if i < 0 {
+		i += n
+	}
+	if end {
+		return int(i), i >= 0 && i <= n
+	}
+	return int(i), i >= 0 && i < n
+}
+
+// Slice Returns the elements from low up to, but excluding, high. The slice shares the storage of a, both are marked
+// as shared until their length changes, see own.
+func (a *ArrayValue) Slice(low, high int) *ArrayValue {
+	a.shared = true
+	return &ArrayValue{elements: a.elements[low:high:high], shared: true}
+}
+
+// own Gives a storage of its own to a before its length changes.
+func (a *ArrayValue) own() {
+	if a.shared {
+		a.elements = slices.Clone(a.elements)
+		a.shared = false
+	}
+}
+
+// Push Appends values to the end of a.
+func (a *ArrayValue) Push(values ...any) {
+	a.own()
+	a.elements = append(a.elements, values...)
+}
+
+// Pop Removes and returns the last element of a, which must not be empty.
+func (a *ArrayValue) Pop() any {
+	a.own()
+	last := a.elements[len(a.elements)-1]
+	a.elements[len(a.elements)-1] = nil
+	a.elements = a.elements[:len(a.elements)-1]
+	return last
+}
+
+// Insert Inserts values before the element i, or appends them if i is the length of a.
+func (a *ArrayValue) Insert(i int, values ...any) {
+	a.own()
+	a.elements = slices.Insert(a.elements, i, values...)
+}
+
+// Remove Removes and returns the element i.
+func (a *ArrayValue) Remove(i int) any {
+	a.own()
+	removed := a.elements[i]
+	a.elements = slices.Delete(a.elements, i, i+1)
+	return removed
+}
@@ -0,0 +1,252 @@
+package vm
+
+import (
+	"fmt"
+	"math"
+	"script/decimal"
+	"sort"
+	"unicode/utf8"
+)
+
+// builtins Returns the globals every VM is created with.
+func builtins(vm *VM) map[string]any {
+	return map[string]any{
+		"int":     Type{Int},
+		"float":   Type{Float},
+		"bool":    Type{Bool},
+		"string":  Type{String},
+		"bigint":  Type{BigInt},
+		"decimal": Type{Decimal},
+
+		"true":  true,
+		"false": false,
+
+		"println": NewExternalFunc(func(v ...any) {
+			fmt.Fprintln(vm.out, v...)
+		}),
+
+		"assert":      ExternalFunc{assert},
+		"assertEqual": ExternalFunc{assertEqual},
+		"fail":        ExternalFunc{fail},
+
+		"round": ExternalFunc{round},
+
+		"len":    ExternalFunc{length},
+		"append": ExternalFunc{appendValues},
+		"push":   ExternalFunc{push},
+		"pop":    ExternalFunc{pop},
+		"insert": ExternalFunc{insert},
+		"remove": ExternalFunc{remove},
+
+		"coroutine": ExternalFunc{coroutine},
+		"resume":    ExternalFunc{resume},
+		"status":    ExternalFunc{status},
+	}
+}
+
+// Builtins Returns the sorted names of the globals declared by New.
+func Builtins() []string {
+	names := make([]string, 0)
+	for name := range builtins(nil) {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// assert Fails if its first argument is not truthy. A second argument is used as the failure message.
+// With three arguments it is called as assert(actual, expected, name) and compares using Equal.
+func assert(vm *VM, argCount int) any {
+	args := vm.popArgs(argCount)
+	switch argCount {
+	case 1:
+		if !Truthy(args[0]) {
+			vm.fail("assertion failed", ErrAssertion)
+		}
+	case 2:
+		if !Truthy(args[0]) {
+			vm.fail(fmt.Sprintf("assertion failed: %v", args[1]), ErrAssertion)
+		}
+	case 3:
+		assertEqualValues(vm, args[0], args[1], args[2])
+	default:
+		vm.Err(fmt.Sprintf("assert expects 1 to 3 arguments, got %d", argCount))
+	}
+	return nil
+}
+
+// assertEqual Fails if assertEqual(actual, expected, [name]) is called with values that are not Equal.
+func assertEqual(vm *VM, argCount int) any {
+	args := vm.popArgs(argCount)
+	switch argCount {
+	case 2:
+		assertEqualValues(vm, args[0], args[1], nil)
+	case 3:
+		assertEqualValues(vm, args[0], args[1], args[2])
+	default:
+		vm.Err(fmt.Sprintf("assertEqual expects 2 or 3 arguments, got %d", argCount))
+	}
+	return nil
+}
+
+func assertEqualValues(vm *VM, actual, expected, name any) {
+	if Equal(actual, expected) {
+		return
+	}
+	msg := fmt.Sprintf("got %s, expected %s", Repr(actual), Repr(expected))
+	if name != nil {
+		msg = fmt.Sprintf("%v: %s", name, msg)
+	}
+	vm.fail(msg, ErrAssertion)
+}
+
+// fail Fails unconditionally with an optional message.
+func fail(vm *VM, argCount int) any {
+	args := vm.popArgs(argCount)
+	if argCount == 0 {
+		vm.fail("failed", ErrAssertion)
+	}
+	vm.fail(fmt.Sprint(args...), ErrAssertion)
+	return nil
+}
+
+// round Rounds a decimal with round(value, [places], [mode]) to places decimal places, 0 by default. The mode is one of
+// the names of decimal.Modes and defaults to "half_even". Integers are rounded as decimals. A float is rounded as the
+// shortest decimal that converts back to it, so round(2.675, 2) is 2.68, and the result is a float again.
+func round(vm *VM, argCount int) any {
+	args := vm.popArgs(argCount)
+	if argCount < 1 || argCount > 3 {
+		vm.Err(fmt.Sprintf("round expects 1 to 3 arguments, got %d", argCount))
+	}
+	f, isFloat := args[0].(float64)
+	d, ok := toDecimal(args[0])
+	if isFloat {
+		var err error
+		if d, err = decimal.FromFloat(f); err != nil {
+			vm.Err(fmt.Sprintf("round: %v", err))
+		}
+		ok = true
+	}
+	if !ok {
+		vm.Err(fmt.Sprintf("round expects a number, got %s", Repr(args[0])))
+	}
+	places := int64(0)
+	if argCount > 1 {
+		places, ok = args[1].(int64)
+		if !ok || places < 0 || places > math.MaxInt32 {
+			vm.Err(fmt.Sprintf("round expects a non-negative number of places, got %s", Repr(args[1])))
+		}
+	}
+	mode := decimal.HalfEven
+	if argCount > 2 {
+		name, _ := args[2].(string)
+		mode, ok = decimal.Modes[name]
+		if !ok {
+			vm.Err(fmt.Sprintf("unknown rounding mode %s", Repr(args[2])))
+		}
+	}
+	if isFloat {
+		return d.Round(int32(places), mode).Float64()
+	}
+	return d.Round(int32(places), mode)
+}
+
+// arrayArg Returns the first of args as array, or fails with a message for the builtin name. The argument count must
+// have been checked.
+func arrayArg(vm *VM, name string, args []any) *ArrayValue {
+	arr, ok := args[0].(*ArrayValue)
+	if !ok {
+		vm.Err(fmt.Sprintf("%s expects an array, got %s", name, Repr(args[0])))
+	}
+	return arr
+}
+
+// indexArg Returns the index of an element of arr or, with end set, the length of arr, for the builtin name.
+// A negative index counts from the end.
+func indexArg(vm *VM, name string, arr *ArrayValue, v any, end bool) int {
+	k, ok := v.(int64)
+	if !ok {
+		vm.Err(fmt.Sprintf("%s expects an integer index, got %s", name, Repr(v)))
+	}
+	i, ok := arr.index(k, end)
+	if !ok {
+		vm.Err(fmt.Sprintf("%s: index %d out of range for array of length %d", name, k, arr.Len()))
+	}
+	return i
+}
+
+// length Returns the number of elements of an array, of characters of a string or of entries of a host map.
+func length(vm *VM, argCount int) any {
+	args := vm.popArgs(argCount)
+	if argCount != 1 {
+		vm.Err(fmt.Sprintf("len expects 1 argument, got %d", argCount))
+	}
+	switch v := args[0].(type) {
+	case *ArrayValue:
+		return int64(v.Len())
+	case string:
+		return int64(utf8.RuneCountInString(v))
+	case map[string]any:
+		return int64(len(v))
+	}
+	vm.Err(fmt.Sprintf("len expects an array, a string or a map, got %s", Repr(args[0])))
+	return nil
+}
+
+// appendValues Returns a new array with the elements of the array of append(array, values...) followed by the values.
+// Unlike push, it does not change the array.
+func appendValues(vm *VM, argCount int) any {
+	args := vm.popArgs(argCount)
+	if argCount < 1 {
+		vm.Err("append expects at least 1 argument, got 0")
+	}
+	arr := arrayArg(vm, "append", args)
+	elements := make([]any, 0, arr.Len()+len(args)-1)
+	elements = append(elements, arr.elements...)
+	return NewArray(append(elements, args[1:]...))
+}
+
+// push Appends the values of push(array, values...) to the array.
+func push(vm *VM, argCount int) any {
+	args := vm.popArgs(argCount)
+	if argCount < 1 {
+		vm.Err("push expects at least 1 argument, got 0")
+	}
+	arrayArg(vm, "push", args).Push(args[1:]...)
+	return nil
+}
+
+// pop Removes and returns the last element of pop(array). The array must not be empty.
+func pop(vm *VM, argCount int) any {
+	args := vm.popArgs(argCount)
+	if argCount != 1 {
+		vm.Err(fmt.Sprintf("pop expects 1 argument, got %d", argCount))
+	}
+	arr := arrayArg(vm, "pop", args)
+	if arr.Len() == 0 {
+		vm.Err("pop from an empty array")
+	}
+	return arr.Pop()
+}
+
+// insert Inserts the values of insert(array, index, values...) before the element index, or appends them if index is
+// the length of the array.
+func insert(vm *VM, argCount int) any {
+	args := vm.popArgs(argCount)
+	if argCount < 2 {
+		vm.Err(fmt.Sprintf("insert expects at least 2 arguments, got %d", argCount))
+	}
+	arr := arrayArg(vm, "insert", args)
+	arr.Insert(indexArg(vm, "insert", arr, args[1], true), args[2:]...)
+	return nil
+}
+
+// remove Removes and returns the element index of remove(array, index).
+func remove(vm *VM, argCount int) any {
+	args := vm.popArgs(argCount)
+	if argCount != 2 {
+		vm.Err(fmt.Sprintf("remove expects 2 arguments, got %d", argCount))
+	}
+	arr := arrayArg(vm, "remove", args)
+	return arr.Remove(indexArg(vm, "remove", arr, args[1], false))
+}
@@ -0,0 +1,211 @@
+package vm
+
+import (
+	"fmt"
+)
+
+//go:generate stringer -type=CoroutineStatus -linecomment
+type CoroutineStatus uint8
+
+const (
+	// Suspended coroutines were not started yet or wait in a yield to be resumed.
+	Suspended CoroutineStatus = iota // suspended
+	// Running is the status of the coroutine being executed.
+	Running // running
+	// Normal coroutines resumed another coroutine and wait for it to yield or return.
+	Normal // normal
+	// Dead coroutines returned or failed with an error. They cannot be resumed.
+	Dead // dead
+)
+
+// Coroutine is a function executed step by step in its own context, with its own stack and frame chain. Every resume
+// runs it until it yields a value or returns. Coroutines are created by the coroutine builtin or with NewCoroutine.
+type Coroutine struct {
+	fn      Func
+	status  CoroutineStatus
+	started bool
+	context context
+	// root is the parent of the call frame of fn. The call returns when the frame chain is back at root.
+	root *Frame
+	// yielded is set by YIELD, value is the value passed to YIELD.
+	yielded bool
+	value   any
+}
+
+// Status Returns the status of the coroutine.
+func (co *Coroutine) Status() CoroutineStatus {
+	return co.status
+}
+
+func (co *Coroutine) String() string {
+	return fmt.Sprintf("<coroutine %s %v>", co.fn.Name, co.status)
+}
+
+// NewCoroutine Creates a suspended coroutine running the script function fn, e.g. a function looked up with Global.
+func (vm *VM) NewCoroutine(fn any) (*Coroutine, error) {
+	f, ok := fn.(Func)
+	if !ok {
+		return nil, fmt.Errorf("cannot create a coroutine from non-function %v", fn)
+	}
+	root := newFrame(vm.global())
+	co := &Coroutine{fn: f, root: root}
+	co.context = context{stack: newStack(), cframe: root, coroutine: co}
+	return co, nil
+}
+
+// Resume Resumes the coroutine like the resume builtin. The first resume passes args to the function, later ones pass
+// at most one value as the result of the yield the coroutine waits in. Arguments are converted like the values of
+// Declare. It returns the value the coroutine yields or returns, or the error it failed with.
+func (vm *VM) Resume(co *Coroutine, args ...any) (value any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			runtimeErr, ok := r.(*RuntimeError)
+			if !ok {
+				panic(r)
+			}
+			err = runtimeErr
+		}
+	}()
+	values := make([]any, len(args))
+	for i, arg := range args {
+		values[i] = fromNative(arg)
+	}
+	return fromNative(vm.resume(co, values)), nil
+}
+
+// resume Switches to the context of co and runs it until it yields or returns. Errors, including the ones raised by
+// the coroutine, are raised in the context of the resumer.
+func (vm *VM) resume(co *Coroutine, args []any) any {
+	switch {
+	case co.status != Suspended:
+		vm.Err(fmt.Sprintf("cannot resume %v coroutine", co.status))
+	case co.started && len(args) > 1:
+		vm.Err(fmt.Sprintf("resume expects at most 1 value for a started coroutine, got %d", len(args)))
+	}
+
+	resumer := vm.context
+	if resumer.coroutine != nil {
+		resumer.coroutine.status = Normal
+	}
+	vm.context = co.context
+	co.status = Running
+	co.yielded = false
+
+	value, err := vm.step(co, args, resumer.pointer)
+
+	co.context = vm.context
+	vm.context = resumer
+	if resumer.coroutine != nil {
+		resumer.coroutine.status = Running
+	}
+
+	if co.yielded && err == nil {
+		co.status = Suspended
+		return value
+	}
+	co.status = Dead
+	if err != nil {
+		if runtimeErr, ok := err.(*RuntimeError); ok {
+			panic(runtimeErr)
+		}
+		vm.fail(err.Error(), err)
+	}
+	return value
+}
+
+// step Runs co in the current context until it yields or returns and returns the value. pointer is the instruction
+// of the resumer, the call of the function returns to it in tracebacks.
+func (vm *VM) step(co *Coroutine, args []any, pointer int) (value any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			runtimeErr, ok := r.(*RuntimeError)
+			if !ok {
+				panic(r)
+			}
+			err = runtimeErr
+		}
+	}()
+
+	if !co.started {
+		co.started = true
+		for i := len(args) - 1; i >= 0; i-- {
+			vm.stack.Push(args[i])
+		}
+		vm.stack.Push(int64(len(args)))
+		vm.stack.Push(co.fn)
+		vm.frame(pointer, pointer+1)
+		vm.call(&vm.pointer)
+		// Skip ENTER like the execution loop does after CALL.
+		vm.pointer++
+	} else {
+		var v any
+		if len(args) > 0 {
+			v = args[0]
+		}
+		// The result of the YIELD.
+		vm.stack.Push(v)
+	}
+
+	if _, err := vm.run(func() bool { return co.yielded || vm.cframe == co.root }); err != nil {
+		return nil, err
+	}
+	switch {
+	case co.yielded:
+		return co.value, nil
+	case vm.cframe == co.root:
+		return vm.stack.Pop(), nil
+	default:
+		return nil, nil
+	}
+}
+
+// yield Suspends the running coroutine with the value on top of the stack, see YIELD.
+func (vm *VM) yield() {
+	switch {
+	case vm.coroutine == nil:
+		vm.Err("yield outside of a coroutine")
+	case vm.invoker != nil:
+		vm.Err("cannot yield across a native call")
+	}
+	vm.coroutine.value = vm.stack.Pop()
+	vm.coroutine.yielded = true
+}
+
+// coroutine Implements coroutine(fn), which creates a suspended coroutine running fn.
+func coroutine(vm *VM, argCount int) any {
+	args := vm.popArgs(argCount)
+	if argCount != 1 {
+		vm.Err(fmt.Sprintf("coroutine expects 1 argument, got %d", argCount))
+	}
+	co, err := vm.NewCoroutine(args[0])
+	if err != nil {
+		vm.fail(err.Error(), err)
+	}
+	return co
+}
+
+// resume Implements resume(co, values...), see VM.Resume.
+func resume(vm *VM, argCount int) any {
+	args := vm.popArgs(argCount)
+	if argCount == 0 {
+		vm.Err("resume expects a coroutine")
+	}
+	co, ok := args[0].(*Coroutine)
+	if !ok {
+		vm.Err(fmt.Sprintf("cannot resume non-coroutine %v", args[0]))
+	}
+	return vm.resume(co, args[1:])
+}
+
+// status Implements status(co), which returns the status of the coroutine as string, e.g. "suspended".
+func status(vm *VM, argCount int) any {
+	args := vm.popArgs(argCount)
+	if argCount != 1 {
+		vm.Err(fmt.Sprintf("status expects 1 argument, got %d", argCount))
+	}
+	co, ok := args[0].(*Coroutine)
+	if !ok {
+		vm.Err(fmt.Sprintf("cannot get the status of non-coroutine %v", args[0]))
+	}
+	return co.status.String()
+}
@@ -0,0 +1,78 @@
+package vm_test
+
+import (
+	"script/compiler"
+	"script/vm"
+	"testing"
+)
+
+func TestResume(t *testing.T) {
+	text := []byte(`fn double(n) {
+  for , n != 0, {
+    n = yield(n * 2)
+  }
+  return "done"
+}
+`)
+	v := vm.New()
+	bc, _, err := compiler.CompileSource(text, v.Globals()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Execute(bc); err != nil {
+		t.Fatal(err)
+	}
+
+	fn, _ := v.Global("double")
+	co, err := v.NewCoroutine(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		arg  any
+		want any
+	}{
+		{2, int64(4)},
+		{int32(3), int64(6)},
+		{uint8(5), int64(10)},
+		{0, "done"},
+	} {
+		got, err := v.Resume(co, tt.arg)
+		if err != nil {
+			t.Fatalf("Resume(%v): %v", tt.arg, err)
+		}
+		if got != tt.want {
+			t.Errorf("Resume(%v) = %#v, want %#v", tt.arg, got, tt.want)
+		}
+	}
+	if co.Status() != vm.Dead {
+		t.Errorf("status is %v, want dead", co.Status())
+	}
+	if _, err := v.Resume(co); err == nil || err.(*vm.RuntimeError).Message != "cannot resume dead coroutine" {
+		t.Errorf("Resume of dead coroutine returned %v", err)
+	}
+}
+
+func TestResumeError(t *testing.T) {
+	text := []byte("fn fail(s) {\n  return s + 1\n}\n")
+	v := vm.New()
+	bc, _, err := compiler.CompileSource(text, v.Globals()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Execute(bc); err != nil {
+		t.Fatal(err)
+	}
+
+	fn, _ := v.Global("fail")
+	co, _ := v.NewCoroutine(fn)
+	if _, err := v.Resume(co, "a"); err == nil {
+		t.Fatal("no error")
+	}
+	if co.Status() != vm.Dead {
+		t.Errorf("status is %v, want dead", co.Status())
+	}
+	if _, err := v.NewCoroutine(1); err == nil {
+		t.Error("NewCoroutine(1) returned no error")
+	}
+}
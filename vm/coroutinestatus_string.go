@@ -0,0 +1,27 @@
+// Code generated by "stringer -type=CoroutineStatus -linecomment"; DO NOT EDIT.
+
+package vm
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[Suspended-0]
+	_ = x[Running-1]
+	_ = x[Normal-2]
+	_ = x[Dead-3]
+}
+
+const _CoroutineStatus_name = "suspendedrunningnormaldead"
+
+var _CoroutineStatus_index = [...]uint8{0, 9, 16, 22, 26}
+
+func (i CoroutineStatus) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_CoroutineStatus_index)-1 {
+		return "CoroutineStatus(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _CoroutineStatus_name[_CoroutineStatus_index[idx]:_CoroutineStatus_index[idx+1]]
+}
@@ -0,0 +1,337 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"script"
+	"sort"
+)
+
+//go:generate stringer -type=StopReason
+type StopReason uint8
+
+const (
+	// StopBreakpoint means the execution reached a breakpoint.
+	StopBreakpoint StopReason = iota
+	// StopStep means a step finished.
+	StopStep
+	// StopException means the execution failed. The state of the VM is kept for inspection, but it cannot be resumed.
+	StopException
+	// StopExited means the program ended.
+	StopExited
+)
+
+// Breakpoint is set on a source line or on an instruction.
+type Breakpoint struct {
+	ID int
+	// Line is the source line of a line breakpoint, or 0.
+	Line int
+	// Pointer is the instruction of an instruction breakpoint, or -1.
+	Pointer int
+	// Hits counts how often the execution stopped at the breakpoint.
+	Hits int
+}
+
+// Debugger controls the execution of a program. Execution advances in Continue and the step methods, which return
+// once the program stops. In between, the stack and frames of the VM may be inspected.
+//
+// Line based features, such as line breakpoints and stepping over statements, need the source the program was
+// compiled from, see SetSource. Stepping considers instructions without a position part of the previous line.
+type Debugger struct {
+	vm *VM
+
+	breakpoints []*Breakpoint
+	nextID      int
+
+	// lines holds the line last executed at every call depth.
+	lines []int
+	depth int
+
+	started, finished bool
+	reason            StopReason
+	err               error
+	hit               *Breakpoint
+}
+
+// Debug Prepares the execution of bc under the control of the returned debugger. The program starts running with the
+// first call to Continue or one of the step methods.
+func (vm *VM) Debug(bc Bytecode) *Debugger {
+	vm.start(bc)
+	return &Debugger{vm: vm, lines: []int{0}}
+}
+
+// SetSource Sets the source the program was compiled from, see VM.SetSource. It enables line breakpoints and line
+// stepping.
+func (d *Debugger) SetSource(src *script.Source) {
+	d.vm.SetSource(src)
+}
+
+// Source Returns the source set with SetSource, or nil.
+func (d *Debugger) Source() *script.Source {
+	return d.vm.source
+}
+
+// BreakInstruction Sets a breakpoint on the instruction with the given index.
+func (d *Debugger) BreakInstruction(pointer int) (*Breakpoint, error) {
+	if pointer < 0 || pointer >= len(d.vm.bc) {
+		return nil, fmt.Errorf("no instruction %d", pointer)
+	}
+	return d.add(&Breakpoint{Pointer: pointer}), nil
+}
+
+// BreakLine Sets a breakpoint on a source line. If no code starts on the line, the breakpoint is moved to the next line
+// with code. The line of the returned breakpoint is the one it was set on.
+func (d *Debugger) BreakLine(line int) (*Breakpoint, error) {
+	if d.vm.source == nil {
+		return nil, errors.New("no source to set line breakpoints")
+	}
+
+	actual := 0
+	for _, instr := range d.vm.bc {
+		if l := d.lineOf(instr); l >= line && (actual == 0 || l < actual) {
+			actual = l
+		}
+	}
+	if actual == 0 {
+		return nil, fmt.Errorf("no code at or after line %d", line)
+	}
+	return d.add(&Breakpoint{Line: actual, Pointer: -1}), nil
+}
+
+func (d *Debugger) add(b *Breakpoint) *Breakpoint {
+	d.nextID++
+	b.ID = d.nextID
+	d.breakpoints = append(d.breakpoints, b)
+	return b
+}
+
+// Clear Removes the breakpoint with the given id and reports whether it existed.
+func (d *Debugger) Clear(id int) bool {
+	for i, b := range d.breakpoints {
+		if b.ID == id {
+			d.breakpoints = append(d.breakpoints[:i], d.breakpoints[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// ClearAll Removes all breakpoints.
+func (d *Debugger) ClearAll() {
+	d.breakpoints = nil
+}
+
+// Breakpoints Returns the breakpoints ordered by id.
+func (d *Debugger) Breakpoints() []*Breakpoint {
+	breakpoints := append([]*Breakpoint(nil), d.breakpoints...)
+	sort.Slice(breakpoints, func(i, j int) bool {
+		return breakpoints[i].ID < breakpoints[j].ID
+	})
+	return breakpoints
+}
+
+// Hit Returns the breakpoint the execution stopped at, or nil if it stopped for another reason.
+func (d *Debugger) Hit() *Breakpoint {
+	return d.hit
+}
+
+// Continue Runs until a breakpoint is reached or the program ends.
+func (d *Debugger) Continue() (StopReason, error) {
+	return d.resume(false, func(line, depth int, newLine bool) bool {
+		return false
+	})
+}
+
+// StepInstruction Executes a single instruction.
+func (d *Debugger) StepInstruction() (StopReason, error) {
+	return d.resume(true, nil)
+}
+
+// StepIn Runs until the next line is reached, entering called functions.
+func (d *Debugger) StepIn() (StopReason, error) {
+	depth := d.depth
+	return d.resume(false, func(line, current int, newLine bool) bool {
+		return newLine || current != depth
+	})
+}
+
+// StepOver Runs until the next line of the current function is reached, or until the function returns.
+func (d *Debugger) StepOver() (StopReason, error) {
+	depth := d.depth
+	return d.resume(false, func(line, current int, newLine bool) bool {
+		return current < depth || current == depth && newLine
+	})
+}
+
+// StepOut Runs until the current function returns to its caller.
+func (d *Debugger) StepOut() (StopReason, error) {
+	depth := d.depth
+	return d.resume(false, func(line, current int, newLine bool) bool {
+		return current < depth
+	})
+}
+
+// resume Runs the program until a breakpoint is reached or done returns true. done is called before every instruction
+// that starts or continues a line, with its line, the call depth and whether the line differs from the one last
+// executed at this depth. If instruction is true, the execution stops before the next instruction instead.
+func (d *Debugger) resume(instruction bool, done func(line, depth int, newLine bool) bool) (StopReason, error) {
+	if d.finished {
+		return d.reason, d.err
+	}
+	d.hit = nil
+
+	stop := func() bool {
+		if b := d.breakpointAt(d.vm.pointer); b != nil {
+			b.Hits++
+			d.reason, d.hit = StopBreakpoint, b
+			return true
+		}
+		if instruction {
+			d.reason = StopStep
+			return true
+		}
+
+		instr := d.vm.bc[d.vm.pointer]
+		if instr.Op == FRAME || instr.Op == CALL {
+			// Part of the call expression, executed before the callee is entered.
+			return false
+		}
+
+		depth := d.vm.depth()
+		for len(d.lines) <= depth {
+			d.lines = append(d.lines, 0)
+		}
+		if depth > d.depth {
+			d.lines[depth] = 0
+		}
+		d.depth = depth
+
+		line := d.lineOf(instr)
+		newLine := line != 0 && line != d.lines[depth]
+		if line != 0 {
+			d.lines[depth] = line
+		}
+
+		if b := d.lineBreakpoint(line); b != nil && newLine {
+			b.Hits++
+			d.reason, d.hit = StopBreakpoint, b
+			return true
+		}
+		if done(line, depth, newLine) {
+			d.reason = StopStep
+			return true
+		}
+		return false
+	}
+
+	if !d.started {
+		d.started = true
+		if len(d.vm.bc) > 0 && stop() {
+			return d.reason, nil
+		}
+	}
+
+	stopped, err := d.vm.run(stop)
+	switch {
+	case err != nil:
+		d.reason, d.err, d.finished = StopException, err, true
+	case !stopped:
+		d.reason, d.finished = StopExited, true
+	}
+	return d.reason, err
+}
+
+func (d *Debugger) breakpointAt(pointer int) *Breakpoint {
+	for _, b := range d.breakpoints {
+		if b.Pointer == pointer {
+			return b
+		}
+	}
+	return nil
+}
+
+func (d *Debugger) lineBreakpoint(line int) *Breakpoint {
+	for _, b := range d.breakpoints {
+		if line != 0 && b.Line == line {
+			return b
+		}
+	}
+	return nil
+}
+
+// lineOf Returns the source line of instr, or 0 if it is unknown.
+func (d *Debugger) lineOf(instr Instr) int {
+	if d.vm.source == nil || !instr.Span.Known() {
+		return 0
+	}
+	return d.vm.source.Position(instr.Span.Start).Line
+}
+
+// Pointer Returns the index of the next instruction to execute.
+func (d *Debugger) Pointer() int {
+	return d.vm.pointer
+}
+
+// Instruction Returns the next instruction to execute. Its Op is INVALID after the program ended.
+func (d *Debugger) Instruction() Instr {
+	if d.vm.pointer < 0 || d.vm.pointer >= len(d.vm.bc) {
+		return Instr{Span: NoSpan}
+	}
+	return d.vm.bc[d.vm.pointer]
+}
+
+// Bytecode Returns the program being debugged.
+func (d *Debugger) Bytecode() Bytecode {
+	return d.vm.bc
+}
+
+// Position Returns the source position of the next instruction. It is the zero Position if it is unknown.
+func (d *Debugger) Position() script.Position {
+	return d.PositionAt(d.vm.pointer)
+}
+
+// PositionAt Returns the source position of the instruction with the given index. It is the zero Position if it is
+// unknown.
+func (d *Debugger) PositionAt(pointer int) script.Position {
+	return d.vm.positionAt(pointer)
+}
+
+// Depth Returns the number of calls in progress.
+func (d *Debugger) Depth() int {
+	return d.vm.depth()
+}
+
+// Stack Returns the values on the stack, the top of the stack last.
+func (d *Debugger) Stack() []any {
+	return append([]any(nil), d.vm.stack.Array[:d.vm.stack.Cursor+1]...)
+}
+
+// Frames Returns the chain of frames from the current frame to the global frame.
+func (d *Debugger) Frames() []*Frame {
+	frames := make([]*Frame, 0)
+	for f := d.vm.cframe; f != nil; f = f.Parent {
+		frames = append(frames, f)
+	}
+	return frames
+}
+
+// Lookup Returns the value of the variable name visible in the current frame and whether it is declared.
+func (d *Debugger) Lookup(name string) (any, bool) {
+	return d.vm.cframe.Lookup(name)
+}
+
+// depth Returns the number of call frames in the frame chain.
+func (vm *VM) depth() int {
+	depth := 0
+	for f := vm.cframe; f != nil; f = f.Parent {
+		if f.IsCall() {
+			depth++
+		}
+	}
+	return depth
+}
+
+// Calls Returns the calls in progress, innermost first. The last one is the program itself, named "main".
+func (d *Debugger) Calls() []Call {
+	return d.vm.calls()
+}
@@ -0,0 +1,65 @@
+package vm
+
+// deferredCall is a call deferred by DEFER, with its evaluated callee and arguments.
+type deferredCall struct {
+	callee any
+	args   []any
+}
+
+// deferCall Adds the call on the stack to the deferred calls of the current call frame.
+func (vm *VM) deferCall() {
+	callee := vm.stack.Pop()
+	args := vm.popArgs(int(vm.stack.Pop().(int64)))
+	f, _ := vm.cframe.End()
+	f.deferred = append(f.deferred, deferredCall{callee: callee, args: args})
+}
+
+// runDeferred Makes the calls deferred in the call frame f, last deferred first. The remaining calls are made even if
+// one of them fails, the last error is returned.
+func (vm *VM) runDeferred(f *Frame) (err *RuntimeError) {
+	for len(f.deferred) > 0 {
+		d := f.deferred[len(f.deferred)-1]
+		f.deferred = f.deferred[:len(f.deferred)-1]
+		if callErr := vm.callDeferred(f, d); callErr != nil {
+			err = callErr
+		}
+	}
+	return err
+}
+
+func (vm *VM) callDeferred(f *Frame, d deferredCall) (err *RuntimeError) {
+	cframe := vm.cframe
+	defer func() {
+		vm.cframe = cframe
+		if r := recover(); r != nil {
+			runtimeErr, ok := r.(*RuntimeError)
+			if !ok {
+				panic(r)
+			}
+			err = runtimeErr
+		}
+	}()
+
+	vm.cframe = f
+	vm.invoke(d.callee, d.args)
+	return nil
+}
+
+// unwind Makes the deferred calls of the functions err leaves, up to the frame of the handler catching it. An error
+// raised by a deferred call replaces err.
+func (vm *VM) unwind(err *RuntimeError) *RuntimeError {
+	target := vm.invoker
+	vm.dropHandlers()
+	if len(vm.handlers) > vm.handlerBase {
+		target = vm.handlers[len(vm.handlers)-1].frame
+	}
+
+	for f := vm.cframe; f != nil && f != target; f = f.Parent {
+		if len(f.deferred) > 0 {
+			if callErr := vm.runDeferred(f); callErr != nil {
+				err = callErr
+			}
+		}
+	}
+	return err
+}
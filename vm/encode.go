@@ -0,0 +1,271 @@
+package vm
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"script"
+	"script/decimal"
+)
+
+// The binary bytecode format starts with the magic, followed by the format version and the source, which is a flag
+// and, if it is set, the file name and the text. The instructions follow as count and list of opcode, argument and
+// span. Integers are varints, strings, big integers and decimals in decimal notation and the source text are length
+// prefixed.
+const (
+	magic         = "ysbc"
+	formatVersion = 1
+)
+
+// Argument tags of the binary format.
+const (
+	argNil byte = iota
+	argInt
+	argFloat
+	argBool
+	argString
+	argFunc
+	argJumpTable
+	argInt64
+	argBigInt
+	argDecimal
+)
+
+var ErrInvalidBytecode = errors.New("invalid bytecode")
+
+// Encode Writes bc in the binary bytecode format to w. The source spans of the instructions are kept. If src is not
+// nil, it is embedded so that errors of the decoded program can be reported with source positions.
+func Encode(w io.Writer, bc Bytecode, src *script.Source) error {
+	buf := []byte(magic)
+	buf = binary.AppendUvarint(buf, formatVersion)
+
+	if src != nil {
+		buf = append(buf, 1)
+		buf = appendString(buf, src.File)
+		buf = appendString(buf, string(src.Text))
+	} else {
+		buf = append(buf, 0)
+	}
+
+	buf = binary.AppendUvarint(buf, uint64(len(bc)))
+	for i, instr := range bc {
+		buf = append(buf, byte(instr.Op))
+		switch arg := instr.Arg.(type) {
+		case nil:
+			buf = append(buf, argNil)
+		case int:
+			buf = append(buf, argInt)
+			buf = binary.AppendVarint(buf, int64(arg))
+		case int64:
+			buf = append(buf, argInt64)
+			buf = binary.AppendVarint(buf, arg)
+		case *big.Int:
+			buf = append(buf, argBigInt)
+			buf = appendString(buf, arg.String())
+		case decimal.Decimal:
+			buf = append(buf, argDecimal)
+			buf = appendString(buf, arg.String())
+		case float64:
+			buf = append(buf, argFloat)
+			buf = binary.AppendUvarint(buf, math.Float64bits(arg))
+		case bool:
+			buf = append(buf, argBool)
+			buf = appendBool(buf, arg)
+		case string:
+			buf = append(buf, argString)
+			buf = appendString(buf, arg)
+		case Func:
+			buf = append(buf, argFunc)
+			buf = binary.AppendVarint(buf, int64(arg.Address))
+			buf = appendString(buf, arg.Name)
+			buf = binary.AppendUvarint(buf, uint64(len(arg.Params)))
+			for _, param := range arg.Params {
+				buf = appendString(buf, param)
+			}
+			buf = appendBool(buf, arg.Variadic)
+			buf = appendSpan(buf, arg.Span)
+		case JumpTable:
+			buf = append(buf, argJumpTable)
+			buf = binary.AppendVarint(buf, arg.Min)
+			buf = binary.AppendUvarint(buf, uint64(len(arg.Targets)))
+			for _, target := range arg.Targets {
+				buf = binary.AppendVarint(buf, int64(target))
+			}
+			buf = binary.AppendVarint(buf, int64(arg.Default))
+		default:
+			return fmt.Errorf("cannot encode argument %v of type %T in instruction %d", arg, arg, i)
+		}
+		buf = appendSpan(buf, instr.Span)
+	}
+
+	_, err := w.Write(buf)
+	return err
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+func appendBool(buf []byte, b bool) []byte {
+	if b {
+		return append(buf, 1)
+	}
+	return append(buf, 0)
+}
+
+func appendSpan(buf []byte, span Span) []byte {
+	buf = binary.AppendVarint(buf, int64(span.Start))
+	return binary.AppendVarint(buf, int64(span.End))
+}
+
+// Decode Reads bytecode written by Encode from r. The returned source is nil if none was embedded.
+func Decode(r io.Reader) (bc Bytecode, src *script.Source, err error) {
+	d := decoder{r: bufio.NewReader(r)}
+
+	if string(d.bytes(uint64(len(magic)))) != magic {
+		return nil, nil, fmt.Errorf("%w: not a bytecode file", ErrInvalidBytecode)
+	}
+	if version := d.uvarint(); d.err == nil && version != formatVersion {
+		return nil, nil, fmt.Errorf("%w: unsupported format version %d", ErrInvalidBytecode, version)
+	}
+
+	if d.byte() == 1 {
+		file := d.string()
+		text := d.string()
+		src = script.NewSource(file, []byte(text))
+	}
+
+	n := d.uvarint()
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		instr := Instr{Op: OpCode(d.byte())}
+		switch tag := d.byte(); tag {
+		case argNil:
+		case argInt:
+			instr.Arg = int(d.varint())
+		case argInt64:
+			instr.Arg = d.varint()
+		case argBigInt:
+			b, ok := new(big.Int).SetString(d.string(), 10)
+			if !ok && d.err == nil {
+				d.err = fmt.Errorf("%w: invalid big integer in instruction %d", ErrInvalidBytecode, i)
+			}
+			instr.Arg = b
+		case argDecimal:
+			v, err := decimal.Parse(d.string())
+			if err != nil && d.err == nil {
+				d.err = fmt.Errorf("%w: invalid decimal in instruction %d", ErrInvalidBytecode, i)
+			}
+			instr.Arg = v
+		case argFloat:
+			instr.Arg = math.Float64frombits(d.uvarint())
+		case argBool:
+			instr.Arg = d.byte() != 0
+		case argString:
+			instr.Arg = d.string()
+		case argFunc:
+			f := Func{Address: int(d.varint()), Name: d.string()}
+			for n := d.uvarint(); n > 0 && d.err == nil; n-- {
+				f.Params = append(f.Params, d.string())
+			}
+			f.Variadic = d.byte() != 0
+			f.Span = d.span()
+			instr.Arg = f
+		case argJumpTable:
+			t := JumpTable{Min: d.varint()}
+			for n := d.uvarint(); n > 0 && d.err == nil; n-- {
+				t.Targets = append(t.Targets, int(d.varint()))
+			}
+			t.Default = int(d.varint())
+			instr.Arg = t
+		default:
+			if d.err == nil {
+				d.err = fmt.Errorf("%w: unknown argument tag %d in instruction %d", ErrInvalidBytecode, tag, i)
+			}
+		}
+		instr.Span = d.span()
+		bc = append(bc, instr)
+	}
+
+	if d.err != nil {
+		return nil, nil, d.err
+	}
+	return bc, src, nil
+}
+
+// decoder reads the values of the binary format. After the first error, all reads return zero values and the error is
+// kept in err.
+type decoder struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (d *decoder) fail(err error) {
+	if d.err != nil {
+		return
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		err = fmt.Errorf("%w: unexpected end of data", ErrInvalidBytecode)
+	}
+	d.err = err
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil {
+		return 0
+	}
+	b, err := d.r.ReadByte()
+	if err != nil {
+		d.fail(err)
+	}
+	return b
+}
+
+// bytes Reads n bytes. The buffer grows while reading, so that a corrupt length does not allocate n bytes up front.
+func (d *decoder) bytes(n uint64) []byte {
+	if d.err != nil {
+		return nil
+	}
+	b, err := io.ReadAll(io.LimitReader(d.r, int64(min(n, math.MaxInt64))))
+	if err == nil && uint64(len(b)) < n {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		d.fail(err)
+	}
+	return b
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		d.fail(err)
+	}
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadVarint(d.r)
+	if err != nil {
+		d.fail(err)
+	}
+	return v
+}
+
+func (d *decoder) span() Span {
+	return Span{Start: int(d.varint()), End: int(d.varint())}
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes(d.uvarint()))
+}
@@ -0,0 +1,104 @@
+package vm_test
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"reflect"
+	"script"
+	"script/compiler"
+	"script/decimal"
+	"script/vm"
+	"testing"
+)
+
+func TestEncodeRoundTrip(t *testing.T) {
+	d, err := decimal.Parse("-12.50")
+	if err != nil {
+		t.Fatal(err)
+	}
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	bc := vm.Bytecode{
+		{Op: vm.PUSH, Span: vm.NoSpan},
+		{Op: vm.PUSH, Arg: int64(-42), Span: vm.Span{Start: 0, End: 3}},
+		{Op: vm.PUSH, Arg: 1.5, Span: vm.Span{Start: 4, End: 7}},
+		{Op: vm.PUSH, Arg: true},
+		{Op: vm.PUSH, Arg: "héllo\n"},
+		{Op: vm.PUSH, Arg: huge},
+		{Op: vm.PUSH, Arg: d},
+		{Op: vm.PUSH, Arg: vm.Func{Address: 9, Name: "f", Params: []string{"a", "rest"}, Variadic: true, Span: vm.Span{Start: 1, End: 20}}},
+		{Op: vm.SWITCH, Arg: vm.JumpTable{Min: -1, Targets: []int{3, 4, 5}, Default: 9}},
+		{Op: vm.JUMP, Arg: 2},
+	}
+	src := script.NewSource("test.ys", []byte("x := 1\n"))
+
+	var b bytes.Buffer
+	if err := vm.Encode(&b, bc, src); err != nil {
+		t.Fatal(err)
+	}
+	got, gotSrc, err := vm.Decode(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, bc) {
+		t.Errorf("got\n%s\nwant\n%s", got.String(), bc.String())
+	}
+	if gotSrc == nil || gotSrc.File != src.File || string(gotSrc.Text) != string(src.Text) {
+		t.Errorf("got source %+v, want %+v", gotSrc, src)
+	}
+
+	b.Reset()
+	if err := vm.Encode(&b, bc, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, gotSrc, err = vm.Decode(&b); err != nil || gotSrc != nil {
+		t.Errorf("got source %v and error %v without an embedded source", gotSrc, err)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	var b bytes.Buffer
+	if err := vm.Encode(&b, vm.Bytecode{{Op: vm.PUSH, Arg: "text"}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	valid := b.Bytes()
+
+	tests := map[string][]byte{
+		"empty":     nil,
+		"magic":     []byte("nope"),
+		"truncated": valid[:len(valid)-3],
+		"version":   append([]byte("ysbc"), 99),
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := vm.Decode(bytes.NewReader(data)); !errors.Is(err, vm.ErrInvalidBytecode) {
+				t.Errorf("got %v, want ErrInvalidBytecode", err)
+			}
+		})
+	}
+}
+
+// TestEncodeKeepsPositions Checks that a decoded program reports errors at the source position of the original.
+func TestEncodeKeepsPositions(t *testing.T) {
+	text := []byte("x := 1\ny := x / 0\n")
+	bc, _, err := compiler.CompileSource(text, vm.Builtins()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := vm.Encode(&b, compiler.Optimize(bc), script.NewSource("div.ys", text)); err != nil {
+		t.Fatal(err)
+	}
+	decoded, src, err := vm.Decode(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := vm.New()
+	v.SetSource(src)
+	err = v.Execute(decoded)
+	var runtimeErr *vm.RuntimeError
+	if !errors.As(err, &runtimeErr) || runtimeErr.Position.String() != "div.ys:2:6" {
+		t.Errorf("got %v, want a division by zero at div.ys:2:6", err)
+	}
+}
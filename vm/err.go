@@ -0,0 +1,47 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"script"
+)
+
+var ErrAssertion = errors.New("assertion failed")
+
+// RuntimeError is raised when the execution of bytecode fails.
+type RuntimeError struct {
+	// Pointer is the index of the failing instruction.
+	Pointer int
+	// Pos is the source position of the failing instruction, or -1 if unknown.
+	Pos int
+	// Position is the source position of the failing instruction. It is the zero Position if the VM has no source,
+	// see VM.SetSource.
+	Position script.Position
+	Message  string
+	Err      error
+	// Value is the value scripts catch the error with. It is the thrown value for throw statements, and the message
+	// otherwise.
+	Value any
+	// Trace holds the calls in progress when the error was raised, innermost last. The first one is the program
+	// itself. Native calls, such as a failing assert, are included.
+	Trace []Call
+
+	source *script.Source
+}
+
+// Error Returns the message prefixed with the position. If the error was raised inside a call, the traceback
+// precedes it.
+func (e *RuntimeError) Error() string {
+	msg := fmt.Sprintf("error at %d: %s", e.Pointer, e.Message)
+	if e.Position.Line > 0 {
+		msg = fmt.Sprintf("%s: %s", e.Position, e.Message)
+	}
+	if len(e.Trace) > 1 {
+		return e.Traceback() + msg
+	}
+	return msg
+}
+
+func (e *RuntimeError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,76 @@
+package vm
+
+import "fmt"
+
+// handler is an exception handler installed by TRY. It is removed by END_TRY, or when an exception is caught by it.
+type handler struct {
+	// address is the first instruction of the handler.
+	address int
+	// frame and cursor are the frame and the stack cursor at the TRY, restored when an exception is caught.
+	frame  *Frame
+	cursor int
+}
+
+// try Installs a handler at address. Handlers left behind by return, break and continue statements are removed
+// first.
+func (vm *VM) try(address int) {
+	vm.dropHandlers()
+	vm.handlers = append(vm.handlers, handler{address: address, frame: vm.cframe, cursor: vm.stack.Cursor})
+}
+
+func (vm *VM) endTry() {
+	vm.dropHandlers()
+	if len(vm.handlers) > 0 {
+		vm.handlers = vm.handlers[:len(vm.handlers)-1]
+	}
+}
+
+// dropHandlers Removes the handlers whose frame was left without END_TRY. A handler is active as long as its frame is
+// part of the current frame chain.
+func (vm *VM) dropHandlers() {
+	for len(vm.handlers) > 0 && !vm.active(vm.handlers[len(vm.handlers)-1].frame) {
+		vm.handlers = vm.handlers[:len(vm.handlers)-1]
+	}
+}
+
+func (vm *VM) active(frame *Frame) bool {
+	for f := vm.cframe; f != nil; f = f.Parent {
+		if f == frame {
+			return true
+		}
+	}
+	return false
+}
+
+// throw Raises v as exception. A *RuntimeError, as pushed for a handler, is raised again unchanged.
+func (vm *VM) throw(v any) {
+	if err, ok := v.(*RuntimeError); ok {
+		panic(err)
+	}
+	err := vm.runtimeError(fmt.Sprintf("uncaught exception: %s", Repr(v)), nil)
+	err.Value = v
+	panic(err)
+}
+
+// catch Passes err to the innermost active handler and reports whether there is one. The frame chain and the stack
+// are unwound to the state at the TRY, err is pushed and the execution continues at the handler.
+func (vm *VM) catch(err *RuntimeError) bool {
+	vm.dropHandlers()
+	if len(vm.handlers) <= vm.handlerBase {
+		return false
+	}
+
+	h := vm.handlers[len(vm.handlers)-1]
+	vm.handlers = vm.handlers[:len(vm.handlers)-1]
+	vm.cframe = h.frame
+	vm.stack.Cursor = h.cursor
+	vm.stack.Push(err)
+	vm.pointer = h.address
+	return true
+}
+
+// catchValue Replaces the *RuntimeError on top of the stack with the value scripts catch.
+func (vm *VM) catchValue() {
+	err := vm.stack.Pop().(*RuntimeError)
+	vm.stack.Push(err.Value)
+}
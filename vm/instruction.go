@@ -1,6 +1,10 @@
 package vm
 
-import "fmt"
+import (
+	"fmt"
+	"math/big"
+	"script/decimal"
+)
 
 //go:generate stringer -type=OpCode
 type OpCode uint8
@@ -16,9 +20,23 @@ const (
 	SUB
 	MUL
 	DIV
+	// MOD Replaces the two values on top of the stack with the remainder of their division, see Mod.
+	MOD
+	// POW Replaces the base and the exponent on top of the stack with the power, see Pow.
+	POW
+	// BIT_AND, BIT_OR and BIT_XOR Replace the two values on top of the stack with the result of the bitwise
+	// operation, see BitAnd.
+	BIT_AND
+	BIT_OR
+	BIT_XOR
+	// SHL and SHR Replace the value and the shift count on top of the stack with the shifted value, see Shl.
+	SHL
+	SHR
 
 	// NEG Negates the top of the stack.
 	NEG
+	// BIT_NOT Replaces the integer on top of the stack with its bitwise complement.
+	BIT_NOT
 
 	CMP
 	CMP_LT
@@ -42,6 +60,10 @@ const (
 	JUMP_T
 	// JUMP_F <index> Will jump to given index if the top of the stack is  false.
 	JUMP_F
+	// JUMP_NIL <index> Will jump to given index if the top of the stack is nil. The value is kept on the stack.
+	JUMP_NIL
+	// JUMP_NOT_NIL <index> Will jump to given index if the top of the stack is not nil. The value is kept on the stack.
+	JUMP_NOT_NIL
 	// Deprecated: Will be removed.
 	// JUMP_S Will jump to the index given on the top of the stack.
 	JUMP_S
@@ -60,20 +82,84 @@ const (
 	JUMP_B
 
 	ANCHOR
+	// RESCUE [skip] Restores the innermost frame marked by ANCHOR. skip is the number of anchors to skip first, for a
+	// labeled break or continue leaving inner loops.
 	RESCUE
 
 	// ARR_INIT Creates an array. Array size on top of stack.
 	ARR_INIT
 	// ARR_CR Creates an array. Array size on top of stack followed by elements.
 	ARR_CR
-	// ARR_ID Indexes into an array. TypeId will be pushed on top of stack.
+	// ARR_ID Indexes into an array, or looks up a string key in a host map. TypeId will be pushed on top of stack. An
+	// index out of bounds gives nil, or raises an error in strict mode.
 	ARR_ID
-	// ARR_V Sets an array element. TypeId on top of stack followed by index.
+	// ARR_V Sets an array element. TypeId on top of stack followed by index. In strict mode an index out of bounds
+	// raises an error, see VM.SetStrict.
 	ARR_V
 
 	PANIC
+
+	// TRY <address> Installs an exception handler at address. When an exception is raised, the frame and the stack of
+	// the TRY are restored, the handler is removed, the *RuntimeError is pushed and the execution continues at address.
+	TRY
+	// END_TRY Removes the handler installed last.
+	END_TRY
+	// THROW Raises the top of the stack as exception. A *RuntimeError is raised again as it is.
+	THROW
+	// CATCH Replaces the *RuntimeError on top of the stack with the value it was raised with.
+	CATCH
+	// DEFER Pops a callee, an argument count and the arguments like CALL, but defers the call until the enclosing
+	// function returns, or is left by an exception.
+	DEFER
+	// YIELD Suspends the running coroutine with the top of the stack as value. When it is resumed, the value passed to
+	// resume is pushed.
+	YIELD
+	// ITER Replaces the top of the stack with an Iterator over it.
+	ITER
+	// ITER_NEXT <withKey> Pops an Iterator and advances it. If there is an entry, its value, its key if withKey is set,
+	// and true are pushed, otherwise false.
+	ITER_NEXT
+	// RANGE Replaces the integers start and end on top of the stack with a Range.
+	RANGE
+	// MATCH_EQ Pops two values and pushes whether they are Equal. Unlike CMP, values of different types are unequal.
+	MATCH_EQ
+	// MATCH_LEN <n> Pops a value and pushes whether it is an array of length n.
+	MATCH_LEN
+	// MATCH_RANGE Pops an end, a start and a value and pushes whether the value is a number in start..end.
+	MATCH_RANGE
+	// SWITCH <JumpTable> Pops a value and jumps to its target in the table.
+	SWITCH
+	// SLICE Pops a high bound, a low bound and an array and pushes the slice array[low:high], see ArrayValue. A nil
+	// bound is omitted.
+	SLICE
 )
 
+// JumpTable is the argument of SWITCH. Integers from Min to Min+len(Targets)-1 jump to their entry in Targets, all
+// other values to Default. Big integers and decimals jump like the integer they are Equal to, as MATCH_EQ matches them.
+type JumpTable struct {
+	Min     int64
+	Targets []int
+	Default int
+}
+
+// Target Returns the address v jumps to.
+func (t JumpTable) Target(v any) int {
+	switch n := v.(type) {
+	case *big.Int:
+		if n.IsInt64() {
+			v = n.Int64()
+		}
+	case decimal.Decimal:
+		if i := n.Int(); i.IsInt64() && n.Cmp(decimal.FromBig(i)) == 0 {
+			v = i.Int64()
+		}
+	}
+	if i, ok := v.(int64); ok && i >= t.Min && uint64(i-t.Min) < uint64(len(t.Targets)) {
+		return t.Targets[i-t.Min]
+	}
+	return t.Default
+}
+
 type Bytecode []Instr
 
 func (bc *Bytecode) Append(instr Instr) {
@@ -88,8 +174,10 @@ func (bc *Bytecode) Len() int {
 	return len(*bc)
 }
 
+// Instruction Appends an instruction without a source span. The compiler sets the span once the node the instruction
+// belongs to is compiled.
 func (bc *Bytecode) Instruction(op OpCode, arg any) {
-	bc.Append(Instr{Op: op, Arg: arg})
+	bc.Append(Instr{Op: op, Arg: arg, Span: NoSpan})
 }
 
 func (bc *Bytecode) SetArg(index int, arg any) {
@@ -113,4 +201,30 @@ func (bc *Bytecode) String() string {
 type Instr struct {
 	Op  OpCode
 	Arg any
+	// Span is the source code the instruction was compiled from.
+	Span Span
+}
+
+// Span is a range of source code in rune offsets. End is exclusive.
+type Span struct {
+	Start, End int
+}
+
+// NoSpan is the span of instructions that do not stem from source code.
+var NoSpan = Span{Start: -1, End: -1}
+
+// Known Reports whether the span refers to source code.
+func (s Span) Known() bool {
+	return s.Start >= 0
+}
+
+// Union Returns the smallest span covering s and other. Unknown spans are ignored.
+func (s Span) Union(other Span) Span {
+	switch {
+	case !other.Known():
+		return s
+	case !s.Known():
+		return other
+	}
+	return Span{Start: min(s.Start, other.Start), End: max(s.End, other.End)}
 }
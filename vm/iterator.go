@@ -0,0 +1,166 @@
+package vm
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Iterable is implemented by host values that can be looped over with for-in.
+type Iterable interface {
+	// Iterate Returns a new iterator positioned before the first entry.
+	Iterate() Iterator
+}
+
+// Iterator steps through the entries of a collection. for key, value in c binds both parts of an entry, for value in c
+// only the value.
+type Iterator interface {
+	// Next Advances to the next entry and reports whether there is one.
+	Next() bool
+	// Entry Returns the key and the value of the current entry.
+	Entry() (key, value any)
+}
+
+// Range is the value of start..end, the integers from Start up to, but excluding, End.
+type Range struct {
+	Start, End int64
+}
+
+func (r Range) String() string {
+	return fmt.Sprintf("%d..%d", r.Start, r.End)
+}
+
+// Iterate Returns an iterator over the integers of the range. Keys count from 0.
+func (r Range) Iterate() Iterator {
+	return &rangeIterator{r: r, index: -1}
+}
+
+type rangeIterator struct {
+	r     Range
+	index int64
+}
+
+func (it *rangeIterator) Next() bool {
+	it.index++
+	return it.r.Start+it.index < it.r.End
+}
+
+func (it *rangeIterator) Entry() (any, any) {
+	return it.index, it.r.Start + it.index
+}
+
+// arrayIterator iterates over the indices and elements of an array.
+type arrayIterator struct {
+	array *ArrayValue
+	index int
+}
+
+func (it *arrayIterator) Next() bool {
+	it.index++
+	return it.index < it.array.Len()
+}
+
+func (it *arrayIterator) Entry() (any, any) {
+	return int64(it.index), it.array.elements[it.index]
+}
+
+// mapIterator iterates over the keys and values of a host map in key order.
+type mapIterator struct {
+	m     map[string]any
+	keys  []string
+	index int
+}
+
+func (it *mapIterator) Next() bool {
+	it.index++
+	return it.index < len(it.keys)
+}
+
+func (it *mapIterator) Entry() (any, any) {
+	key := it.keys[it.index]
+	return key, it.m[key]
+}
+
+// coroutineIterator resumes a coroutine for every entry. The values are the yielded values, the keys count from 0.
+// The value the coroutine returns ends the iteration and is not an entry.
+type coroutineIterator struct {
+	vm    *VM
+	co    *Coroutine
+	index int
+	value any
+}
+
+func (it *coroutineIterator) Next() bool {
+	if it.co.status == Dead {
+		return false
+	}
+	it.value = it.vm.resume(it.co, nil)
+	it.index++
+	return it.co.status != Dead
+}
+
+func (it *coroutineIterator) Entry() (any, any) {
+	return int64(it.index), it.value
+}
+
+// iterate Replaces the value on top of the stack with an iterator over it, see ITER.
+func (vm *VM) iterate() {
+	switch v := vm.stack.Pop().(type) {
+	case Iterable:
+		vm.stack.Push(v.Iterate())
+	case *ArrayValue:
+		vm.stack.Push(&arrayIterator{array: v, index: -1})
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		vm.stack.Push(&mapIterator{m: v, keys: keys, index: -1})
+	case *Coroutine:
+		vm.stack.Push(&coroutineIterator{vm: vm, co: v, index: -1})
+	default:
+		vm.Err(fmt.Sprintf("cannot iterate over %s", Repr(v)))
+	}
+}
+
+// next Advances the iterator on top of the stack, see ITER_NEXT. Entries of host maps and Iterables are converted like
+// the values of Declare.
+func (vm *VM) next(withKey bool) {
+	it := vm.stack.Pop().(Iterator)
+	if !it.Next() {
+		vm.stack.Push(false)
+		return
+	}
+	key, value := it.Entry()
+	vm.stack.Push(fromNative(value))
+	if withKey {
+		vm.stack.Push(fromNative(key))
+	}
+	vm.stack.Push(true)
+}
+
+// matchRange Replaces the value, the start and the end on top of the stack with whether the value is in start..end, see
+// MATCH_RANGE.
+func (vm *VM) matchRange() {
+	start, end := vm.popBinary()
+	startInt, endInt := start.(int64), end.(int64)
+	switch v := vm.stack.Pop().(type) {
+	case int64:
+		vm.stack.Push(v >= startInt && v < endInt)
+	case float64:
+		vm.stack.Push(v >= float64(startInt) && v < float64(endInt))
+	default:
+		vm.stack.Push(false)
+	}
+}
+
+// makeRange Replaces the start and the end on top of the stack with a Range, see RANGE.
+func (vm *VM) makeRange() {
+	start, end := vm.popBinary()
+	startInt, ok := start.(int64)
+	endInt, ok2 := end.(int64)
+	if !ok || !ok2 {
+		vm.Err(fmt.Sprintf("range expects integers, got %s..%s", Repr(start), Repr(end)))
+	}
+	vm.stack.Push(Range{Start: startInt, End: endInt})
+}
@@ -1,5 +1,7 @@
 package vm
 
+import "sort"
+
 func newFrame(parent *Frame) *Frame {
 	return &Frame{
 		Parent:   parent,
@@ -15,6 +17,12 @@ type Frame struct {
 	// end is the index of the instruction which invoked the function.
 	start, end int
 	anchor     bool
+	// called is set by CALL on the frame created by the preceding FRAME. callee is the name of the called function, see
+	// Call.Name. native is set for calls of external functions and type casts.
+	called, native bool
+	callee         string
+	// deferred holds the calls deferred by DEFER in a call frame, in the order of the DEFER instructions.
+	deferred []deferredCall
 }
 
 func (f *Frame) End() (*Frame, int) {
@@ -55,3 +63,33 @@ func (f *Frame) Get(name string) any {
 	}
 	return nil
 }
+
+// Lookup Returns the value of the variable name declared in f or one of its parents and whether it is declared.
+func (f *Frame) Lookup(name string) (any, bool) {
+	for ; f != nil; f = f.Parent {
+		if v, ok := f.Declared[name]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// Names Returns the sorted names of the variables declared directly in f.
+func (f *Frame) Names() []string {
+	names := make([]string, 0, len(f.Declared))
+	for name := range f.Declared {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsCall Reports whether f was created by FRAME for a function call, as opposed to a block or the global frame.
+func (f *Frame) IsCall() bool {
+	return f.end >= 0
+}
+
+// ReturnAddress Returns the index of the instruction a call frame returns to, or -1.
+func (f *Frame) ReturnAddress() int {
+	return f.end
+}
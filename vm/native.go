@@ -2,7 +2,9 @@ package vm
 
 import (
 	"fmt"
+	"math/big"
 	"reflect"
+	"script/decimal"
 )
 
 type NativeFunc func(vm *VM, argCount int) any
@@ -25,8 +27,14 @@ func newExternalCallback(f any) (NativeFunc, error) {
 		return nil, fmt.Errorf("%v is not a function", f)
 	}
 
-	if t.NumOut() > 1 {
-		return nil, fmt.Errorf("%v cannot return more than 1 value", f)
+	// A function may return a value, an error, or both. A non-nil error is raised as exception.
+	errorType := reflect.TypeOf((*error)(nil)).Elem()
+	returnsErr := t.NumOut() > 0 && t.Out(t.NumOut()-1) == errorType
+	switch {
+	case t.NumOut() > 2:
+		return nil, fmt.Errorf("%v cannot return more than 2 values", f)
+	case t.NumOut() == 2 && !returnsErr:
+		return nil, fmt.Errorf("%v must return an error as second value", f)
 	}
 
 	v := reflect.ValueOf(f)
@@ -41,32 +49,13 @@ func newExternalCallback(f any) (NativeFunc, error) {
 
 	for i := 0; i < numIn; i++ {
 		in := t.In(i)
-
-		var val any
-
-		if variadicIndex == i {
+		if i == variadicIndex {
 			if in.Kind() != reflect.Slice {
 				return nil, fmt.Errorf("variadic argument must be a slice")
 			}
-
-			val = reflect.New(in).Interface()
-		} else {
-			val = reflect.New(in).Elem().Interface()
-		}
-
-		vmType := TypeOf(val)
-
-		if vmType != Invalid {
-			types[i] = vmType
-			continue
-		}
-
-		switch in.Kind() {
-		case reflect.Func:
-			types[i] = Function
-		default:
-			return nil, fmt.Errorf("unsupported argument type at position %d: %v", i, in.Kind())
+			in = in.Elem()
 		}
+		types[i] = nativeType(in)
 	}
 
 	return func(vm *VM, argCount int) any {
@@ -96,12 +85,129 @@ func newExternalCallback(f any) (NativeFunc, error) {
 
 		values := make([]reflect.Value, len(args))
 
-		for i := 0; i < argCount; i++ {
-			values[i] = reflect.ValueOf(args[i])
+		for i := range args {
+			param := t.In(min(i, numIn-1))
+			if i >= numIn-1 && variadicIndex >= 0 {
+				param = param.Elem()
+			}
+			if args[i] == nil {
+				// nil is passed as the zero value of the parameter type.
+				values[i] = reflect.Zero(param)
+				continue
+			}
+			values[i] = vm.toNative(args[i], param)
+			if !values[i].Type().AssignableTo(param) {
+				vm.Err(fmt.Sprintf("cannot pass %v as %v", TypeOf(args[i]), param))
+			}
 		}
 
-		v.Call(values)
+		results := v.Call(values)
 
-		return nil
+		if returnsErr {
+			if err, _ := results[len(results)-1].Interface().(error); err != nil {
+				vm.fail(err.Error(), err)
+			}
+			results = results[:len(results)-1]
+		}
+		if len(results) == 0 {
+			return nil
+		}
+		return fromNative(results[0].Interface())
 	}, nil
 }
+
+// nativeType Returns the type arguments for a parameter of type t are cast to. Numbers are cast to int or float, and
+// converted to the type of the parameter when it is called, like the elements of arrays passed for slices. Interfaces
+// such as any take every value.
+func nativeType(t reflect.Type) TypeId {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Int
+	case reflect.Float32, reflect.Float64:
+		return Float
+	case reflect.Interface:
+		return Any
+	case reflect.Slice:
+		return Array
+	}
+	return TypeOf(reflect.New(t).Elem().Interface())
+}
+
+// toNative Returns the argument v for a parameter of type t. Integers and floats are converted to the numeric type of
+// the parameter, e.g. int or float32. Decimals are passed as number for numeric parameters, and as string, e.g.
+// "12.50", for strings and interfaces unless t is decimal.Decimal. Arrays are passed as []any sharing their elements,
+// or as a new slice of another type with elements cast like arguments.
+func (vm *VM) toNative(v any, t reflect.Type) reflect.Value {
+	if d, ok := v.(decimal.Decimal); ok && t != reflect.TypeOf(d) {
+		switch {
+		case t.Kind() == reflect.String || t.Kind() == reflect.Interface:
+			return reflect.ValueOf(d.String())
+		case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+			return reflect.ValueOf(d.Float64()).Convert(t)
+		case isNumber(t.Kind()) && d.Int().IsInt64():
+			return reflect.ValueOf(d.Int().Int64()).Convert(t)
+		}
+	}
+	if arr, ok := v.(*ArrayValue); ok && t != reflect.TypeOf(arr) {
+		if t.Kind() != reflect.Slice || t == reflect.TypeOf(arr.elements) {
+			return reflect.ValueOf(arr.elements)
+		}
+		slice := reflect.MakeSlice(t, arr.Len(), arr.Len())
+		for i, e := range arr.elements {
+			if e != nil {
+				slice.Index(i).Set(vm.nativeElement(e, t.Elem()))
+			}
+		}
+		return slice
+	}
+	value := reflect.ValueOf(v)
+	if value.Type() != t && isNumber(value.Kind()) && isNumber(t.Kind()) {
+		return value.Convert(t)
+	}
+	return value
+}
+
+// nativeElement Returns the element e of an array passed for a slice with elements of type t. It is cast with the
+// rules of the arguments, and raises an error if it does not fit.
+func (vm *VM) nativeElement(e any, t reflect.Type) reflect.Value {
+	c, err := convert(e, nativeType(t))
+	if err == nil {
+		if value := vm.toNative(c, t); value.Type().AssignableTo(t) {
+			return value
+		}
+	}
+	vm.Err(fmt.Sprintf("cannot pass %v as %v", TypeOf(e), t))
+	return reflect.Value{}
+}
+
+// fromNative Returns the value v returned by a host function as script value. Integers become int64, floats float64,
+// a nil *big.Int nil and slices arrays. A []any is kept by the array, other slices are copied.
+func fromNative(v any) any {
+	if elements, ok := v.([]any); ok {
+		return NewArray(elements)
+	}
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Slice:
+		elements := make([]any, value.Len())
+		for i := range elements {
+			elements[i] = fromNative(value.Index(i).Interface())
+		}
+		return NewArray(elements)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		return value.Float()
+	}
+	if b, ok := v.(*big.Int); ok && b == nil {
+		return nil
+	}
+	return v
+}
+
+func isNumber(kind reflect.Kind) bool {
+	return kind >= reflect.Int && kind <= reflect.Float64
+}
@@ -15,42 +15,67 @@ func _() {
 	_ = x[SUB-4]
 	_ = x[MUL-5]
 	_ = x[DIV-6]
-	_ = x[NEG-7]
-	_ = x[CMP-8]
-	_ = x[CMP_LT-9]
-	_ = x[CMP_GT-10]
-	_ = x[CMP_LTE-11]
-	_ = x[CMP_GTE-12]
-	_ = x[NOT-13]
-	_ = x[DECLARE-14]
-	_ = x[STORE-15]
-	_ = x[LOAD-16]
-	_ = x[JUMP-17]
-	_ = x[JUMP_T-18]
-	_ = x[JUMP_F-19]
-	_ = x[JUMP_S-20]
-	_ = x[ENTER-21]
-	_ = x[LEAVE-22]
-	_ = x[CALL-23]
-	_ = x[FRAME-24]
-	_ = x[RET-25]
-	_ = x[JUMP_B-26]
-	_ = x[ANCHOR-27]
-	_ = x[RESCUE-28]
-	_ = x[ARR_INIT-29]
-	_ = x[ARR_CR-30]
-	_ = x[ARR_ID-31]
-	_ = x[ARR_V-32]
-	_ = x[PANIC-33]
+	_ = x[MOD-7]
+	_ = x[POW-8]
+	_ = x[BIT_AND-9]
+	_ = x[BIT_OR-10]
+	_ = x[BIT_XOR-11]
+	_ = x[SHL-12]
+	_ = x[SHR-13]
+	_ = x[NEG-14]
+	_ = x[BIT_NOT-15]
+	_ = x[CMP-16]
+	_ = x[CMP_LT-17]
+	_ = x[CMP_GT-18]
+	_ = x[CMP_LTE-19]
+	_ = x[CMP_GTE-20]
+	_ = x[NOT-21]
+	_ = x[DECLARE-22]
+	_ = x[STORE-23]
+	_ = x[LOAD-24]
+	_ = x[JUMP-25]
+	_ = x[JUMP_T-26]
+	_ = x[JUMP_F-27]
+	_ = x[JUMP_NIL-28]
+	_ = x[JUMP_NOT_NIL-29]
+	_ = x[JUMP_S-30]
+	_ = x[ENTER-31]
+	_ = x[LEAVE-32]
+	_ = x[CALL-33]
+	_ = x[FRAME-34]
+	_ = x[RET-35]
+	_ = x[JUMP_B-36]
+	_ = x[ANCHOR-37]
+	_ = x[RESCUE-38]
+	_ = x[ARR_INIT-39]
+	_ = x[ARR_CR-40]
+	_ = x[ARR_ID-41]
+	_ = x[ARR_V-42]
+	_ = x[PANIC-43]
+	_ = x[TRY-44]
+	_ = x[END_TRY-45]
+	_ = x[THROW-46]
+	_ = x[CATCH-47]
+	_ = x[DEFER-48]
+	_ = x[YIELD-49]
+	_ = x[ITER-50]
+	_ = x[ITER_NEXT-51]
+	_ = x[RANGE-52]
+	_ = x[MATCH_EQ-53]
+	_ = x[MATCH_LEN-54]
+	_ = x[MATCH_RANGE-55]
+	_ = x[SWITCH-56]
+	_ = x[SLICE-57]
 }
 
-const _OpCode_name = "INVALIDPUSHPOPADDSUBMULDIVNEGCMPCMP_LTCMP_GTCMP_LTECMP_GTENOTDECLARESTORELOADJUMPJUMP_TJUMP_FJUMP_SENTERLEAVECALLFRAMERETJUMP_BANCHORRESCUEARR_INITARR_CRARR_IDARR_VPANIC"
+const _OpCode_name = "INVALIDPUSHPOPADDSUBMULDIVMODPOWBIT_ANDBIT_ORBIT_XORSHLSHRNEGBIT_NOTCMPCMP_LTCMP_GTCMP_LTECMP_GTENOTDECLARESTORELOADJUMPJUMP_TJUMP_FJUMP_NILJUMP_NOT_NILJUMP_SENTERLEAVECALLFRAMERETJUMP_BANCHORRESCUEARR_INITARR_CRARR_IDARR_VPANICTRYEND_TRYTHROWCATCHDEFERYIELDITERITER_NEXTRANGEMATCH_EQMATCH_LENMATCH_RANGESWITCHSLICE"
 
-var _OpCode_index = [...]uint8{0, 7, 11, 14, 17, 20, 23, 26, 29, 32, 38, 44, 51, 58, 61, 68, 73, 77, 81, 87, 93, 99, 104, 109, 113, 118, 121, 127, 133, 139, 147, 153, 159, 164, 169}
+var _OpCode_index = [...]uint16{0, 7, 11, 14, 17, 20, 23, 26, 29, 32, 39, 45, 52, 55, 58, 61, 68, 71, 77, 83, 90, 97, 100, 107, 112, 116, 120, 126, 132, 140, 152, 158, 163, 168, 172, 177, 180, 186, 192, 198, 206, 212, 218, 223, 228, 231, 238, 243, 248, 253, 258, 262, 271, 276, 284, 293, 304, 310, 315}
 
 func (i OpCode) String() string {
-	if i >= OpCode(len(_OpCode_index)-1) {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_OpCode_index)-1 {
 		return "OpCode(" + strconv.FormatInt(int64(i), 10) + ")"
 	}
-	return _OpCode_name[_OpCode_index[i]:_OpCode_index[i+1]]
+	return _OpCode_name[_OpCode_index[idx]:_OpCode_index[idx+1]]
 }
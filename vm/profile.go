@@ -0,0 +1,80 @@
+package vm
+
+import (
+	"fmt"
+	"io"
+	"script"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Profile counts how often the instructions of a program are executed, see VM.Profile.
+type Profile struct {
+	// Counts holds the number of executions of every instruction, indexed like the bytecode.
+	Counts []int
+	vm     *VM
+	bc     Bytecode
+}
+
+// Profile Enables profiling of the following executions and returns the profile they fill in. Every execution
+// starts with fresh counts.
+func (vm *VM) Profile() *Profile {
+	vm.profile = &Profile{vm: vm}
+	return vm.profile
+}
+
+func (p *Profile) reset(bc Bytecode) {
+	p.bc = bc
+	p.Counts = make([]int, len(bc))
+}
+
+// Sample is the number of instructions executed for the code starting at a source position.
+type Sample struct {
+	// Position is the zero Position for instructions without a span, or if the VM has no source.
+	Position script.Position
+	Span     Span
+	Count    int
+}
+
+// Samples Returns the execution counts summed per source position, the most executed first.
+func (p *Profile) Samples() []Sample {
+	index := make(map[int]int)
+	samples := make([]Sample, 0)
+	for i, count := range p.Counts {
+		if count == 0 {
+			continue
+		}
+		span := p.bc[i].Span
+		if j, ok := index[span.Start]; ok {
+			samples[j].Span = samples[j].Span.Union(span)
+			samples[j].Count += count
+			continue
+		}
+		index[span.Start] = len(samples)
+		samples = append(samples, Sample{Position: p.vm.positionAt(i), Span: span, Count: count})
+	}
+
+	sort.SliceStable(samples, func(i, j int) bool {
+		if samples[i].Count != samples[j].Count {
+			return samples[i].Count > samples[j].Count
+		}
+		return samples[i].Span.Start < samples[j].Span.Start
+	})
+	return samples
+}
+
+// Write Writes a table of the samples to w. Every row shows the count, the position and the first line of the code.
+func (p *Profile) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "count\tposition\tcode")
+	for _, s := range p.Samples() {
+		position, code := "?", ""
+		if s.Position.Line > 0 {
+			position = s.Position.String()
+			code = strings.TrimSpace(p.vm.source.Line(s.Position.Line))
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", s.Count, position, code)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,71 @@
+package vm_test
+
+import (
+	"bytes"
+	"script"
+	"script/compiler"
+	"script/vm"
+	"strings"
+	"testing"
+)
+
+func TestProfile(t *testing.T) {
+	text := []byte("sum := 0\nfor i := 0, i < 100, i++ {\n    sum += i\n}\n")
+	bc, _, err := compiler.CompileSource(text, vm.Builtins()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := vm.New()
+	v.SetSource(script.NewSource("loop.ys", text))
+	p := v.Profile()
+	if err := v.Execute(bc); err != nil {
+		t.Fatal(err)
+	}
+
+	samples := p.Samples()
+	if len(samples) == 0 {
+		t.Fatal("no samples")
+	}
+	total := 0
+	for i, s := range samples {
+		total += s.Count
+		if i > 0 && s.Count > samples[i-1].Count {
+			t.Errorf("sample %d has a higher count than the one before", i)
+		}
+	}
+	counted := 0
+	for _, count := range p.Counts {
+		counted += count
+	}
+	if total != counted {
+		t.Errorf("samples sum up to %d, want %d", total, counted)
+	}
+	if top := samples[0]; top.Position.Line < 2 || top.Position.Line > 3 || top.Count < 100 {
+		t.Errorf("got %+v as the most executed code, want the loop", top)
+	}
+
+	var b bytes.Buffer
+	if err := p.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != len(samples)+1 || !strings.HasPrefix(lines[0], "count") {
+		t.Fatalf("got table\n%s", b.String())
+	}
+	if !strings.Contains(b.String(), "loop.ys:3:5") || !strings.Contains(b.String(), "sum += i") {
+		t.Errorf("the table does not show the loop body\n%s", b.String())
+	}
+
+	// Every execution starts with fresh counts.
+	if err := v.Execute(bc); err != nil {
+		t.Fatal(err)
+	}
+	again := 0
+	for _, count := range p.Counts {
+		again += count
+	}
+	if again != counted {
+		t.Errorf("got %d instructions executed the second time, want %d", again, counted)
+	}
+}
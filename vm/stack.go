@@ -33,8 +33,8 @@ func (s *Stack) Len() int {
 	return s.Cursor
 }
 
-func newStack() Stack {
-	return Stack{
+func newStack() *Stack {
+	return &Stack{
 		Array:  [MaxStackSize]any{},
 		Cursor: -1,
 	}
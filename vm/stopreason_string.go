@@ -0,0 +1,27 @@
+// Code generated by "stringer -type=StopReason"; DO NOT EDIT.
+
+package vm
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[StopBreakpoint-0]
+	_ = x[StopStep-1]
+	_ = x[StopException-2]
+	_ = x[StopExited-3]
+}
+
+const _StopReason_name = "StopBreakpointStopStepStopExceptionStopExited"
+
+var _StopReason_index = [...]uint8{0, 14, 22, 35, 45}
+
+func (i StopReason) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_StopReason_index)-1 {
+		return "StopReason(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _StopReason_name[_StopReason_index[idx]:_StopReason_index[idx+1]]
+}
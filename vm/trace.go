@@ -0,0 +1,83 @@
+package vm
+
+import (
+	"fmt"
+	"script"
+	"strings"
+)
+
+// Call is a function call in progress, see Debugger.Calls and RuntimeError.Trace.
+type Call struct {
+	// Name is the name of the called function. For anonymous functions it is the name of the called variable, or "" if
+	// the callee is not a variable.
+	Name string
+	// Native is set for calls of external functions, such as println, and type casts.
+	Native bool
+	// Pointer is the next instruction to execute in the call. For calls waiting for another call to return, it is the
+	// CALL instruction.
+	Pointer int
+	// Position is the source position of the instruction at Pointer. It is the zero Position for native calls, or if
+	// the source is unknown.
+	Position script.Position
+	// Frame is the innermost frame of the call. Its chain up to the call frame holds the local variables.
+	Frame *Frame
+}
+
+// calls Returns the calls in progress, innermost first. The last one is the program itself, named "main".
+func (vm *VM) calls() []Call {
+	calls := make([]Call, 0)
+	call := Call{Pointer: vm.pointer, Frame: vm.cframe}
+	for f := vm.cframe; f != nil; f = f.Parent {
+		if !f.IsCall() {
+			continue
+		}
+		if !f.called {
+			// FRAME was executed, but CALL was not yet.
+			call.Frame = f.Parent
+			continue
+		}
+		call.Name, call.Native = f.callee, f.native
+		if !f.native {
+			call.Position = vm.positionAt(call.Pointer)
+		}
+		calls = append(calls, call)
+		call = Call{Pointer: f.end - 1, Frame: f.Parent}
+	}
+	call.Name = "main"
+	call.Position = vm.positionAt(call.Pointer)
+	return append(calls, call)
+}
+
+// trace Returns the calls in progress, innermost last.
+func (vm *VM) trace() []Call {
+	calls := vm.calls()
+	for i, j := 0, len(calls)-1; i < j; i, j = i+1, j-1 {
+		calls[i], calls[j] = calls[j], calls[i]
+	}
+	return calls
+}
+
+// Traceback Formats the trace of the error, innermost call last. Every call shows its position and, if the source is
+// known, the line it is executing.
+func (e *RuntimeError) Traceback() string {
+	var sb strings.Builder
+	sb.WriteString("Traceback (most recent call last):\n")
+	for _, call := range e.Trace {
+		name := call.Name
+		if name == "" {
+			name = "<anonymous>"
+		}
+		switch {
+		case call.Native:
+			fmt.Fprintf(&sb, "  in %s (native)\n", name)
+		case call.Position.Line > 0:
+			fmt.Fprintf(&sb, "  %s, in %s\n", call.Position, name)
+			if e.source != nil {
+				fmt.Fprintf(&sb, "    %s\n", strings.TrimSpace(e.source.Line(call.Position.Line)))
+			}
+		default:
+			fmt.Fprintf(&sb, "  instruction %d, in %s\n", call.Pointer, name)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,102 @@
+package vm_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"script"
+	"script/compiler"
+	"script/vm"
+	"strings"
+	"testing"
+)
+
+// execute Compiles and executes text as file and returns the runtime error.
+func execute(t *testing.T, file, text string) *vm.RuntimeError {
+	t.Helper()
+	v := vm.New()
+	v.SetOutput(new(bytes.Buffer))
+	bc, _, err := compiler.CompileSource([]byte(text), v.Globals()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.SetSource(script.NewSource(file, []byte(text)))
+	var runtimeErr *vm.RuntimeError
+	if err := v.Execute(bc); !errors.As(err, &runtimeErr) {
+		t.Fatalf("got %v, want a runtime error", err)
+	}
+	return runtimeErr
+}
+
+func TestTraceback(t *testing.T) {
+	err := execute(t, "avg.ys", `fn divide(a, b) {
+  return a / b
+}
+fn average(xs) {
+  return divide(xs[0] + xs[1], len(xs) - 2)
+}
+println(average([1, 2]))
+`)
+	want := `Traceback (most recent call last):
+  avg.ys:7:9, in main
+    println(average([1, 2]))
+  avg.ys:5:10, in average
+    return divide(xs[0] + xs[1], len(xs) - 2)
+  avg.ys:2:10, in divide
+    return a / b
+avg.ys:2:10: division by zero`
+	if got := err.Error(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	names := make([]string, len(err.Trace))
+	for i, call := range err.Trace {
+		names[i] = fmt.Sprintf("%s %d:%d", call.Name, call.Position.Line, call.Position.Column)
+	}
+	if got := strings.Join(names, ", "); got != "main 7:9, average 5:10, divide 2:10" {
+		t.Errorf("got trace %s", got)
+	}
+}
+
+// TestTracebackNative Checks that calls of external functions and anonymous functions are part of the trace.
+func TestTracebackNative(t *testing.T) {
+	err := execute(t, "check.ys", `check := fn (x) {
+  assert(x > 0, "x is positive")
+}
+apply := fn (f, x) {
+  f(x)
+}
+apply(check, 1)
+apply(fn (x) { check(-x) }, 2)
+`)
+	want := `Traceback (most recent call last):
+  check.ys:8:1, in main
+    apply(fn (x) { check(-x) }, 2)
+  check.ys:5:3, in apply
+    f(x)
+  check.ys:8:16, in f
+    apply(fn (x) { check(-x) }, 2)
+  check.ys:2:3, in check
+    assert(x > 0, "x is positive")
+  in assert (native)
+check.ys:2:3: assertion failed: x is positive`
+	if got := err.Error(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if last := err.Trace[len(err.Trace)-1]; !last.Native || last.Name != "assert" || last.Position.Line != 0 {
+		t.Errorf("got innermost call %+v, want the native assert", last)
+	}
+	if !errors.Is(err, vm.ErrAssertion) {
+		t.Errorf("got %v, want an assertion error", err.Err)
+	}
+}
+
+func TestTracebackMain(t *testing.T) {
+	err := execute(t, "main.ys", "x := [1]\ny := x + 1\n")
+	if got, want := err.Error(), "main.ys:2:6: type mismatch: Array and Int"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if len(err.Trace) != 1 || err.Trace[0].Name != "main" {
+		t.Errorf("got trace %+v, want only main", err.Trace)
+	}
+}
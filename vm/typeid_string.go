@@ -14,18 +14,22 @@ func _() {
 	_ = x[Int-3]
 	_ = x[Float-4]
 	_ = x[Bool-5]
-	_ = x[Function-6]
-	_ = x[Array-7]
-	_ = x[ExternalFunction-8]
+	_ = x[String-6]
+	_ = x[Function-7]
+	_ = x[Array-8]
+	_ = x[ExternalFunction-9]
+	_ = x[BigInt-10]
+	_ = x[Decimal-11]
 }
 
-const _TypeId_name = "InvalidNilAnyIntFloatBoolFunctionArrayExternalFunction"
+const _TypeId_name = "InvalidNilAnyIntFloatBoolStringFunctionArrayExternalFunctionBigIntDecimal"
 
-var _TypeId_index = [...]uint8{0, 7, 10, 13, 16, 21, 25, 33, 38, 54}
+var _TypeId_index = [...]uint8{0, 7, 10, 13, 16, 21, 25, 31, 39, 44, 60, 66, 73}
 
 func (i TypeId) String() string {
-	if i >= TypeId(len(_TypeId_index)-1) {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_TypeId_index)-1 {
 		return "TypeId(" + strconv.FormatInt(int64(i), 10) + ")"
 	}
-	return _TypeId_name[_TypeId_index[i]:_TypeId_index[i+1]]
+	return _TypeId_name[_TypeId_index[idx]:_TypeId_index[idx+1]]
 }
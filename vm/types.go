@@ -3,6 +3,12 @@ package vm
 import (
 	"errors"
 	"fmt"
+	"math"
+	"math/big"
+	"script/decimal"
+	"slices"
+	"strconv"
+	"strings"
 )
 
 //go:generate stringer -type=TypeId
@@ -15,9 +21,12 @@ const (
 	Int
 	Float
 	Bool
+	String
 	Function
 	Array
 	ExternalFunction
+	BigInt
+	Decimal
 )
 
 func TypeOf(v any) TypeId {
@@ -25,15 +34,21 @@ func TypeOf(v any) TypeId {
 		return Nil
 	}
 	switch v.(type) {
-	case int:
+	case int64:
 		return Int
+	case *big.Int:
+		return BigInt
+	case decimal.Decimal:
+		return Decimal
 	case float64:
 		return Float
 	case bool:
 		return Bool
+	case string:
+		return String
 	case Func:
 		return Function
-	case []any:
+	case *ArrayValue:
 		return Array
 	case any:
 		return Any
@@ -51,45 +66,167 @@ func (t Type) String() string {
 	return fmt.Sprintf("<type %v>", t.Id)
 }
 
+// Func is a function compiled into the bytecode.
 type Func struct {
-	//Params []string
+	// Address is the index of the first instruction of the function.
 	Address int
+	// Name is the name of the variable the function was declared with, or "" for anonymous functions.
+	Name   string
+	Params []string
+	// Variadic is set if the last parameter collects the remaining arguments into an array.
+	Variadic bool
+	// Span is the source code of the function expression.
+	Span Span
 }
 
 type ExternalFunc struct {
 	Callback NativeFunc `json:"-"`
 }
 
+// String Returns the signature of the function, e.g. <fn fib(n)>. Variadic parameters end with dots.
 func (f Func) String() string {
-	return fmt.Sprintf("<func %d>", f.Address)
+	params := append([]string(nil), f.Params...)
+	if f.Variadic && len(params) > 0 {
+		params[len(params)-1] += "..."
+	}
+	return fmt.Sprintf("<fn %s(%s)>", f.Name, strings.Join(params, ", "))
+}
+
+// arityError Returns the error message of calling f with argCount arguments, or "" if the call is valid.
+func (f Func) arityError(argCount int) string {
+	name := f.Name
+	if name == "" {
+		name = "function"
+	}
+
+	expected, qualifier := len(f.Params), ""
+	if f.Variadic {
+		// The variadic parameter accepts an empty array.
+		expected, qualifier = len(f.Params)-1, "at least "
+		if argCount >= expected {
+			return ""
+		}
+	} else if argCount == expected {
+		return ""
+	}
+
+	noun := "arguments"
+	if expected == 1 {
+		noun = "argument"
+	}
+	return fmt.Sprintf("%s expects %s%d %s, got %d", name, qualifier, expected, noun, argCount)
 }
 
 var ErrTypeMismatch = errors.New("type mismatch")
 var ErrTypeOperationUnsupported = errors.New("operation is unsupported for type")
+var ErrDivisionByZero = errors.New("division by zero")
+var ErrNegativeShift = errors.New("negative shift count")
+var ErrIntegerOverflow = errors.New("integer overflow")
+
+// Integers are int64 values. Add, Sub, Mul, Div, Pow, Shl and Neg return ErrIntegerOverflow together with the wrapped
+// result if the result of two integers does not fit into an int64, see Overflow. Integers of any size are *big.Int
+// values of the type BigInt, which are never modified once created. Mixing integers and big integers gives a big
+// integer.
+
+// Decimals are decimal.Decimal values. Mixing decimals and integers or big integers gives a decimal, mixing them with
+// floats is a type mismatch.
+
+// decimalOperands Returns a and b as decimals if one of them is a decimal and the other one a decimal, an integer or a
+// big integer.
+func decimalOperands(a, b any) (decimal.Decimal, decimal.Decimal, bool) {
+	_, decimalA := a.(decimal.Decimal)
+	_, decimalB := b.(decimal.Decimal)
+	if !decimalA && !decimalB {
+		return decimal.Decimal{}, decimal.Decimal{}, false
+	}
+	x, ok := toDecimal(a)
+	y, ok2 := toDecimal(b)
+	return x, y, ok && ok2
+}
+
+// toDecimal Returns the decimal, integer or big integer v as decimal.
+func toDecimal(v any) (decimal.Decimal, bool) {
+	switch v := v.(type) {
+	case decimal.Decimal:
+		return v, true
+	case int64:
+		return decimal.New(v, 0), true
+	case *big.Int:
+		return decimal.FromBig(v), true
+	}
+	return decimal.Decimal{}, false
+}
+
+// bigOperands Returns a and b as big integers if one of them is a big integer and the other one an integer.
+func bigOperands(a, b any) (*big.Int, *big.Int, bool) {
+	_, bigA := a.(*big.Int)
+	_, bigB := b.(*big.Int)
+	if !bigA && !bigB {
+		return nil, nil, false
+	}
+	x, ok := toBig(a)
+	y, ok2 := toBig(b)
+	return x, y, ok && ok2
+}
+
+// toBig Returns the integer or big integer v as big integer.
+func toBig(v any) (*big.Int, bool) {
+	switch v := v.(type) {
+	case int64:
+		return big.NewInt(v), true
+	case *big.Int:
+		return v, true
+	}
+	return nil, false
+}
 
 func Add(a, b any) (any, error) {
+	if x, y, ok := decimalOperands(a, b); ok {
+		return x.Add(y), nil
+	}
+	if x, y, ok := bigOperands(a, b); ok {
+		return new(big.Int).Add(x, y), nil
+	}
 	t := TypeOf(a)
 	if t != TypeOf(b) {
 		return nil, ErrTypeMismatch
 	}
 	switch t {
 	case Int:
-		return a.(int) + b.(int), nil
+		x, y := a.(int64), b.(int64)
+		sum := x + y
+		if (x^sum)&(y^sum) < 0 {
+			return sum, ErrIntegerOverflow
+		}
+		return sum, nil
 	case Float:
 		return a.(float64) + b.(float64), nil
+	case String:
+		return a.(string) + b.(string), nil
 	default:
 		return nil, ErrTypeOperationUnsupported
 	}
 }
 
 func Sub(a, b any) (any, error) {
+	if x, y, ok := decimalOperands(a, b); ok {
+		return x.Sub(y), nil
+	}
+	if x, y, ok := bigOperands(a, b); ok {
+		return new(big.Int).Sub(x, y), nil
+	}
 	t := TypeOf(a)
 	if t != TypeOf(b) {
 		return nil, ErrTypeMismatch
 	}
 	switch t {
 	case Int:
-		return a.(int) - b.(int), nil
+		x, y := a.(int64), b.(int64)
+		difference := x - y
+		if (x^y)&(x^difference) < 0 {
+			return difference, ErrIntegerOverflow
+		}
+		return difference, nil
 	case Float:
 		return a.(float64) - b.(float64), nil
 	default:
@@ -98,13 +235,23 @@ func Sub(a, b any) (any, error) {
 }
 
 func Mul(a, b any) (any, error) {
+	if x, y, ok := decimalOperands(a, b); ok {
+		return x.Mul(y), nil
+	}
+	if x, y, ok := bigOperands(a, b); ok {
+		return new(big.Int).Mul(x, y), nil
+	}
 	t := TypeOf(a)
 	if t != TypeOf(b) {
 		return nil, ErrTypeMismatch
 	}
 	switch t {
 	case Int:
-		return a.(int) * b.(int), nil
+		product, overflow := mulInt(a.(int64), b.(int64))
+		if overflow {
+			return product, ErrIntegerOverflow
+		}
+		return product, nil
 	case Float:
 		return a.(float64) * b.(float64), nil
 	default:
@@ -112,14 +259,45 @@ func Mul(a, b any) (any, error) {
 	}
 }
 
+// mulInt Returns the wrapped product of x and y and whether it overflowed.
+func mulInt(x, y int64) (int64, bool) {
+	product := x * y
+	if x == 0 || y == 0 {
+		return 0, false
+	}
+	overflow := product/y != x || (x == -1 && y == math.MinInt64) || (y == -1 && x == math.MinInt64)
+	return product, overflow
+}
+
+// Div Returns a / b. The quotient of integers is truncated towards zero, the one of decimals is rounded, see
+// decimal.Decimal.Quo.
 func Div(a, b any) (any, error) {
+	if x, y, ok := decimalOperands(a, b); ok {
+		if y.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return x.Quo(y)
+	}
+	if x, y, ok := bigOperands(a, b); ok {
+		if y.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return new(big.Int).Quo(x, y), nil
+	}
 	t := TypeOf(a)
 	if t != TypeOf(b) {
 		return nil, ErrTypeMismatch
 	}
 	switch t {
 	case Int:
-		return a.(int) / b.(int), nil
+		x, y := a.(int64), b.(int64)
+		if y == 0 {
+			return nil, ErrDivisionByZero
+		}
+		if x == math.MinInt64 && y == -1 {
+			return x, ErrIntegerOverflow
+		}
+		return x / y, nil
 	case Float:
 		return a.(float64) / b.(float64), nil
 	default:
@@ -127,11 +305,306 @@ func Div(a, b any) (any, error) {
 	}
 }
 
+// Mod Returns the remainder of a / b. The remainder of integers has the sign of a, like in Go, the remainder of floats
+// is math.Mod. Like for the other arithmetic operators, an integer and a float are a type mismatch, e.g. 7.5 % 2.
+func Mod(a, b any) (any, error) {
+	if x, y, ok := decimalOperands(a, b); ok {
+		if y.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return x.Rem(y)
+	}
+	if x, y, ok := bigOperands(a, b); ok {
+		if y.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return new(big.Int).Rem(x, y), nil
+	}
+	t := TypeOf(a)
+	if t != TypeOf(b) {
+		return nil, ErrTypeMismatch
+	}
+	switch t {
+	case Int:
+		if b.(int64) == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return a.(int64) % b.(int64), nil
+	case Float:
+		return math.Mod(a.(float64), b.(float64)), nil
+	default:
+		return nil, ErrTypeOperationUnsupported
+	}
+}
+
+// Pow Returns a raised to the power of b. Integers with a non-negative exponent give an integer, integers with a
+// negative exponent and floats give a float. Decimals may be raised to the power of an integer. Like for the other
+// arithmetic operators, an integer and a float are a type mismatch, e.g. 2 ** 0.5.
+func Pow(a, b any) (any, error) {
+	if x, ok := a.(decimal.Decimal); ok {
+		n, ok := b.(int64)
+		if !ok {
+			return nil, ErrTypeOperationUnsupported
+		}
+		if x.Sign() == 0 && n < 0 {
+			return nil, ErrDivisionByZero
+		}
+		return x.Pow(n)
+	}
+	if x, y, ok := bigOperands(a, b); ok {
+		if y.Sign() < 0 {
+			base, _ := new(big.Float).SetInt(x).Float64()
+			exponent, _ := new(big.Float).SetInt(y).Float64()
+			return math.Pow(base, exponent), nil
+		}
+		return new(big.Int).Exp(x, y, nil), nil
+	}
+	t := TypeOf(a)
+	if t != TypeOf(b) {
+		return nil, ErrTypeMismatch
+	}
+	switch t {
+	case Int:
+		base, exponent := a.(int64), b.(int64)
+		if exponent < 0 {
+			return math.Pow(float64(base), float64(exponent)), nil
+		}
+		result, overflow := int64(1), false
+		for ; exponent > 0; exponent >>= 1 {
+			var o bool
+			if exponent&1 == 1 {
+				result, o = mulInt(result, base)
+				overflow = overflow || o
+			}
+			if exponent > 1 {
+				base, o = mulInt(base, base)
+				overflow = overflow || o
+			}
+		}
+		if overflow {
+			return result, ErrIntegerOverflow
+		}
+		return result, nil
+	case Float:
+		return math.Pow(a.(float64), b.(float64)), nil
+	default:
+		return nil, ErrTypeOperationUnsupported
+	}
+}
+
+// The bitwise operators &, | and ^ take two integers, or two booleans for a logical operation that evaluates both
+// operands. Shifts take integers and big integers, ~ takes integers only. Floats are not supported by any of them.
+
+func BitAnd(a, b any) (any, error) {
+	t := TypeOf(a)
+	if t != TypeOf(b) {
+		return nil, ErrTypeMismatch
+	}
+	switch t {
+	case Int:
+		return a.(int64) & b.(int64), nil
+	case Bool:
+		return a.(bool) && b.(bool), nil
+	default:
+		return nil, ErrTypeOperationUnsupported
+	}
+}
+
+func BitOr(a, b any) (any, error) {
+	t := TypeOf(a)
+	if t != TypeOf(b) {
+		return nil, ErrTypeMismatch
+	}
+	switch t {
+	case Int:
+		return a.(int64) | b.(int64), nil
+	case Bool:
+		return a.(bool) || b.(bool), nil
+	default:
+		return nil, ErrTypeOperationUnsupported
+	}
+}
+
+func BitXor(a, b any) (any, error) {
+	t := TypeOf(a)
+	if t != TypeOf(b) {
+		return nil, ErrTypeMismatch
+	}
+	switch t {
+	case Int:
+		return a.(int64) ^ b.(int64), nil
+	case Bool:
+		return a.(bool) != b.(bool), nil
+	default:
+		return nil, ErrTypeOperationUnsupported
+	}
+}
+
+// Shl Returns a shifted left by b bits. The shift count must not be negative. Shifting bits out of an integer,
+// including its sign bit, is an overflow.
+func Shl(a, b any) (any, error) {
+	if x, y, ok := bigOperands(a, b); ok {
+		n, err := bigShiftCount(y)
+		if err != nil {
+			return nil, err
+		}
+		if n > maxBigShift {
+			return nil, ErrIntegerOverflow
+		}
+		return new(big.Int).Lsh(x, n), nil
+	}
+	x, n, err := shiftOperands(a, b)
+	if err != nil {
+		return nil, err
+	}
+	shifted := x << n
+	if n >= 64 || shifted>>n != x {
+		return shifted, ErrIntegerOverflow
+	}
+	return shifted, nil
+}
+
+// Shr Returns a shifted right by b bits, keeping the sign. The shift count must not be negative.
+func Shr(a, b any) (any, error) {
+	if x, y, ok := bigOperands(a, b); ok {
+		n, err := bigShiftCount(y)
+		if err != nil {
+			return nil, err
+		}
+		// Shifting by the bit length or more leaves 0 or -1, larger counts give the same.
+		return new(big.Int).Rsh(x, min(n, uint(x.BitLen())+1)), nil
+	}
+	x, n, err := shiftOperands(a, b)
+	if err != nil {
+		return nil, err
+	}
+	return x >> n, nil
+}
+
+// maxBigShift is the largest count a big integer may be shifted left by, which keeps the result below 128 MiB.
+const maxBigShift = 1 << 30
+
+func shiftOperands(a, b any) (int64, uint, error) {
+	if TypeOf(a) != Int || TypeOf(b) != Int {
+		return 0, 0, ErrTypeOperationUnsupported
+	}
+	if b.(int64) < 0 {
+		return 0, 0, ErrNegativeShift
+	}
+	return a.(int64), uint(b.(int64)), nil
+}
+
+// bigShiftCount Returns the shift count y of a big integer. Counts too large for a uint give the largest uint.
+func bigShiftCount(y *big.Int) (uint, error) {
+	if y.Sign() < 0 {
+		return 0, ErrNegativeShift
+	}
+	if !y.IsUint64() || y.Uint64() > math.MaxUint {
+		return math.MaxUint, nil
+	}
+	return uint(y.Uint64()), nil
+}
+
+// BitNot Returns the bitwise complement of the integer a.
+func BitNot(a any) (any, error) {
+	if TypeOf(a) != Int {
+		return nil, ErrTypeOperationUnsupported
+	}
+	return ^a.(int64), nil
+}
+
+// Truthy Reports whether v counts as true in a condition. Only nil, false and a zero float are false.
+func Truthy(v any) bool {
+	if v == nil {
+		return false
+	}
+
+	switch t := v.(type) {
+	case bool:
+		return t
+	case float64:
+		// TODO remove this once booleans are implemented later
+		return t != 0
+	default:
+		return true
+	}
+}
+
+// Equal Reports whether a and b hold the same value. Arrays are compared element by element, integers, big integers
+// and decimals by their value.
+func Equal(a, b any) bool {
+	return equal(a, b, nil)
+}
+
+// arrayPair is a pair of arrays being compared by equal.
+type arrayPair struct {
+	a, b *ArrayValue
+}
+
+// equal Reports whether a and b are Equal. Arrays containing themselves are compared element by element until a pair
+// of arrays repeats, which is in outer. Such a pair is equal if all other elements are.
+func equal(a, b any, outer []arrayPair) bool {
+	if x, y, ok := decimalOperands(a, b); ok {
+		return x.Cmp(y) == 0
+	}
+	if x, y, ok := bigOperands(a, b); ok {
+		return x.Cmp(y) == 0
+	}
+	switch t := a.(type) {
+	case *ArrayValue:
+		other, ok := b.(*ArrayValue)
+		if !ok || t.Len() != other.Len() {
+			return false
+		}
+		pair := arrayPair{t, other}
+		if slices.Contains(outer, pair) {
+			return true
+		}
+		outer = append(outer, pair)
+		for i := range t.elements {
+			if !equal(t.elements[i], other.elements[i], outer) {
+				return false
+			}
+		}
+		return true
+	case ExternalFunc:
+		return false
+	case Func:
+		other, ok := b.(Func)
+		return ok && t.Address == other.Address
+	}
+	if _, ok := b.(ExternalFunc); ok {
+		return false
+	}
+	return a == b
+}
+
+// Repr Returns the representation of v used in error messages. Strings are quoted.
+func Repr(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return strconv.Quote(t)
+	case *ArrayValue:
+		return t.join(", ", Repr, nil)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
 func Neg(a any) (any, error) {
 	t := TypeOf(a)
 	switch t {
 	case Int:
-		return -a.(int), nil
+		if a.(int64) == math.MinInt64 {
+			return a, ErrIntegerOverflow
+		}
+		return -a.(int64), nil
+	case BigInt:
+		return new(big.Int).Neg(a.(*big.Int)), nil
+	case Decimal:
+		return a.(decimal.Decimal).Neg(), nil
 	case Float:
 		return -a.(float64), nil
 	default:
@@ -1,37 +1,173 @@
 package vm
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"runtime"
 	"script"
+	"script/decimal"
+	"sort"
 	"strings"
 )
 
 func New() *VM {
 	vm := &VM{
-		stack:  newStack(),
-		cframe: newFrame(nil),
+		context: context{
+			stack:  newStack(),
+			cframe: newFrame(nil),
+		},
+		out: os.Stdout,
 	}
 
-	vm.cframe.Declare("int", Type{Int})
-	vm.cframe.Declare("float", Type{Float})
-	vm.cframe.Declare("bool", Type{Bool})
-
-	vm.cframe.Declare("println", NewExternalFunc(func(v ...any) {
-		fmt.Println(v...)
-	}))
+	for name, v := range builtins(vm) {
+		vm.cframe.Declare(name, v)
+	}
 
 	return vm
 }
 
 type VM struct {
+	// context is the execution context of the main program, or of the running coroutine, see resume.
+	context
+	bc      Bytecode
+	out     io.Writer
+	source  *script.Source
+	profile *Profile
+	// overflow is what integer arithmetic does on overflow, see SetOverflow.
+	overflow Overflow
+	// strict is set if indexing an array out of bounds raises an error, see SetStrict.
+	strict bool
+}
+
+// Overflow is what integer arithmetic does if its result does not fit into an int64.
+type Overflow uint8
+
+const (
+	// OverflowWrap Wraps the result around, like Go does.
+	OverflowWrap Overflow = iota
+	// OverflowError Raises an integer overflow error.
+	OverflowError
+	// OverflowPromote Gives the exact result as big integer.
+	OverflowPromote
+)
+
+// context is the state of the execution of the main program or of a coroutine.
+type context struct {
 	cframe  *Frame
-	stack   Stack
+	stack   *Stack
 	pointer int
+	// handlers holds the installed exception handlers, innermost last. Only the ones starting at handlerBase catch
+	// exceptions, the others belong to the code waiting for invoke to return.
+	handlers    []handler
+	handlerBase int
+	// invoker is the frame of the innermost invoke call in progress, or nil. Exceptions do not unwind it.
+	invoker *Frame
+	// coroutine is the coroutine the context belongs to, or nil for the main program.
+	coroutine *Coroutine
 }
 
+// Declare Declares a global variable, e.g. a host function created with NewExternalFunc. Numbers are converted like the
+// results of host functions, e.g. an int to an int64.
+func (vm *VM) Declare(name string, v any) {
+	vm.global().Declare(name, fromNative(v))
+}
+
+// Globals Returns the sorted names of all global variables, including builtins.
+func (vm *VM) Globals() []string {
+	names := make([]string, 0, len(vm.global().Declared))
+	for name := range vm.global().Declared {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Global Returns the value of the global variable name and whether it is declared.
+func (vm *VM) Global(name string) (any, bool) {
+	v, ok := vm.global().Declared[name]
+	return v, ok
+}
+
+func (vm *VM) global() *Frame {
+	f := vm.cframe
+	for f.Parent != nil {
+		f = f.Parent
+	}
+	return f
+}
+
+// SetOutput Sets the writer builtins such as println write to. Defaults to os.Stdout.
+func (vm *VM) SetOutput(w io.Writer) {
+	vm.out = w
+}
+
+// SetOverflow Sets what integer arithmetic does on overflow. Defaults to OverflowWrap.
+func (vm *VM) SetOverflow(o Overflow) {
+	vm.overflow = o
+}
+
+// SetStrict Sets whether reading or assigning an element out of the bounds of an array, or slicing beyond them, raises
+// an error. Otherwise reading gives nil, assigning does nothing and slice bounds are clamped. Defaults to false.
+func (vm *VM) SetStrict(strict bool) {
+	vm.strict = strict
+}
+
+// SetSource Sets the source the executed bytecode was compiled from. Errors then report file:line:col positions.
+func (vm *VM) SetSource(src *script.Source) {
+	vm.source = src
+}
+
+// Source Returns the source set with SetSource, or nil.
+func (vm *VM) Source() *script.Source {
+	return vm.source
+}
+
+// Pos Returns the source position of the current instruction, or -1 if it is unknown.
+func (vm *VM) Pos() int {
+	return vm.spanAt(vm.pointer).Start
+}
+
+func (vm *VM) spanAt(pointer int) Span {
+	if pointer < 0 || pointer >= len(vm.bc) {
+		return NoSpan
+	}
+	return vm.bc[pointer].Span
+}
+
+// positionAt Returns the source position of the instruction with the given index. It is the zero Position if the
+// source or the span of the instruction is unknown.
+func (vm *VM) positionAt(pointer int) script.Position {
+	span := vm.spanAt(pointer)
+	if vm.source == nil || !span.Known() {
+		return script.Position{}
+	}
+	return vm.source.Position(span.Start)
+}
+
+// Err Aborts the execution with a RuntimeError. It is returned by Execute.
 func (vm *VM) Err(msg string) {
-	log.Fatalf("error at %d: %s", vm.pointer, msg)
+	vm.fail(msg, nil)
+}
+
+func (vm *VM) fail(msg string, err error) {
+	panic(vm.runtimeError(msg, err))
+}
+
+func (vm *VM) runtimeError(msg string, err error) *RuntimeError {
+	return &RuntimeError{
+		Pointer:  vm.pointer,
+		Pos:      vm.Pos(),
+		Position: vm.positionAt(vm.pointer),
+		Message:  msg,
+		Err:      err,
+		Value:    msg,
+		Trace:    vm.trace(),
+		source:   vm.source,
+	}
 }
 
 func (vm *VM) Dump() string {
@@ -50,13 +186,71 @@ const (
 )
 
 func (vm *VM) Execute(bc Bytecode) error {
+	vm.start(bc)
+	_, err := vm.run(nil)
+	return err
+}
+
+func (vm *VM) start(bc Bytecode) {
+	vm.bc = bc
+	vm.pointer = 0
+	vm.handlers = nil
+	vm.handlerBase = 0
+	vm.invoker = nil
+	if vm.profile != nil {
+		vm.profile.reset(bc)
+	}
+}
+
+// run Executes instructions from the current one until the program ends or stop returns true. stop is called before
+// every instruction, except the first one, so that a stopped execution can be resumed. Runtime errors are passed to
+// the installed exception handlers, see TRY.
+func (vm *VM) run(stop func() bool) (stopped bool, err error) {
+	first := true
+	for {
+		stopped, err = vm.exec(stop, first)
+		runtimeErr, ok := err.(*RuntimeError)
+		if !ok {
+			return stopped, err
+		}
+		runtimeErr = vm.unwind(runtimeErr)
+		if !vm.catch(runtimeErr) {
+			return stopped, runtimeErr
+		}
+		first = false
+	}
+}
+
+// exec Executes instructions like run until the program ends, stop returns true or an error is raised. If first is
+// set, stop is not called before the current instruction.
+func (vm *VM) exec(stop func() bool, first bool) (stopped bool, err error) {
+	defer func() {
+		switch r := recover().(type) {
+		case nil:
+		case *RuntimeError:
+			err = r
+		case runtime.Error:
+			err = vm.runtimeError(r.Error(), r)
+		default:
+			panic(r)
+		}
+	}()
+
 	if debugStack {
 		fmt.Println(strings.TrimSpace(strings.ReplaceAll(script.Stringify(vm.stack), "\n", "")))
 	}
 
-	vm.pointer = 0
+	bc := vm.bc
 	for ; vm.pointer < len(bc); vm.pointer++ {
+		if stop != nil && !first && stop() {
+			return true, nil
+		}
+		first = false
+
 		instr := bc[vm.pointer]
+		if vm.profile != nil {
+			vm.profile.Counts[vm.pointer]++
+		}
 		if debugInstructions {
 			if instr.Arg != nil {
 				fmt.Println(instr.Op, instr.Arg)
@@ -70,17 +264,33 @@ func (vm *VM) Execute(bc Bytecode) error {
 		case POP:
 			vm.stack.Pop()
 		case ADD:
-			vm.add()
+			vm.binary(Add)
 		case SUB:
-			vm.sub()
+			vm.binary(Sub)
 		case MUL:
-			vm.mul()
+			vm.binary(Mul)
 		case DIV:
-			vm.div()
+			vm.binary(Div)
+		case MOD:
+			vm.binary(Mod)
+		case POW:
+			vm.binary(Pow)
+		case BIT_AND:
+			vm.binary(BitAnd)
+		case BIT_OR:
+			vm.binary(BitOr)
+		case BIT_XOR:
+			vm.binary(BitXor)
+		case SHL:
+			vm.binary(Shl)
+		case SHR:
+			vm.binary(Shr)
 		case CMP, CMP_LT, CMP_GT, CMP_LTE, CMP_GTE:
 			vm.cmp(instr.Op)
 		case NEG:
-			vm.neg()
+			vm.unary(Neg)
+		case BIT_NOT:
+			vm.unary(BitNot)
 		case NOT:
 			vm.not()
 		case DECLARE:
@@ -99,11 +309,19 @@ func (vm *VM) Execute(bc Bytecode) error {
 			if !vm.popBool() {
 				vm.pointer = instr.Arg.(int) - 1
 			}
+		case JUMP_NIL:
+			if vm.stack.Top() == nil {
+				vm.pointer = instr.Arg.(int) - 1
+			}
+		case JUMP_NOT_NIL:
+			if vm.stack.Top() != nil {
+				vm.pointer = instr.Arg.(int) - 1
+			}
 		case ENTER:
 			vm.cframe = newFrame(vm.cframe)
 		case LEAVE:
 			if vm.cframe.Parent == nil {
-				return fmt.Errorf("cannot leave cframe scope")
+				return false, fmt.Errorf("cannot leave cframe scope")
 			}
 			vm.cframe = vm.cframe.Parent
 		case CALL:
@@ -112,7 +330,7 @@ func (vm *VM) Execute(bc Bytecode) error {
 			var err error
 			vm.pointer, err = vm.ret(vm.pointer)
 			if err != nil {
-				return err
+				return false, err
 			}
 		case ARR_INIT:
 			vm.arrayInit()
@@ -122,22 +340,57 @@ func (vm *VM) Execute(bc Bytecode) error {
 			vm.arrayIndex()
 		case ARR_V:
 			vm.arraySet()
+		case SLICE:
+			vm.slice()
 		case FRAME:
 			vm.frame(vm.pointer, instr.Arg.(int))
 		case ANCHOR:
 			vm.cframe.anchor = instr.Arg.(bool)
 		case RESCUE:
 			vm.cframe = vm.cframe.Anchor()
+			if skip, ok := instr.Arg.(int); ok {
+				for ; skip > 0; skip-- {
+					vm.cframe = vm.cframe.Parent.Anchor()
+				}
+			}
 		case JUMP_B:
 			var err error
 			vm.pointer, err = vm.jump_b(vm.pointer)
 			if err != nil {
-				return err
+				return false, err
 			}
 		case PANIC:
-			return fmt.Errorf("panic at %d: %v", vm.pointer, instr.Arg)
+			return false, vm.runtimeError(fmt.Sprintf("panic: %v", instr.Arg), nil)
+		case TRY:
+			vm.try(instr.Arg.(int))
+		case END_TRY:
+			vm.endTry()
+		case THROW:
+			vm.throw(vm.stack.Pop())
+		case CATCH:
+			vm.catchValue()
+		case DEFER:
+			vm.deferCall()
+		case YIELD:
+			vm.yield()
+		case ITER:
+			vm.iterate()
+		case ITER_NEXT:
+			vm.next(instr.Arg.(bool))
+		case RANGE:
+			vm.makeRange()
+		case MATCH_EQ:
+			left, right := vm.popBinary()
+			vm.stack.Push(Equal(left, right))
+		case MATCH_LEN:
+			arr, ok := vm.stack.Pop().(*ArrayValue)
+			vm.stack.Push(ok && arr.Len() == instr.Arg.(int))
+		case MATCH_RANGE:
+			vm.matchRange()
+		case SWITCH:
+			vm.pointer = instr.Arg.(JumpTable).Target(vm.stack.Pop()) - 1
 		default:
-			return fmt.Errorf("unknown opcode %v in instruction %d", instr.Op, vm.pointer)
+			return false, fmt.Errorf("unknown opcode %v in instruction %d", instr.Op, vm.pointer)
 		}
 		if debugStack {
 			c := max(0, vm.stack.Cursor+1)
@@ -146,10 +399,10 @@ func (vm *VM) Execute(bc Bytecode) error {
 	}
 
 	if vm.stack.Len() > 0 {
-		return fmt.Errorf("memory leak: stack size is %d", vm.stack.Len())
+		return false, fmt.Errorf("memory leak: stack size is %d", vm.stack.Len())
 	}
 
-	return nil
+	return false, nil
 }
 
 func (vm *VM) ret(i int) (int, error) {
@@ -157,6 +410,9 @@ func (vm *VM) ret(i int) (int, error) {
 		return 0, fmt.Errorf("cannot return without a frame")
 	}
 	p, index := vm.cframe.End()
+	if err := vm.runDeferred(p); err != nil {
+		panic(err)
+	}
 	vm.cframe = p.Parent //return
 	i = index - 1
 	return i, nil
@@ -173,21 +429,16 @@ func (vm *VM) jump_b(i int) (int, error) {
 }
 
 func (vm *VM) popBool() bool {
-	value := vm.stack.Pop()
-
-	if value == nil {
-		return false
-	}
+	return Truthy(vm.stack.Pop())
+}
 
-	switch t := value.(type) {
-	case bool:
-		return t
-	case float64:
-		// TODO remove this once booleans are implemented later
-		return t != 0
-	default:
-		return true
+// popArgs Pops argCount call arguments and returns them in declaration order.
+func (vm *VM) popArgs(argCount int) []any {
+	args := make([]any, argCount)
+	for i := range args {
+		args[i] = vm.stack.Pop()
 	}
+	return args
 }
 
 func (vm *VM) popBinary() (any, any) {
@@ -196,48 +447,63 @@ func (vm *VM) popBinary() (any, any) {
 	return left, right
 }
 
-func (vm *VM) add() {
+// binary Replaces the two values on top of the stack with the result of op. An integer overflow is handled as set with
+// SetOverflow, other errors of op are raised.
+func (vm *VM) binary(op func(a, b any) (any, error)) {
 	left, right := vm.popBinary()
-	if v, err := Add(left, right); err != nil {
-		vm.stack.Push(nil)
-	} else {
-		vm.stack.Push(v)
+	v, err := op(left, right)
+	if errors.Is(err, ErrIntegerOverflow) {
+		v, err = vm.overflowed(v, func() (any, error) {
+			x, _ := toBig(left)
+			y, _ := toBig(right)
+			return op(x, y)
+		})
 	}
-}
-
-func (vm *VM) sub() {
-	left, right := vm.popBinary()
-	if v, err := Sub(left, right); err != nil {
-		vm.stack.Push(nil)
-	} else {
-		vm.stack.Push(v)
+	if err != nil {
+		vm.fail(operandError(err, left, right), err)
 	}
+	vm.stack.Push(v)
 }
 
-func (vm *VM) mul() {
-	left, right := vm.popBinary()
-	if v, err := Mul(left, right); err != nil {
-		vm.stack.Push(nil)
-	} else {
-		vm.stack.Push(v)
+// unary Replaces the value on top of the stack with the result of op, see binary.
+func (vm *VM) unary(op func(a any) (any, error)) {
+	operand := vm.stack.Pop()
+	v, err := op(operand)
+	if errors.Is(err, ErrIntegerOverflow) {
+		v, err = vm.overflowed(v, func() (any, error) {
+			x, _ := toBig(operand)
+			return op(x)
+		})
+	}
+	if err != nil {
+		vm.fail(operandError(err, operand), err)
 	}
+	vm.stack.Push(v)
 }
 
-func (vm *VM) div() {
-	left, right := vm.popBinary()
-	if v, err := Div(left, right); err != nil {
-		vm.stack.Push(nil)
-	} else {
-		vm.stack.Push(v)
+// operandError Returns the message of the error err of an operation, which names the types of the operands if they do
+// not fit the operation, e.g. "type mismatch: Int and Float".
+func operandError(err error, operands ...any) string {
+	if !errors.Is(err, ErrTypeMismatch) && !errors.Is(err, ErrTypeOperationUnsupported) {
+		return err.Error()
 	}
+	types := make([]string, len(operands))
+	for i, operand := range operands {
+		types[i] = TypeOf(operand).String()
+	}
+	return fmt.Sprintf("%v: %s", err, strings.Join(types, " and "))
 }
 
-func (vm *VM) neg() {
-	if v, err := Neg(vm.stack.Pop()); err != nil {
-		vm.stack.Push(nil)
-	} else {
-		vm.stack.Push(v)
+// overflowed Returns the result of an operation that overflowed: the wrapped result, or the result of exact, which
+// repeats the operation on big integers. With OverflowError, an error is raised.
+func (vm *VM) overflowed(wrapped any, exact func() (any, error)) (any, error) {
+	switch vm.overflow {
+	case OverflowError:
+		vm.fail(ErrIntegerOverflow.Error(), ErrIntegerOverflow)
+	case OverflowPromote:
+		return exact()
 	}
+	return wrapped, nil
 }
 
 func (vm *VM) not() {
@@ -246,6 +512,23 @@ func (vm *VM) not() {
 
 func (vm *VM) cmp(code OpCode) {
 	left, right := vm.popBinary()
+	if c, ok := compareNumbers(left, right); ok {
+		switch code {
+		case CMP:
+			vm.stack.Push(c == 0)
+		case CMP_LT:
+			vm.stack.Push(c < 0)
+		case CMP_GT:
+			vm.stack.Push(c > 0)
+		case CMP_LTE:
+			vm.stack.Push(c <= 0)
+		case CMP_GTE:
+			vm.stack.Push(c >= 0)
+		default:
+			vm.Err(fmt.Sprintf("undefined comparison operation %v", code))
+		}
+		return
+	}
 	lType := TypeOf(left)
 	rType := TypeOf(right)
 
@@ -262,8 +545,8 @@ func (vm *VM) cmp(code OpCode) {
 			vm.Err(fmt.Sprintf("undefined comparison operation %v", code))
 		}
 	case Int:
-		if leftInt, ok := left.(int); ok {
-			if rightInt, ok := right.(int); ok {
+		if leftInt, ok := left.(int64); ok {
+			if rightInt, ok := right.(int64); ok {
 				switch code {
 				case CMP:
 					vm.stack.Push(leftInt == rightInt)
@@ -303,6 +586,27 @@ func (vm *VM) cmp(code OpCode) {
 			}
 			vm.Err(fmt.Sprintf("cannot compare number with non-number %v", code))
 		}
+	case String:
+		if leftString, ok := left.(string); ok {
+			if rightString, ok := right.(string); ok {
+				switch code {
+				case CMP:
+					vm.stack.Push(leftString == rightString)
+				case CMP_LT:
+					vm.stack.Push(leftString < rightString)
+				case CMP_GT:
+					vm.stack.Push(leftString > rightString)
+				case CMP_LTE:
+					vm.stack.Push(leftString <= rightString)
+				case CMP_GTE:
+					vm.stack.Push(leftString >= rightString)
+				default:
+					vm.Err(fmt.Sprintf("undefined comparison operation %v", code))
+				}
+				return
+			}
+			vm.Err(fmt.Sprintf("cannot compare string with non-string %v", code))
+		}
 	case Bool:
 		if leftBool, ok := left.(bool); ok {
 			if rightBool, ok := right.(bool); ok {
@@ -321,6 +625,17 @@ func (vm *VM) cmp(code OpCode) {
 	}
 }
 
+// compareNumbers Returns the result of Cmp for a decimal or a big integer and another integer or decimal.
+func compareNumbers(a, b any) (int, bool) {
+	if x, y, ok := decimalOperands(a, b); ok {
+		return x.Cmp(y), true
+	}
+	if x, y, ok := bigOperands(a, b); ok {
+		return x.Cmp(y), true
+	}
+	return 0, false
+}
+
 func (vm *VM) declare(s string) {
 	vm.cframe.Declare(string(s), vm.stack.Pop())
 }
@@ -335,50 +650,103 @@ func (vm *VM) store(s string) {
 }
 
 func (vm *VM) arrayInit() {
-	size := vm.stack.Pop().(int)
+	size := vm.stack.Pop().(int64)
 	arr := make([]any, size)
-	vm.stack.Push(arr)
+	vm.stack.Push(NewArray(arr))
 }
 
 func (vm *VM) arrayCreate() {
-	size := vm.stack.Pop().(int)
+	size := vm.stack.Pop().(int64)
 	arr := make([]any, size)
-	for i := 0; i < size; i++ {
+	for i := range arr {
 		arr[i] = vm.stack.Pop()
 	}
-	vm.stack.Push(arr)
+	vm.stack.Push(NewArray(arr))
 }
 
 func (vm *VM) arrayIndex() {
-	index := vm.stack.Pop().(int)
-	arr := vm.stack.Pop().([]any)
-
-	if index < 0 {
-		index = len(arr) + index
+	key, value := vm.stack.Pop(), vm.stack.Pop()
+	if m, ok := value.(map[string]any); ok {
+		name, ok := key.(string)
+		if !ok {
+			vm.Err(fmt.Sprintf("map key must be a string, got %s", Repr(key)))
+		}
+		vm.stack.Push(fromNative(m[name]))
+		return
 	}
-
-	if index < 0 || index >= len(arr) {
+	arr, ok := value.(*ArrayValue)
+	if !ok {
+		vm.Err(fmt.Sprintf("cannot index %s", Repr(value)))
+	}
+	i, ok := vm.elementIndex(arr, key)
+	if !ok {
 		vm.stack.Push(nil)
 		return
 	}
 
-	vm.stack.Push(arr[index])
+	vm.stack.Push(arr.elements[i])
 }
 
 func (vm *VM) arraySet() {
-	index := vm.stack.Pop().(int)
-	arr := vm.stack.Pop().([]any)
-
-	if index < 0 {
-		index = len(arr) + index
+	key, value := vm.stack.Pop(), vm.stack.Pop()
+	element := vm.stack.Pop()
+	arr, ok := value.(*ArrayValue)
+	if !ok {
+		vm.Err(fmt.Sprintf("cannot assign an element of %s", Repr(value)))
+	}
+	if i, ok := vm.elementIndex(arr, key); ok {
+		arr.elements[i] = element
 	}
+}
 
-	if index < 0 || index >= len(arr) {
-		//vm.stack.Push(nil)
-		return
+// elementIndex Returns the index of the element of arr the integer key refers to and whether it is in bounds. A
+// negative key counts from the end. In strict mode an error is raised if it is out of bounds.
+func (vm *VM) elementIndex(arr *ArrayValue, key any) (int, bool) {
+	k, ok := key.(int64)
+	if !ok {
+		vm.Err(fmt.Sprintf("array index must be an integer, got %s", Repr(key)))
+	}
+	i, ok := arr.index(k, false)
+	if !ok && vm.strict {
+		vm.Err(fmt.Sprintf("index %d out of range for array of length %d", k, arr.Len()))
 	}
+	return i, ok
+}
 
-	arr[index] = vm.stack.Pop()
+// slice Replaces the array, the low and the high bound on top of the stack with the slice array[low:high], see SLICE.
+// A nil bound is omitted.
+func (vm *VM) slice() {
+	low, high := vm.popBinary()
+	value := vm.stack.Pop()
+	arr, ok := value.(*ArrayValue)
+	if !ok {
+		vm.Err(fmt.Sprintf("cannot slice %s", Repr(value)))
+	}
+	bound := func(v any, omitted int) int {
+		if v == nil {
+			return omitted
+		}
+		k, ok := v.(int64)
+		if !ok {
+			vm.Err(fmt.Sprintf("slice bound must be an integer, got %s", Repr(v)))
+		}
+		i, ok := arr.index(k, true)
+		if !ok {
+			if vm.strict {
+				vm.Err(fmt.Sprintf("slice bound %d out of range for array of length %d", k, arr.Len()))
+			}
+			i = min(max(i, 0), arr.Len())
+		}
+		return i
+	}
+	l, h := bound(low, 0), bound(high, arr.Len())
+	if h < l {
+		if vm.strict {
+			vm.Err(fmt.Sprintf("invalid slice bounds %d > %d", l, h))
+		}
+		h = l
+	}
+	vm.stack.Push(arr.Slice(l, h))
 }
 
 func (vm *VM) call(i *int) {
@@ -386,8 +754,23 @@ func (vm *VM) call(i *int) {
 
 	address := -1
 
+	f := vm.cframe
+	if t, ok := top.(Func); ok {
+		// Checked before the call is entered, so that the error is raised in the caller.
+		if msg := t.arityError(int(vm.stack.Top().(int64))); msg != "" {
+			vm.Err(msg)
+		}
+		f.callee = t.Name
+	}
+	f.called = true
+	if f.callee == "" && f.start > 0 && vm.bc[f.start-1].Op == LOAD {
+		// The callee is loaded right before FRAME.
+		f.callee = vm.bc[f.start-1].Arg.(string)
+	}
+
 	switch t := top.(type) {
 	case Type:
+		f.native = true
 		// Pop arg count
 		vm.stack.Pop()
 		// Cast
@@ -398,20 +781,53 @@ func (vm *VM) call(i *int) {
 	case Func:
 		address = t.Address
 	case ExternalFunc:
-		argCount := vm.stack.Pop().(int)
+		f.native = true
+		argCount := int(vm.stack.Pop().(int64))
 		result := t.Callback(vm, argCount)
 		// Return
 		*i, _ = vm.ret(*i)
 		vm.stack.Push(result)
 		return
 	default:
-		log.Fatalf("cannot call non-function %v @ %d", top, *i)
+		vm.Err(fmt.Sprintf("cannot call non-function %v", top))
 		return
 	}
 
 	*i = address
 }
 
+// invoke Calls callee with args from Go, while the instruction at the current pointer is executed, and returns the
+// result. Errors are raised as panics, as by the instruction.
+func (vm *VM) invoke(callee any, args []any) any {
+	pointer, caller, base, invoker := vm.pointer, vm.cframe, vm.handlerBase, vm.invoker
+	defer func() {
+		vm.pointer, vm.handlerBase, vm.invoker = pointer, base, invoker
+		if r := recover(); r != nil {
+			// The handlers installed by the call are not active anymore.
+			vm.cframe = caller
+			panic(r)
+		}
+	}()
+
+	for i := len(args) - 1; i >= 0; i-- {
+		vm.stack.Push(args[i])
+	}
+	vm.stack.Push(int64(len(args)))
+	vm.stack.Push(callee)
+	// The call returns to the instruction after the current one, where the execution stops.
+	vm.frame(pointer, pointer+1)
+	vm.call(&vm.pointer)
+	if _, ok := callee.(Func); ok {
+		// Skip ENTER like the execution loop does after CALL.
+		vm.pointer++
+		vm.handlerBase, vm.invoker = len(vm.handlers), caller
+		if _, err := vm.run(func() bool { return vm.cframe == caller }); err != nil {
+			panic(err)
+		}
+	}
+	return vm.stack.Pop()
+}
+
 func (vm *VM) frame(current, end int) {
 	f := newFrame(vm.cframe)
 	f.start = current
@@ -420,60 +836,140 @@ func (vm *VM) frame(current, end int) {
 }
 
 func (vm *VM) cast(t TypeId) {
-	v := vm.stack.Pop()
+	v, err := convert(vm.stack.Pop(), t)
+	if err != nil {
+		vm.Err(err.Error())
+	}
+	vm.stack.Push(v)
+}
+
+// convert Returns v cast to the type t, as done by the CAST instruction and for the arguments of host functions.
+func convert(v any, t TypeId) (any, error) {
 	vt := TypeOf(v)
 
 	switch t {
 	case Any:
-		vm.stack.Push(v)
-		return
+		return v, nil
 	case Array:
 		if vt != Array {
-			log.Fatalf("cannot cast to array from %v", vt)
+			return nil, fmt.Errorf("cannot cast to array from %v", vt)
 		}
-		vm.stack.Push(v.([]any))
+		return v, nil
 	case Int:
 		switch vt {
 		case Int:
-			vm.stack.Push(v)
+			return v, nil
+		case BigInt:
+			if !v.(*big.Int).IsInt64() {
+				return nil, fmt.Errorf("cannot cast %v to int, it is out of range", v)
+			}
+			return v.(*big.Int).Int64(), nil
+		case Decimal:
+			i := v.(decimal.Decimal).Int()
+			if !i.IsInt64() {
+				return nil, fmt.Errorf("cannot cast %v to int, it is out of range", v)
+			}
+			return i.Int64(), nil
 		case Float:
-			vm.stack.Push(int(v.(float64)))
+			return int64(v.(float64)), nil
 		case Bool:
 			if v.(bool) {
-				vm.stack.Push(int(1))
+				return int64(1), nil
 			} else {
-				vm.stack.Push(int(0))
+				return int64(0), nil
 			}
 		default:
-			log.Fatalf("cannot cast to int from %v", vt)
+			return nil, fmt.Errorf("cannot cast to int from %v", vt)
 		}
 	case Float:
 		switch vt {
 		case Int:
-			vm.stack.Push(float64(v.(int)))
+			return float64(v.(int64)), nil
+		case BigInt:
+			f, _ := new(big.Float).SetInt(v.(*big.Int)).Float64()
+			return f, nil
+		case Decimal:
+			return v.(decimal.Decimal).Float64(), nil
 		case Float:
-			vm.stack.Push(v)
+			return v, nil
 		case Bool:
 			if v.(bool) {
-				vm.stack.Push(float64(1))
+				return float64(1), nil
 			} else {
-				vm.stack.Push(float64(0))
+				return float64(0), nil
 			}
 		default:
-			log.Fatalf("cannot cast to float from %v", vt)
+			return nil, fmt.Errorf("cannot cast to float from %v", vt)
 		}
 	case Bool:
 		switch vt {
 		case Int:
-			vm.stack.Push(v.(int) != 0)
+			return v.(int64) != 0, nil
+		case BigInt:
+			return v.(*big.Int).Sign() != 0, nil
+		case Decimal:
+			return v.(decimal.Decimal).Sign() != 0, nil
 		case Float:
-			vm.stack.Push(v.(float64) != 0)
+			return v.(float64) != 0, nil
 		case Bool:
-			vm.stack.Push(v)
+			return v, nil
+		default:
+			return nil, fmt.Errorf("cannot cast to bool from %v", vt)
+		}
+	case String:
+		switch vt {
+		case String:
+			return v, nil
+		case Int, BigInt, Decimal, Float, Bool:
+			return fmt.Sprint(v), nil
+		default:
+			return nil, fmt.Errorf("cannot cast to string from %v", vt)
+		}
+	case BigInt:
+		switch vt {
+		case Int:
+			return big.NewInt(v.(int64)), nil
+		case BigInt:
+			return v, nil
+		case Decimal:
+			return v.(decimal.Decimal).Int(), nil
+		case Float:
+			f := v.(float64)
+			if math.IsInf(f, 0) || math.IsNaN(f) {
+				return nil, fmt.Errorf("cannot cast %v to bigint", f)
+			}
+			i, _ := big.NewFloat(f).Int(nil)
+			return i, nil
+		case String:
+			i, ok := new(big.Int).SetString(v.(string), 10)
+			if !ok {
+				return nil, fmt.Errorf("cannot cast %s to bigint", Repr(v))
+			}
+			return i, nil
+		default:
+			return nil, fmt.Errorf("cannot cast to bigint from %v", vt)
+		}
+	case Decimal:
+		switch vt {
+		case Int, BigInt, Decimal:
+			d, _ := toDecimal(v)
+			return d, nil
+		case Float:
+			d, err := decimal.FromFloat(v.(float64))
+			if err != nil {
+				return nil, err
+			}
+			return d, nil
+		case String:
+			d, err := decimal.Parse(v.(string))
+			if err != nil {
+				return nil, fmt.Errorf("cannot cast %s to decimal", Repr(v))
+			}
+			return d, nil
 		default:
-			log.Fatalf("cannot cast to bool from %v", vt)
+			return nil, fmt.Errorf("cannot cast to decimal from %v", vt)
 		}
 	default:
-		log.Fatalf("cannot cast to unknown type %vm", t)
+		return nil, fmt.Errorf("cannot cast to unknown type %v", t)
 	}
 }
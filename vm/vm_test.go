@@ -0,0 +1,89 @@
+package vm_test
+
+import (
+	"script/scripttest"
+	"script/vm"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestScripts(t *testing.T) {
+	scripttest.Run(t, "testdata/scripts", scripttest.Options{})
+}
+
+func TestOverflowError(t *testing.T) {
+	scripttest.Run(t, "testdata/overflow_error", scripttest.Options{
+		Setup: func(t *testing.T, v *vm.VM) {
+			v.SetOverflow(vm.OverflowError)
+		},
+	})
+}
+
+func TestOverflowPromote(t *testing.T) {
+	scripttest.Run(t, "testdata/overflow_promote", scripttest.Options{
+		Setup: func(t *testing.T, v *vm.VM) {
+			v.SetOverflow(vm.OverflowPromote)
+		},
+	})
+}
+
+func TestStrict(t *testing.T) {
+	scripttest.Run(t, "testdata/strict", scripttest.Options{
+		Setup: func(t *testing.T, v *vm.VM) {
+			v.SetStrict(true)
+		},
+	})
+}
+
+func TestNative(t *testing.T) {
+	scripttest.Run(t, "testdata/native", scripttest.Options{
+		Setup: func(t *testing.T, v *vm.VM) {
+			v.Declare("sum", vm.NewExternalFunc(func(xs []int) int {
+				total := 0
+				for _, x := range xs {
+					total += x
+				}
+				return total
+			}))
+			v.Declare("average", vm.NewExternalFunc(func(xs []float64) float64 {
+				total := 0.0
+				for _, x := range xs {
+					total += x
+				}
+				return total / float64(len(xs))
+			}))
+			v.Declare("join", vm.NewExternalFunc(func(xs []string) string {
+				return strings.Join(xs, "")
+			}))
+			v.Declare("parseInt", vm.NewExternalFunc(strconv.Atoi))
+			v.Declare("scores", map[string]any{"bob": 5, "alice": int32(2)})
+			v.Declare("countdown", vm.NewExternalFunc(func(n int) countdown {
+				return countdown{n}
+			}))
+		},
+	})
+}
+
+// countdown is an Iterable host value counting down from n to 1. Its keys are uint8 indices.
+type countdown struct {
+	n int
+}
+
+func (c countdown) Iterate() vm.Iterator {
+	return &countdownIterator{n: c.n + 1, index: -1}
+}
+
+type countdownIterator struct {
+	n, index int
+}
+
+func (it *countdownIterator) Next() bool {
+	it.n--
+	it.index++
+	return it.n > 0
+}
+
+func (it *countdownIterator) Entry() (any, any) {
+	return uint8(it.index), it.n
+}